@@ -0,0 +1,45 @@
+package auth
+
+import (
+	"strings"
+
+	"example.com/go-project/helper"
+	"example.com/go-project/services"
+	"github.com/gin-gonic/gin"
+)
+
+// APIKeyHeader carries the API key of a request
+const APIKeyHeader = "X-API-Key"
+
+var ErrInvalidAPIKey = helper.Unauthorized("invalid API key")
+
+// APIKeyAuthenticator accepts the API keys users create for their scripts.
+// The principal gets the owner's current role, limited to the key's scopes.
+type APIKeyAuthenticator struct {
+	Keys *services.ApiKeyService
+}
+
+func (a APIKeyAuthenticator) Authenticate(c *gin.Context) (*Principal, error) {
+	raw := c.GetHeader(APIKeyHeader)
+	if raw == "" {
+		return nil, ErrNoCredentials
+	}
+
+	key, user, err := a.Keys.Authenticate(raw)
+	if err != nil {
+		return nil, ErrInvalidAPIKey
+	}
+
+	p := &Principal{
+		UserId:   user.Id,
+		Email:    user.Email,
+		Roles:    []string{user.Role},
+		Scopes:   strings.Split(key.Scopes, ","),
+		Method:   MethodAPIKey,
+		IssuedAt: key.CreatedAt,
+	}
+	if key.ExpiresAt != nil {
+		p.ExpiresAt = *key.ExpiresAt
+	}
+	return p, nil
+}
@@ -0,0 +1,89 @@
+package auth
+
+import (
+	"log"
+	"net/http"
+
+	"example.com/go-project/helper"
+	"example.com/go-project/model"
+	"github.com/gin-gonic/gin"
+)
+
+// AuditLog records security events
+type AuditLog interface {
+	Record(event model.AuditEvent) error
+}
+
+// auditLog is nil until one is set, nothing is recorded then
+var auditLog AuditLog
+
+const (
+	auditDetailKey = "auditDetail"
+	auditTargetKey = "auditTarget"
+)
+
+// UseAuditLog sets where security events are recorded
+func UseAuditLog(log AuditLog) {
+	auditLog = log
+}
+
+// Audit records the event with the address and the user agent of the caller.
+// An event without an actor gets the authenticated caller, if there is one.
+// Failing to record the event doesn't fail the request.
+func Audit(c *gin.Context, event model.AuditEvent) {
+	if auditLog == nil {
+		return
+	}
+	event.Ip = c.ClientIP()
+	event.UserAgent = c.Request.UserAgent()
+	if p, ok := CurrentPrincipal(c); ok && event.ActorId == nil {
+		userId := p.UserId
+		event.ActorId = &userId
+		if event.Email == "" {
+			event.Email = p.Email
+		}
+	}
+
+	if err := auditLog.Record(event); err != nil {
+		log.Println("Error recording audit event:", err)
+	}
+}
+
+// SetAuditDetail adds a detail to the event Audited records for a successful request
+func SetAuditDetail(c *gin.Context, detail string) {
+	c.Set(auditDetailKey, detail)
+}
+
+// SetAuditTarget names the target of the event Audited records when no path
+// parameter does, like a role that is being created
+func SetAuditTarget(c *gin.Context, targetId string) {
+	c.Set(auditTargetKey, targetId)
+}
+
+// Audited records the outcome of the request as the action on the target the
+// path parameter names, or the handler set. Put it before the permission
+// check, refused attempts are recorded too.
+func Audited(action string, targetType string, param string) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		c.Next()
+
+		event := model.AuditEvent{
+			Action:     action,
+			TargetType: targetType,
+			TargetId:   c.Param(param),
+			Outcome:    model.AuditSuccess,
+			Detail:     c.GetString(auditDetailKey),
+		}
+		if targetId := c.GetString(auditTargetKey); targetId != "" {
+			event.TargetId = targetId
+		}
+		if len(c.Errors) > 0 {
+			event.Outcome = model.AuditFailure
+			event.Detail = helper.AsAppError(c.Errors.Last().Err).Message
+		} else if c.Writer.Status() >= http.StatusBadRequest {
+			event.Outcome = model.AuditFailure
+			event.Detail = http.StatusText(c.Writer.Status())
+		}
+		Audit(c, event)
+	}
+}
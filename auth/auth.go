@@ -2,45 +2,87 @@ package auth
 
 import (
 	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
 	"fmt"
+	"log"
 	"net/http"
-	"os"
+	"strconv"
 	"time"
 
+	"example.com/go-project/config"
+	"example.com/go-project/data/response"
+	"example.com/go-project/model"
 	"example.com/go-project/services"
 	"github.com/gin-gonic/gin"
 	"github.com/golang-jwt/jwt"
 	"github.com/gorilla/sessions"
-	"github.com/joho/godotenv"
 	"github.com/markbates/goth"
 	"github.com/markbates/goth/gothic"
 	"github.com/markbates/goth/providers/google"
 )
 
-var jwtSecret = []byte("KJKJvjVJgj&^574&768&*^&$728y7JvjVJFjgvjhgVuyglwajhqoiewosiqwhaiVUVKUVKJhw")
-var userService *services.UsersService
+var keyring *Keyring
+var sessionStore sessions.Store
+
+// AccessTokenTTL is kept short, clients use their refresh token to get a new one
+var AccessTokenTTL = 15 * time.Minute
 
 // GenerateJWT generates a JWT token for a user including role
 func GenerateJWT(userId int, email string, role string) (string, error) {
+	jti, err := newTokenId()
+	if err != nil {
+		return "", err
+	}
+
+	now := time.Now()
 	claims := jwt.MapClaims{
+		"jti":     jti, // Token id, used to revoke the token
 		"user_id": userId,
 		"email":   email,
-		"role":    role,                                  // Add role to the token
-		"exp":     time.Now().Add(time.Hour * 24).Unix(), // Token expiry time
+		"role":    role,                           // Add role to the token
+		"iat":     now.Unix(),                     // Token issue time
+		"exp":     now.Add(AccessTokenTTL).Unix(), // Token expiry time
 	}
 
-	token := jwt.NewWithClaims(jwt.SigningMethodHS256, claims)
-	return token.SignedString(jwtSecret)
+	if keyring == nil || keyring.Current() == nil {
+		return "", errors.New("no signing key configured")
+	}
+	key := keyring.Current()
+
+	token := jwt.NewWithClaims(key.Method, claims)
+	token.Header["kid"] = key.Kid // Lets verifiers pick the right public key
+	return token.SignedString(key.PrivateKey)
+}
+
+func newTokenId() (string, error) {
+	bytes := make([]byte, 16)
+	if _, err := rand.Read(bytes); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(bytes), nil
 }
 
 // ValidateJWT validates the JWT token
 func ValidateJWT(tokenString string) (*jwt.Token, error) {
 	token, err := jwt.Parse(tokenString, func(token *jwt.Token) (interface{}, error) {
-		// Validate the signing method
-		if _, ok := token.Method.(*jwt.SigningMethodHMAC); !ok {
+		if keyring == nil {
+			return nil, errors.New("no signing key configured")
+		}
+
+		// Find the key the token claims to be signed with
+		kid, _ := token.Header["kid"].(string)
+		key, ok := keyring.Lookup(kid)
+		if !ok {
+			return nil, jwt.NewValidationError("unknown signing key", jwt.ValidationErrorUnverifiable)
+		}
+
+		// Validate the signing method, never trust the alg header on its own
+		if token.Method.Alg() != key.Method.Alg() {
 			return nil, jwt.NewValidationError("invalid signing method", jwt.ValidationErrorSignatureInvalid)
 		}
-		return jwtSecret, nil
+		return key.PublicKey, nil
 	})
 	if err != nil {
 		return nil, err
@@ -48,31 +90,113 @@ func ValidateJWT(tokenString string) (*jwt.Token, error) {
 	return token, nil
 }
 
-const (
-	key    = "uygs@*ibiIVSUYU@sIUSbibspougefuvASDSUGU@*W&873ni3h993oBIsib2"
-	MaxAge = 86400 * 30 // Session expiration
-	IsProd = false      // Whether it's production or not
-)
+// UseKeyring sets the keys used to sign and verify access tokens
+func UseKeyring(k *Keyring) {
+	keyring = k
+}
+
+// LoadKeyring loads the signing keys from PEM files, the last file being the
+// current key. Without files a new key is generated, which means tokens don't
+// survive a restart.
+func LoadKeyring(keyFiles []string, alg string, grace time.Duration) (*Keyring, error) {
+	k := NewKeyring(grace)
 
-func NewAuth(router *gin.Engine, userService *services.UsersService) {
-	// Load environment variables
-	err := godotenv.Load()
+	for _, keyFile := range keyFiles {
+		key, err := LoadSigningKey(keyFile)
+		if err != nil {
+			return nil, err
+		}
+		k.Add(key)
+	}
+
+	if k.Current() == nil {
+		key, err := GenerateSigningKey(alg)
+		if err != nil {
+			return nil, err
+		}
+		k.Add(key)
+	}
+	return k, nil
+}
+
+// IssueTokenPair generates an access token and a fresh refresh token family for the user
+func IssueTokenPair(user *model.Users, tokenService *services.TokenService) (response.TokenResponse, error) {
+	refreshToken, err := tokenService.Issue(user.Id)
 	if err != nil {
-		fmt.Println("Error loading .env file:", err)
-		return
+		return response.TokenResponse{}, err
 	}
+	return newTokenResponse(user, refreshToken)
+}
 
-	// Get Google credentials from environment variables
-	googleClientId := os.Getenv("GOOGLE_CLIENT_ID")
-	googleClientSecret := os.Getenv("GOOGLE_CLIENT_SECRET")
+// RefreshTokenPair rotates the refresh token and generates a new access token
+func RefreshTokenPair(refreshToken string, tokenService *services.TokenService) (response.TokenResponse, error) {
+	user, newRefreshToken, err := tokenService.Rotate(refreshToken)
+	if err != nil {
+		return response.TokenResponse{}, err
+	}
+	return newTokenResponse(user, newRefreshToken)
+}
+
+func newTokenResponse(user *model.Users, refreshToken string) (response.TokenResponse, error) {
+	token, err := GenerateJWT(user.Id, user.Email, user.Role)
+	if err != nil {
+		return response.TokenResponse{}, err
+	}
+
+	return response.TokenResponse{
+		Token:        "Bearer " + token,
+		RefreshToken: refreshToken,
+		ExpiresIn:    int(AccessTokenTTL.Seconds()),
+	}, nil
+}
+
+func NewAuth(router *gin.Engine, cfg config.AuthConfig, userService *services.UsersService, tokenService *services.TokenService, apiKeyService *services.ApiKeyService) error {
+	AccessTokenTTL = cfg.AccessTokenTTL
+	UseRoleHierarchy(NewRoleHierarchy(cfg.Roles))
+	PermissionCacheTTL = cfg.PermissionCacheTTL
+
+	// Load the keys access tokens are signed with, retired keys stay
+	// valid until every token signed with them has expired
+	k, err := LoadKeyring(cfg.PrivateKeyFiles, cfg.SigningAlg, cfg.AccessTokenTTL)
+	if err != nil {
+		return fmt.Errorf("loading signing keys: %w", err)
+	}
+	UseKeyring(k)
+
+	// Public keys for verifying our access tokens
+	router.GET("/.well-known/jwks.json", JWKSHandler)
 
 	// Set up session store
-	store := sessions.NewCookieStore([]byte(key))
+	store := sessions.NewCookieStore([]byte(cfg.CookieKey))
+	store.MaxAge(cfg.SessionMaxAge)
+	store.Options.HttpOnly = true
+	store.Options.Secure = cfg.IsProd
+	store.Options.SameSite = http.SameSiteLaxMode
 	gothic.Store = store
+	sessionStore = store
+
+	// Callers are authenticated by the first configured method they present
+	var chain []Authenticator
+	for _, method := range cfg.Authenticators {
+		switch method {
+		case MethodBearer:
+			chain = append(chain, BearerAuthenticator{})
+		case MethodAPIKey:
+			chain = append(chain, APIKeyAuthenticator{Keys: apiKeyService})
+		case MethodSession:
+			chain = append(chain, SessionAuthenticator{Store: store, Users: userService})
+		}
+	}
+	UseAuthenticators(chain...)
+
+	if cfg.GoogleClientID == "" {
+		fmt.Println("Google OAuth is not configured, skipping its routes")
+		return nil
+	}
 
 	// Register Google provider
 	goth.UseProviders(
-		google.New(googleClientId, googleClientSecret, "http://localhost:8888/auth/callback/google", "email", "profile"),
+		google.New(cfg.GoogleClientID, cfg.GoogleClientSecret, cfg.OAuthCallbackURL, "email", "profile"),
 	)
 
 	// Authentication routes
@@ -82,16 +206,17 @@ func NewAuth(router *gin.Engine, userService *services.UsersService) {
 	})
 
 	router.GET("/auth/callback/google", func(c *gin.Context) {
-		getAuthCallBackFunctions(c, userService)
+		getAuthCallBackFunctions(c, userService, tokenService)
 	})
+	return nil
 }
 
 // Callback function
-func getAuthCallBackFunctions(c *gin.Context, userService *services.UsersService) {
+func getAuthCallBackFunctions(c *gin.Context, userService *services.UsersService, tokenService *services.TokenService) {
 	// Get the provider name
-	provider, err := gothic.GetProviderName(c.Request)
+	_, err := gothic.GetProviderName(c.Request)
 	if err != nil {
-		fmt.Println("Error getting provider name:", err, provider)
+		auditGoogleCallback(c, "", nil, "unable to get provider")
 		c.JSON(http.StatusBadRequest, gin.H{"error": "Unable to get provider"})
 		return
 	}
@@ -99,7 +224,8 @@ func getAuthCallBackFunctions(c *gin.Context, userService *services.UsersService
 	// Complete user authentication
 	user, err := gothic.CompleteUserAuth(c.Writer, c.Request)
 	if err != nil {
-		fmt.Println("Error completing user authentication:", err)
+		log.Println("Error completing user authentication:", err)
+		auditGoogleCallback(c, "", nil, "could not complete authentication")
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "Unable to complete authentication"})
 		return
 	}
@@ -107,33 +233,65 @@ func getAuthCallBackFunctions(c *gin.Context, userService *services.UsersService
 	// Extract email from the Google user payload
 	email := user.Email
 
-	fmt.Println("Google Email : ", email)
 	// Check if the email exists in the user repository
 	existingUser, err := userService.FindUserByEmail(email)
 	if err != nil {
-		fmt.Println("Error searching for user in repository:", err)
+		log.Println("Error searching for user in repository:", err)
+		auditGoogleCallback(c, email, nil, "could not search the user")
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "Error searching user"})
 		return
 	}
 
 	if existingUser != nil {
-		// User exists, generate a JWT token
-		token, err := GenerateJWT(existingUser.Id, existingUser.Email, existingUser.Role)
+		// User exists, generate an access and refresh token
+		tokens, err := IssueTokenPair(existingUser, tokenService)
 		if err != nil {
-			fmt.Println("Error generating JWT:", err)
+			log.Println("Error generating JWT:", err)
+			auditGoogleCallback(c, email, existingUser, "could not generate the token")
 			c.JSON(http.StatusInternalServerError, gin.H{"error": "Error generating token"})
 			return
 		}
 
-		// Return the user and token
+		// Browsers keep a session cookie, API clients use the tokens
+		if err := StartSession(c, sessionStore, existingUser); err != nil {
+			log.Println("Error saving session:", err)
+			auditGoogleCallback(c, email, existingUser, "could not save the session")
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Error saving session"})
+			return
+		}
+
+		// Return the user and tokens
+		auditGoogleCallback(c, email, existingUser, "")
 		c.JSON(http.StatusOK, gin.H{
-			"user":  existingUser,
-			"token": token,
+			"user":         response.NewUserResponse(existingUser),
+			"token":        tokens.Token,
+			"refreshToken": tokens.RefreshToken,
+			"expiresIn":    tokens.ExpiresIn,
 		})
 	} else {
 		// User does not exist, prompt for registration
+		auditGoogleCallback(c, email, nil, "no account with the email")
 		c.JSON(http.StatusOK, gin.H{
 			"message": "You need to register to this platform",
 		})
 	}
 }
+
+// auditGoogleCallback records a Google login, it failed when there is a detail
+func auditGoogleCallback(c *gin.Context, email string, user *model.Users, detail string) {
+	event := model.AuditEvent{
+		Action:  model.AuditGoogleCallback,
+		Email:   email,
+		Outcome: model.AuditSuccess,
+		Detail:  detail,
+	}
+	if detail != "" {
+		event.Outcome = model.AuditFailure
+	}
+	if user != nil {
+		event.ActorId = &user.Id
+		event.TargetType = "user"
+		event.TargetId = strconv.Itoa(user.Id)
+	}
+	Audit(c, event)
+}
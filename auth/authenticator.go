@@ -0,0 +1,104 @@
+package auth
+
+import (
+	"errors"
+	"fmt"
+
+	"example.com/go-project/helper"
+	"example.com/go-project/middleware"
+	"github.com/gin-gonic/gin"
+)
+
+var (
+	// ErrNoCredentials is returned by an Authenticator when the request carries
+	// no credentials of its kind, the next authenticator is tried then
+	ErrNoCredentials = helper.Unauthorized("authorization token required")
+	ErrInvalidToken  = helper.Unauthorized("invalid token")
+	ErrTokenRevoked  = helper.Unauthorized("token has been revoked")
+)
+
+// Authenticator turns the credentials of a request into a Principal
+type Authenticator interface {
+	Authenticate(c *gin.Context) (*Principal, error)
+}
+
+var authenticators = []Authenticator{BearerAuthenticator{}}
+
+// UseAuthenticators sets the authenticators the middleware tries, in order
+func UseAuthenticators(a ...Authenticator) {
+	authenticators = a
+}
+
+// RequireAuth only requires an authenticated caller, whatever its roles
+func RequireAuth() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if _, ok := authenticate(c); ok {
+			c.Next()
+		}
+	}
+}
+
+// RequireRole requires a caller with one of the allowed roles, or a role
+// above one of them in the hierarchy
+func RequireRole(allowedRoles ...string) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		p, ok := authenticate(c)
+		if !ok {
+			return
+		}
+
+		if !p.HasRole(allowedRoles...) {
+			middleware.AbortWithError(c, helper.Forbidden("Forbidden: Insufficient privileges"))
+			return
+		}
+		c.Next()
+	}
+}
+
+// RequirePermission requires a caller that was granted every one of the permissions
+func RequirePermission(permissions ...string) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		p, ok := authenticate(c)
+		if !ok {
+			return
+		}
+
+		allowed, err := p.HasPermission(permissions...)
+		if err != nil {
+			middleware.AbortWithError(c, fmt.Errorf("could not check permissions: %w", err))
+			return
+		}
+		if !allowed {
+			middleware.AbortWithError(c, helper.Forbidden("Forbidden: Insufficient privileges"))
+			return
+		}
+		c.Next()
+	}
+}
+
+// authenticate runs the authenticator chain once per request and stores the
+// Principal in the context. It aborts the request and returns false when no
+// authenticator accepts the request.
+func authenticate(c *gin.Context) (*Principal, bool) {
+	if p, ok := CurrentPrincipal(c); ok {
+		return p, true
+	}
+
+	for _, authenticator := range authenticators {
+		p, err := authenticator.Authenticate(c)
+		if errors.Is(err, ErrNoCredentials) {
+			continue
+		}
+		if err != nil {
+			// Credentials were given but are wrong, don't fall back to other methods
+			middleware.AbortWithError(c, err)
+			return nil, false
+		}
+
+		SetPrincipal(c, p)
+		return p, true
+	}
+
+	middleware.AbortWithError(c, ErrNoCredentials)
+	return nil, false
+}
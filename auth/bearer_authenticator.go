@@ -0,0 +1,58 @@
+package auth
+
+import (
+	"strings"
+
+	"github.com/gin-gonic/gin"
+	"github.com/golang-jwt/jwt"
+)
+
+// BearerAuthenticator accepts the access tokens issued by GenerateJWT
+type BearerAuthenticator struct{}
+
+func (BearerAuthenticator) Authenticate(c *gin.Context) (*Principal, error) {
+	// Check if the token is prefixed with "Bearer"
+	authHeader := c.GetHeader("Authorization")
+	tokenString := strings.TrimPrefix(authHeader, "Bearer ")
+	if authHeader == "" || tokenString == authHeader {
+		return nil, ErrNoCredentials
+	}
+
+	// Validate the token
+	token, err := ValidateJWT(tokenString)
+	if err != nil || !token.Valid {
+		return nil, ErrInvalidToken
+	}
+	claims, ok := token.Claims.(jwt.MapClaims)
+	if !ok {
+		return nil, ErrInvalidToken
+	}
+
+	// Never trust the shape of the claims, a missing claim must not panic
+	userId := claimInt(claims, "user_id")
+	jti, _ := claims["jti"].(string)
+	email, _ := claims["email"].(string)
+	role, _ := claims["role"].(string)
+	if userId == 0 || jti == "" {
+		return nil, ErrInvalidToken
+	}
+
+	p := &Principal{
+		UserId:    userId,
+		Email:     email,
+		Method:    MethodBearer,
+		TokenId:   jti,
+		IssuedAt:  claimTime(claims, "iat"),
+		ExpiresAt: claimTime(claims, "exp"),
+	}
+	if role != "" {
+		p.Roles = []string{role}
+	}
+
+	// Reject tokens that were revoked before their expiry
+	revoked, err := revocationStore.IsRevoked(p.TokenId, p.UserId, p.IssuedAt)
+	if err != nil || revoked {
+		return nil, ErrTokenRevoked
+	}
+	return p, nil
+}
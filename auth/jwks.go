@@ -0,0 +1,38 @@
+package auth
+
+import (
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+)
+
+// JWKSHandler publishes the public signing keys so other services can verify our tokens
+func JWKSHandler(c *gin.Context) {
+	if keyring == nil {
+		c.JSON(http.StatusOK, JWKSet{Keys: []JWK{}})
+		return
+	}
+	c.Header("Cache-Control", "public, max-age=300")
+	c.JSON(http.StatusOK, keyring.JWKS())
+}
+
+// RotateKeysHandler generates a new signing key, the old one stays valid for its grace period
+func RotateKeysHandler(c *gin.Context) {
+	if keyring == nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "no signing key configured"})
+		return
+	}
+
+	key, err := keyring.Rotate()
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "could not rotate signing key"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"code":   http.StatusOK,
+		"status": "ok",
+		"data":   key.JWK(),
+		"msg":    "Signing key rotated successfully.",
+	})
+}
@@ -0,0 +1,264 @@
+package auth
+
+import (
+	"crypto/ed25519"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/json"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"math/big"
+	"os"
+	"sync"
+	"time"
+
+	"github.com/golang-jwt/jwt"
+)
+
+// Supported signing algorithms
+const (
+	AlgRS256 = "RS256"
+	AlgEdDSA = "EdDSA"
+)
+
+// SigningKey is an asymmetric key pair used to sign and verify access tokens
+type SigningKey struct {
+	Kid        string
+	Method     jwt.SigningMethod
+	PrivateKey interface{}
+	PublicKey  interface{}
+	RetiredAt  *time.Time // Set once a newer key has taken over signing
+}
+
+// Keyring holds the current signing key plus retired keys that are still
+// accepted for verification until their grace period has passed
+type Keyring struct {
+	mu      sync.RWMutex
+	current *SigningKey
+	keys    map[string]*SigningKey
+	grace   time.Duration
+}
+
+func NewKeyring(grace time.Duration) *Keyring {
+	return &Keyring{keys: make(map[string]*SigningKey), grace: grace}
+}
+
+// Add makes the key the current signing key and retires the previous one
+func (k *Keyring) Add(key *SigningKey) {
+	k.mu.Lock()
+	defer k.mu.Unlock()
+
+	if k.current != nil {
+		now := time.Now()
+		k.current.RetiredAt = &now
+	}
+	key.RetiredAt = nil
+	k.keys[key.Kid] = key
+	k.current = key
+	k.pruneLocked(time.Now())
+}
+
+// Rotate generates a new key with the same algorithm as the current one
+func (k *Keyring) Rotate() (*SigningKey, error) {
+	alg := AlgEdDSA
+	if current := k.Current(); current != nil {
+		alg = current.Method.Alg()
+	}
+
+	key, err := GenerateSigningKey(alg)
+	if err != nil {
+		return nil, err
+	}
+	k.Add(key)
+	return key, nil
+}
+
+// Current returns the key new tokens are signed with
+func (k *Keyring) Current() *SigningKey {
+	k.mu.RLock()
+	defer k.mu.RUnlock()
+	return k.current
+}
+
+// Lookup finds a key that is still valid for verification
+func (k *Keyring) Lookup(kid string) (*SigningKey, bool) {
+	k.mu.RLock()
+	defer k.mu.RUnlock()
+
+	key, ok := k.keys[kid]
+	if !ok || k.expired(key, time.Now()) {
+		return nil, false
+	}
+	return key, true
+}
+
+// Keys returns every key that is still valid for verification
+func (k *Keyring) Keys() []*SigningKey {
+	k.mu.RLock()
+	defer k.mu.RUnlock()
+
+	now := time.Now()
+	var keys []*SigningKey
+	for _, key := range k.keys {
+		if !k.expired(key, now) {
+			keys = append(keys, key)
+		}
+	}
+	return keys
+}
+
+func (k *Keyring) expired(key *SigningKey, now time.Time) bool {
+	return key.RetiredAt != nil && now.After(key.RetiredAt.Add(k.grace))
+}
+
+func (k *Keyring) pruneLocked(now time.Time) {
+	for kid, key := range k.keys {
+		if k.expired(key, now) {
+			delete(k.keys, kid)
+		}
+	}
+}
+
+// GenerateSigningKey creates a new random key pair for the algorithm
+func GenerateSigningKey(alg string) (*SigningKey, error) {
+	switch alg {
+	case AlgRS256:
+		privateKey, err := rsa.GenerateKey(rand.Reader, 2048)
+		if err != nil {
+			return nil, err
+		}
+		return newSigningKey(privateKey)
+	case AlgEdDSA:
+		_, privateKey, err := ed25519.GenerateKey(rand.Reader)
+		if err != nil {
+			return nil, err
+		}
+		return newSigningKey(privateKey)
+	default:
+		return nil, fmt.Errorf("unsupported signing algorithm %q", alg)
+	}
+}
+
+// LoadSigningKey reads a PKCS#1 or PKCS#8 encoded RSA or Ed25519 private key from a PEM file
+func LoadSigningKey(path string) (*SigningKey, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	block, _ := pem.Decode(data)
+	if block == nil {
+		return nil, fmt.Errorf("%s: no PEM data found", path)
+	}
+
+	var privateKey interface{}
+	switch block.Type {
+	case "RSA PRIVATE KEY":
+		privateKey, err = x509.ParsePKCS1PrivateKey(block.Bytes)
+	case "PRIVATE KEY":
+		privateKey, err = x509.ParsePKCS8PrivateKey(block.Bytes)
+	default:
+		return nil, fmt.Errorf("%s: unsupported PEM block %q", path, block.Type)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", path, err)
+	}
+
+	return newSigningKey(privateKey)
+}
+
+func newSigningKey(privateKey interface{}) (*SigningKey, error) {
+	key := &SigningKey{PrivateKey: privateKey}
+
+	switch private := privateKey.(type) {
+	case *rsa.PrivateKey:
+		key.Method = jwt.SigningMethodRS256
+		key.PublicKey = &private.PublicKey
+	case ed25519.PrivateKey:
+		key.Method = jwt.SigningMethodEdDSA
+		key.PublicKey = private.Public().(ed25519.PublicKey)
+	default:
+		return nil, errors.New("unsupported private key type")
+	}
+
+	kid, err := thumbprint(key.JWK())
+	if err != nil {
+		return nil, err
+	}
+	key.Kid = kid
+	return key, nil
+}
+
+// JWK is the public part of a signing key as described in RFC 7517
+type JWK struct {
+	Kty string `json:"kty"`
+	Kid string `json:"kid,omitempty"`
+	Use string `json:"use,omitempty"`
+	Alg string `json:"alg,omitempty"`
+	Crv string `json:"crv,omitempty"`
+	X   string `json:"x,omitempty"`
+	N   string `json:"n,omitempty"`
+	E   string `json:"e,omitempty"`
+}
+
+type JWKSet struct {
+	Keys []JWK `json:"keys"`
+}
+
+// JWK returns the public key in JWK format
+func (key *SigningKey) JWK() JWK {
+	jwk := JWK{Kid: key.Kid, Use: "sig", Alg: key.Method.Alg()}
+
+	switch public := key.PublicKey.(type) {
+	case *rsa.PublicKey:
+		jwk.Kty = "RSA"
+		jwk.N = base64.RawURLEncoding.EncodeToString(public.N.Bytes())
+		jwk.E = base64.RawURLEncoding.EncodeToString(big.NewInt(int64(public.E)).Bytes())
+	case ed25519.PublicKey:
+		jwk.Kty = "OKP"
+		jwk.Crv = "Ed25519"
+		jwk.X = base64.RawURLEncoding.EncodeToString(public)
+	}
+	return jwk
+}
+
+// JWKS returns every key still valid for verification
+func (k *Keyring) JWKS() JWKSet {
+	set := JWKSet{Keys: []JWK{}}
+	for _, key := range k.Keys() {
+		set.Keys = append(set.Keys, key.JWK())
+	}
+	return set
+}
+
+// thumbprint computes the RFC 7638 key id from the required JWK members
+func thumbprint(jwk JWK) (string, error) {
+	var members interface{}
+	switch jwk.Kty {
+	case "RSA":
+		members = struct {
+			E   string `json:"e"`
+			Kty string `json:"kty"`
+			N   string `json:"n"`
+		}{jwk.E, jwk.Kty, jwk.N}
+	case "OKP":
+		members = struct {
+			Crv string `json:"crv"`
+			Kty string `json:"kty"`
+			X   string `json:"x"`
+		}{jwk.Crv, jwk.Kty, jwk.X}
+	default:
+		return "", errors.New("unsupported key type")
+	}
+
+	data, err := json.Marshal(members)
+	if err != nil {
+		return "", err
+	}
+	sum := sha256.Sum256(data)
+	return base64.RawURLEncoding.EncodeToString(sum[:]), nil
+}
@@ -0,0 +1,100 @@
+package auth
+
+import (
+	"sync"
+	"time"
+)
+
+// PermissionStore looks up what a role is allowed to do
+type PermissionStore interface {
+	PermissionsForRole(role string) ([]string, error)
+	RoleExists(role string) (bool, error)
+}
+
+// PermissionCacheTTL is how long the permissions of a role are cached. Changes
+// made through the admin endpoints invalidate the cache right away, this only
+// bounds how long changes made elsewhere take to show up.
+var PermissionCacheTTL = time.Minute
+
+var (
+	permissionStore PermissionStore
+	permissionCache = struct {
+		sync.RWMutex
+		entries map[string]cachedPermissions
+	}{entries: make(map[string]cachedPermissions)}
+)
+
+type cachedPermissions struct {
+	permissions map[string]bool
+	loadedAt    time.Time
+}
+
+// UsePermissionStore sets where role permissions are looked up
+func UsePermissionStore(store PermissionStore) {
+	permissionStore = store
+	InvalidatePermissions()
+}
+
+// InvalidatePermissions drops every cached role, call it after changing grants
+func InvalidatePermissions() {
+	permissionCache.Lock()
+	defer permissionCache.Unlock()
+	permissionCache.entries = make(map[string]cachedPermissions)
+}
+
+// HasPermission reports whether the role was granted every one of the
+// permissions, itself or through a role below it in the hierarchy
+func HasPermission(role string, permissions ...string) (bool, error) {
+	granted, err := rolePermissions(role)
+	if err != nil {
+		return false, err
+	}
+	for _, permission := range permissions {
+		if !granted[permission] {
+			return false, nil
+		}
+	}
+	return true, nil
+}
+
+// IsKnownRole reports whether the role exists and can be given to users
+func IsKnownRole(role string) (bool, error) {
+	if permissionStore != nil {
+		return permissionStore.RoleExists(role)
+	}
+	rolesMu.RLock()
+	defer rolesMu.RUnlock()
+	return roleHierarchy.Known(role), nil
+}
+
+func rolePermissions(role string) (map[string]bool, error) {
+	permissionCache.RLock()
+	cached, ok := permissionCache.entries[role]
+	permissionCache.RUnlock()
+	if ok && time.Since(cached.loadedAt) < PermissionCacheTTL {
+		return cached.permissions, nil
+	}
+
+	rolesMu.RLock()
+	roles := append([]string{role}, roleHierarchy.Below(role)...)
+	rolesMu.RUnlock()
+
+	// Without a store nobody has any permission
+	granted := make(map[string]bool)
+	if permissionStore != nil {
+		for _, r := range roles {
+			names, err := permissionStore.PermissionsForRole(r)
+			if err != nil {
+				return nil, err
+			}
+			for _, name := range names {
+				granted[name] = true
+			}
+		}
+	}
+
+	permissionCache.Lock()
+	permissionCache.entries[role] = cachedPermissions{permissions: granted, loadedAt: time.Now()}
+	permissionCache.Unlock()
+	return granted, nil
+}
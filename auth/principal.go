@@ -0,0 +1,89 @@
+package auth
+
+import (
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+// Ways a caller can authenticate
+const (
+	MethodBearer  = "bearer"
+	MethodAPIKey  = "apiKey"
+	MethodSession = "session"
+)
+
+const principalKey = "principal"
+
+// Principal is the authenticated caller of a request
+type Principal struct {
+	UserId    int
+	Email     string
+	Roles     []string
+	Scopes    []string // Limits the roles' permissions, empty means no limit
+	Method    string
+	TokenId   string // jti of a bearer token
+	IssuedAt  time.Time
+	ExpiresAt time.Time
+}
+
+// SetPrincipal stores the authenticated caller in the request context
+func SetPrincipal(c *gin.Context, p *Principal) {
+	c.Set(principalKey, p)
+}
+
+// CurrentPrincipal returns the caller set by the auth middleware
+func CurrentPrincipal(c *gin.Context) (*Principal, bool) {
+	value, ok := c.Get(principalKey)
+	if !ok {
+		return nil, false
+	}
+	p, ok := value.(*Principal)
+	return p, ok
+}
+
+// HasRole reports whether one of the principal's roles satisfies one of the allowed roles
+func (p *Principal) HasRole(allowed ...string) bool {
+	for _, role := range p.Roles {
+		if HasRole(role, allowed...) {
+			return true
+		}
+	}
+	return false
+}
+
+// HasPermission reports whether the principal may do all of the given
+// actions. Each permission has to be granted to one of its roles and, for
+// scoped credentials like API keys, be one of its scopes.
+func (p *Principal) HasPermission(permissions ...string) (bool, error) {
+	for _, permission := range permissions {
+		if len(p.Scopes) > 0 && !contains(p.Scopes, permission) {
+			return false, nil
+		}
+
+		granted := false
+		for _, role := range p.Roles {
+			ok, err := HasPermission(role, permission)
+			if err != nil {
+				return false, err
+			}
+			if ok {
+				granted = true
+				break
+			}
+		}
+		if !granted {
+			return false, nil
+		}
+	}
+	return true, nil
+}
+
+func contains(values []string, value string) bool {
+	for _, v := range values {
+		if v == value {
+			return true
+		}
+	}
+	return false
+}
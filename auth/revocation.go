@@ -0,0 +1,117 @@
+package auth
+
+import (
+	"log"
+	"sync"
+	"time"
+
+	"example.com/go-project/helper"
+	"github.com/golang-jwt/jwt"
+)
+
+// RevocationStore keeps track of access tokens that were invalidated before their expiry
+type RevocationStore interface {
+	RevokeToken(jti string, userId int, expiresAt time.Time) error
+	RevokeUser(userId int, revokedAt time.Time, expiresAt time.Time) error
+	IsRevoked(jti string, userId int, issuedAt time.Time) (bool, error)
+	Prune(now time.Time) error
+}
+
+var revocationStore RevocationStore = NewMemoryRevocationStore()
+
+// UseRevocationStore replaces the default in-memory revocation store
+func UseRevocationStore(store RevocationStore) {
+	revocationStore = store
+}
+
+// RevokeUserSessions revokes every access token issued to the user so far
+func RevokeUserSessions(userId int) error {
+	now := time.Now()
+	// Tokens issued before now are all expired after one access token lifetime
+	return revocationStore.RevokeUser(userId, now, now.Add(AccessTokenTTL))
+}
+
+// StartRevocationPruning periodically removes revocations of expired tokens
+func StartRevocationPruning(interval time.Duration) (stop func()) {
+	return helper.StartPeriodic(interval, func(now time.Time) {
+		if err := revocationStore.Prune(now); err != nil {
+			log.Println("Error pruning revoked tokens:", err)
+		}
+	})
+}
+
+// MemoryRevocationStore keeps revocations in process memory, suitable for a single instance
+type MemoryRevocationStore struct {
+	mu     sync.RWMutex
+	tokens map[string]time.Time
+	users  map[int]userRevocation
+}
+
+type userRevocation struct {
+	revokedAt time.Time
+	expiresAt time.Time
+}
+
+func NewMemoryRevocationStore() *MemoryRevocationStore {
+	return &MemoryRevocationStore{
+		tokens: make(map[string]time.Time),
+		users:  make(map[int]userRevocation),
+	}
+}
+
+func (m *MemoryRevocationStore) RevokeToken(jti string, userId int, expiresAt time.Time) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.tokens[jti] = expiresAt
+	return nil
+}
+
+func (m *MemoryRevocationStore) RevokeUser(userId int, revokedAt time.Time, expiresAt time.Time) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.users[userId] = userRevocation{revokedAt: revokedAt, expiresAt: expiresAt}
+	return nil
+}
+
+func (m *MemoryRevocationStore) IsRevoked(jti string, userId int, issuedAt time.Time) (bool, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	if _, ok := m.tokens[jti]; ok {
+		return true, nil
+	}
+	if revocation, ok := m.users[userId]; ok {
+		return issuedAt.Before(revocation.revokedAt), nil
+	}
+	return false, nil
+}
+
+func (m *MemoryRevocationStore) Prune(now time.Time) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	for jti, expiresAt := range m.tokens {
+		if expiresAt.Before(now) {
+			delete(m.tokens, jti)
+		}
+	}
+	for userId, revocation := range m.users {
+		if revocation.expiresAt.Before(now) {
+			delete(m.users, userId)
+		}
+	}
+	return nil
+}
+
+// claimInt reads a numeric claim, JSON numbers are decoded as float64
+func claimInt(claims jwt.MapClaims, name string) int {
+	if value, ok := claims[name].(float64); ok {
+		return int(value)
+	}
+	return 0
+}
+
+func claimTime(claims jwt.MapClaims, name string) time.Time {
+	if value, ok := claims[name].(float64); ok {
+		return time.Unix(int64(value), 0)
+	}
+	return time.Time{}
+}
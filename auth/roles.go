@@ -0,0 +1,81 @@
+package auth
+
+import "sync"
+
+// DefaultRoles is the role hierarchy used when none is configured, highest first
+var DefaultRoles = []string{"Admin", "Editor", "User", "Viewer"}
+
+// RoleHierarchy ranks roles so that a higher role satisfies every requirement
+// of the roles below it, e.g. an Admin may do anything a User may do
+type RoleHierarchy struct {
+	rank map[string]int
+}
+
+// NewRoleHierarchy builds a hierarchy from roles ordered from highest to lowest
+func NewRoleHierarchy(roles []string) *RoleHierarchy {
+	h := &RoleHierarchy{rank: make(map[string]int, len(roles))}
+	for i, role := range roles {
+		h.rank[role] = len(roles) - i
+	}
+	return h
+}
+
+// Known reports whether the role is part of the hierarchy
+func (h *RoleHierarchy) Known(role string) bool {
+	_, ok := h.rank[role]
+	return ok
+}
+
+// Satisfies reports whether role is the required role or ranks above it
+func (h *RoleHierarchy) Satisfies(role string, required string) bool {
+	if role == required {
+		return true
+	}
+	have, ok := h.rank[role]
+	if !ok {
+		return false
+	}
+	want, ok := h.rank[required]
+	return ok && have >= want
+}
+
+// Below returns the roles ranked under role, their permissions are inherited by it
+func (h *RoleHierarchy) Below(role string) []string {
+	have, ok := h.rank[role]
+	if !ok {
+		return nil
+	}
+	var below []string
+	for other, rank := range h.rank {
+		if rank < have {
+			below = append(below, other)
+		}
+	}
+	return below
+}
+
+var (
+	rolesMu       sync.RWMutex
+	roleHierarchy = NewRoleHierarchy(DefaultRoles)
+)
+
+// UseRoleHierarchy sets the hierarchy used by HasRole and to inherit permissions
+func UseRoleHierarchy(h *RoleHierarchy) {
+	rolesMu.Lock()
+	roleHierarchy = h
+	rolesMu.Unlock()
+	InvalidatePermissions()
+}
+
+// HasRole reports whether role satisfies at least one of the allowed roles
+func HasRole(role string, allowed ...string) bool {
+	rolesMu.RLock()
+	defer rolesMu.RUnlock()
+
+	for _, required := range allowed {
+		if roleHierarchy.Satisfies(role, required) {
+			return true
+		}
+	}
+	return false
+}
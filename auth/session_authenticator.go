@@ -0,0 +1,82 @@
+package auth
+
+import (
+	"time"
+
+	"example.com/go-project/model"
+	"example.com/go-project/services"
+	"github.com/gin-gonic/gin"
+	"github.com/gorilla/sessions"
+)
+
+// SessionName is the cookie browser sessions are kept in
+const SessionName = "go-project-session"
+
+// SessionAuthenticator accepts the session cookie set after the Google login.
+// The role is read from the database on every request.
+type SessionAuthenticator struct {
+	Store sessions.Store
+	Users *services.UsersService
+}
+
+func (a SessionAuthenticator) Authenticate(c *gin.Context) (*Principal, error) {
+	session, err := a.Store.Get(c.Request, SessionName)
+	if err != nil || session.IsNew {
+		return nil, ErrNoCredentials
+	}
+	userId, ok := session.Values["user_id"].(int)
+	if !ok {
+		return nil, ErrNoCredentials
+	}
+	issuedAt, _ := session.Values["issued_at"].(int64)
+
+	// Revoking all sessions of a user covers cookies too
+	revoked, err := revocationStore.IsRevoked("", userId, time.Unix(issuedAt, 0))
+	if err != nil || revoked {
+		return nil, ErrTokenRevoked
+	}
+
+	user, err := a.Users.FindUserById(userId)
+	if err != nil || user == nil {
+		return nil, ErrInvalidToken
+	}
+
+	return &Principal{
+		UserId:   user.Id,
+		Email:    user.Email,
+		Roles:    []string{user.Role},
+		Method:   MethodSession,
+		IssuedAt: time.Unix(issuedAt, 0),
+	}, nil
+}
+
+// StartSession logs the user in with a session cookie
+func StartSession(c *gin.Context, store sessions.Store, user *model.Users) error {
+	session, err := store.New(c.Request, SessionName)
+	if err != nil && session == nil {
+		return err
+	}
+	session.Values["user_id"] = user.Id
+	session.Values["issued_at"] = time.Now().Unix()
+	return session.Save(c.Request, c.Writer)
+}
+
+// EndSession logs the principal out, the access token or session cookie it
+// authenticated with stops working. API keys stay valid until they are revoked.
+func EndSession(c *gin.Context, p *Principal) error {
+	switch p.Method {
+	case MethodBearer:
+		return revocationStore.RevokeToken(p.TokenId, p.UserId, p.ExpiresAt)
+	case MethodSession:
+		if sessionStore == nil {
+			return nil
+		}
+		session, err := sessionStore.Get(c.Request, SessionName)
+		if err != nil {
+			return err
+		}
+		session.Options.MaxAge = -1
+		return session.Save(c.Request, c.Writer)
+	}
+	return nil
+}
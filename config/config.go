@@ -0,0 +1,389 @@
+package config
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"os"
+	"reflect"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/joho/godotenv"
+	"gopkg.in/yaml.v3"
+)
+
+// Config is the whole application configuration. Every field can be set from
+// the YAML config file, an environment variable and a command line flag, in
+// increasing order of precedence.
+type Config struct {
+	Server   ServerConfig   `yaml:"server"`
+	Database DatabaseConfig `yaml:"database"`
+	Auth     AuthConfig     `yaml:"auth"`
+	API      APIConfig      `yaml:"api"`
+	Trash    TrashConfig    `yaml:"trash"`
+	Login    LoginConfig    `yaml:"login"`
+}
+
+type ServerConfig struct {
+	Host string `yaml:"host" env:"SERVER_HOST" flag:"host" usage:"interface to listen on"`
+	Port int    `yaml:"port" env:"SERVER_PORT" flag:"port" usage:"port to listen on"`
+}
+
+// Addr returns the address the server listens on
+func (s ServerConfig) Addr() string {
+	return fmt.Sprintf("%s:%d", s.Host, s.Port)
+}
+
+type DatabaseConfig struct {
+	Driver          string        `yaml:"driver" env:"DB_DRIVER" flag:"db-driver" usage:"postgres, sqlite or sqlite-memory"`
+	Path            string        `yaml:"path" env:"DB_PATH" flag:"db-path" usage:"database file for the sqlite driver"`
+	Host            string        `yaml:"host" env:"DB_HOST" flag:"db-host" usage:"database host"`
+	Port            int           `yaml:"port" env:"DB_PORT" flag:"db-port" usage:"database port"`
+	User            string        `yaml:"user" env:"DB_USER" flag:"db-user" usage:"database user"`
+	Password        string        `yaml:"password" env:"DB_PASSWORD" flag:"db-password" usage:"database password"`
+	Name            string        `yaml:"name" env:"DB_NAME" flag:"db-name" usage:"database name"`
+	SSLMode         string        `yaml:"sslMode" env:"DB_SSLMODE" flag:"db-sslmode" usage:"postgres sslmode"`
+	MaxOpenConns    int           `yaml:"maxOpenConns" env:"DB_MAX_OPEN_CONNS" flag:"db-max-open-conns" usage:"maximum open connections"`
+	MaxIdleConns    int           `yaml:"maxIdleConns" env:"DB_MAX_IDLE_CONNS" flag:"db-max-idle-conns" usage:"maximum idle connections"`
+	ConnMaxLifetime time.Duration `yaml:"connMaxLifetime" env:"DB_CONN_MAX_LIFETIME" flag:"db-conn-max-lifetime" usage:"maximum lifetime of a connection"`
+	MigrateOnStart  bool          `yaml:"migrateOnStart" env:"DB_MIGRATE_ON_START" flag:"migrate-on-start" usage:"apply pending migrations when the server starts"`
+}
+
+type AuthConfig struct {
+	CookieKey          string        `yaml:"cookieKey" env:"AUTH_COOKIE_KEY" flag:"cookie-key" usage:"secret used to sign session cookies"`
+	SessionMaxAge      int           `yaml:"sessionMaxAge" env:"AUTH_SESSION_MAX_AGE" flag:"session-max-age" usage:"session cookie lifetime in seconds"`
+	IsProd             bool          `yaml:"isProd" env:"AUTH_IS_PROD" flag:"prod" usage:"serve secure cookies only"`
+	OAuthCallbackURL   string        `yaml:"oauthCallbackUrl" env:"AUTH_OAUTH_CALLBACK_URL" flag:"oauth-callback-url" usage:"Google OAuth callback URL"`
+	GoogleClientID     string        `yaml:"googleClientId" env:"GOOGLE_CLIENT_ID" flag:"google-client-id" usage:"Google OAuth client id"`
+	GoogleClientSecret string        `yaml:"googleClientSecret" env:"GOOGLE_CLIENT_SECRET" flag:"google-client-secret" usage:"Google OAuth client secret"`
+	AccessTokenTTL     time.Duration `yaml:"accessTokenTtl" env:"AUTH_ACCESS_TOKEN_TTL" flag:"access-token-ttl" usage:"access token lifetime"`
+	RefreshTokenTTL    time.Duration `yaml:"refreshTokenTtl" env:"AUTH_REFRESH_TOKEN_TTL" flag:"refresh-token-ttl" usage:"refresh token lifetime"`
+	SigningAlg         string        `yaml:"signingAlg" env:"JWT_SIGNING_ALG" flag:"signing-alg" usage:"RS256 or EdDSA, used when keys are generated"`
+	PrivateKeyFiles    []string      `yaml:"privateKeyFiles" env:"JWT_PRIVATE_KEY_FILES" flag:"private-key-files" usage:"comma separated PEM signing keys, the last one is current"`
+	RevocationStore    string        `yaml:"revocationStore" env:"AUTH_REVOCATION_STORE" flag:"revocation-store" usage:"memory or database"`
+	Roles              []string      `yaml:"roles" env:"AUTH_ROLES" flag:"roles" usage:"comma separated role hierarchy, highest first"`
+	Authenticators     []string      `yaml:"authenticators" env:"AUTH_AUTHENTICATORS" flag:"authenticators" usage:"comma separated authentication methods tried in order: bearer, apiKey, session"`
+	PermissionCacheTTL time.Duration `yaml:"permissionCacheTtl" env:"AUTH_PERMISSION_CACHE_TTL" flag:"permission-cache-ttl" usage:"how long role permissions are cached"`
+}
+
+type APIConfig struct {
+	DefaultPageSize int    `yaml:"defaultPageSize" env:"API_DEFAULT_PAGE_SIZE" flag:"default-page-size" usage:"page size of listings when the client doesn't ask for one"`
+	MaxPageSize     int    `yaml:"maxPageSize" env:"API_MAX_PAGE_SIZE" flag:"max-page-size" usage:"largest page size a client can ask for"`
+	CursorSecret    string `yaml:"cursorSecret" env:"API_CURSOR_SECRET" flag:"cursor-secret" usage:"secret pagination cursors are signed with, generated when empty"`
+	RequireIfMatch  bool   `yaml:"requireIfMatch" env:"API_REQUIRE_IF_MATCH" flag:"require-if-match" usage:"reject updates and deletes without an If-Match header"`
+}
+
+// TrashConfig controls how long deleted tags and neches can be restored
+type TrashConfig struct {
+	Retention     time.Duration `yaml:"retention" env:"TRASH_RETENTION" flag:"trash-retention" usage:"how long deleted tags and neches are kept before they are purged"`
+	PurgeInterval time.Duration `yaml:"purgeInterval" env:"TRASH_PURGE_INTERVAL" flag:"trash-purge-interval" usage:"how often expired trash is purged"`
+}
+
+// LoginConfig slows down password guessing. Every failed login doubles the
+// wait before the next attempt, until the account or the address is locked.
+type LoginConfig struct {
+	MaxFailures     int           `yaml:"maxFailures" env:"LOGIN_MAX_FAILURES" flag:"login-max-failures" usage:"failed logins of an account before it is locked"`
+	MaxIPFailures   int           `yaml:"maxIpFailures" env:"LOGIN_MAX_IP_FAILURES" flag:"login-max-ip-failures" usage:"failed logins from an address before it is locked"`
+	BackoffBase     time.Duration `yaml:"backoffBase" env:"LOGIN_BACKOFF_BASE" flag:"login-backoff-base" usage:"wait after the first failed login, doubled by every further one"`
+	BackoffMax      time.Duration `yaml:"backoffMax" env:"LOGIN_BACKOFF_MAX" flag:"login-backoff-max" usage:"longest wait between failed logins"`
+	LockoutDuration time.Duration `yaml:"lockoutDuration" env:"LOGIN_LOCKOUT_DURATION" flag:"login-lockout-duration" usage:"how long a locked account or address can't log in"`
+	Window          time.Duration `yaml:"window" env:"LOGIN_WINDOW" flag:"login-window" usage:"failed logins are forgotten after this long without another one"`
+	PruneInterval   time.Duration `yaml:"pruneInterval" env:"LOGIN_PRUNE_INTERVAL" flag:"login-prune-interval" usage:"how often forgotten failed logins are pruned"`
+}
+
+// Default returns the configuration used for anything that isn't set explicitly
+func Default() Config {
+	return Config{
+		Server: ServerConfig{
+			Port: 8888,
+		},
+		Database: DatabaseConfig{
+			Driver:          DriverPostgres,
+			Path:            "go-project.db",
+			Host:            "localhost",
+			Port:            5432,
+			User:            "root",
+			Name:            "test",
+			SSLMode:         "disable",
+			MaxOpenConns:    100,
+			MaxIdleConns:    10,
+			ConnMaxLifetime: time.Hour,
+			MigrateOnStart:  true,
+		},
+		Auth: AuthConfig{
+			SessionMaxAge:      86400 * 30,
+			OAuthCallbackURL:   "http://localhost:8888/auth/callback/google",
+			AccessTokenTTL:     15 * time.Minute,
+			RefreshTokenTTL:    30 * 24 * time.Hour,
+			SigningAlg:         "EdDSA",
+			RevocationStore:    "database",
+			Roles:              []string{"Admin", "Editor", "User", "Viewer"},
+			PermissionCacheTTL: time.Minute,
+			Authenticators:     []string{"bearer", "apiKey", "session"},
+		},
+		API: APIConfig{
+			DefaultPageSize: 10,
+			MaxPageSize:     100,
+			RequireIfMatch:  true,
+		},
+		Trash: TrashConfig{
+			Retention:     30 * 24 * time.Hour,
+			PurgeInterval: time.Hour,
+		},
+		Login: LoginConfig{
+			MaxFailures:     5,
+			MaxIPFailures:   20,
+			BackoffBase:     time.Second,
+			BackoffMax:      time.Minute,
+			LockoutDuration: 15 * time.Minute,
+			Window:          15 * time.Minute,
+			PruneInterval:   time.Hour,
+		},
+	}
+}
+
+// Load builds the configuration from the defaults, the config file, the
+// environment (including a .env file) and the command line flags in args.
+// The config file is given with -config or APP_CONFIG_FILE. The arguments
+// left after the flags are returned as well.
+func Load(args []string) (*Config, []string, error) {
+	cfg := Default()
+
+	// A missing .env file is fine, the variables may come from the real environment
+	_ = godotenv.Load()
+
+	flags := flag.NewFlagSet("go-project", flag.ContinueOnError)
+	configFile := flags.String("config", os.Getenv("APP_CONFIG_FILE"), "path to a YAML config file")
+	fields := collectFields(reflect.ValueOf(&cfg).Elem())
+	for _, field := range fields {
+		switch {
+		case field.flag == "":
+		case field.value.Kind() == reflect.Bool:
+			flags.Bool(field.flag, false, field.usage)
+		default:
+			flags.String(field.flag, "", field.usage)
+		}
+	}
+	if err := flags.Parse(args); err != nil {
+		return nil, nil, err
+	}
+
+	if *configFile != "" {
+		data, err := os.ReadFile(*configFile)
+		if err != nil {
+			return nil, nil, fmt.Errorf("reading config file: %w", err)
+		}
+		if err := yaml.Unmarshal(data, &cfg); err != nil {
+			return nil, nil, fmt.Errorf("parsing config file %s: %w", *configFile, err)
+		}
+	}
+
+	for _, field := range fields {
+		if raw, ok := os.LookupEnv(field.env); ok && field.env != "" {
+			if err := setField(field.value, raw); err != nil {
+				return nil, nil, fmt.Errorf("environment variable %s: %w", field.env, err)
+			}
+		}
+	}
+
+	var flagErr error
+	flags.Visit(func(f *flag.Flag) {
+		for _, field := range fields {
+			if field.flag == f.Name && flagErr == nil {
+				if err := setField(field.value, f.Value.String()); err != nil {
+					flagErr = fmt.Errorf("flag -%s: %w", f.Name, err)
+				}
+			}
+		}
+	})
+	if flagErr != nil {
+		return nil, nil, flagErr
+	}
+
+	if err := cfg.Validate(); err != nil {
+		return nil, nil, err
+	}
+	return &cfg, flags.Args(), nil
+}
+
+// Validate checks the configuration so the server fails at startup rather than on first use
+func (c *Config) Validate() error {
+	var problems []string
+
+	if c.Server.Port < 1 || c.Server.Port > 65535 {
+		problems = append(problems, "server.port must be between 1 and 65535")
+	}
+
+	switch c.Database.Driver {
+	case DriverPostgres:
+		if c.Database.Password == "" {
+			problems = append(problems, "database.password is required (DB_PASSWORD)")
+		}
+		if c.Database.Host == "" || c.Database.Name == "" || c.Database.User == "" {
+			problems = append(problems, "database.host, database.name and database.user are required")
+		}
+	case DriverSQLite:
+		if c.Database.Path == "" {
+			problems = append(problems, "database.path is required for the sqlite driver (DB_PATH)")
+		}
+	case DriverSQLiteMemory:
+	default:
+		problems = append(problems, "database.driver must be postgres, sqlite or sqlite-memory")
+	}
+
+	if len(c.Auth.CookieKey) < 32 {
+		problems = append(problems, "auth.cookieKey is required and must be at least 32 characters (AUTH_COOKIE_KEY)")
+	}
+	if (c.Auth.GoogleClientID == "") != (c.Auth.GoogleClientSecret == "") {
+		problems = append(problems, "auth.googleClientId and auth.googleClientSecret must be set together")
+	}
+	if c.Auth.AccessTokenTTL <= 0 || c.Auth.RefreshTokenTTL <= 0 {
+		problems = append(problems, "auth.accessTokenTtl and auth.refreshTokenTtl must be positive")
+	}
+	if c.Auth.PermissionCacheTTL < 0 {
+		problems = append(problems, "auth.permissionCacheTtl must not be negative")
+	}
+	if c.Auth.SigningAlg != "RS256" && c.Auth.SigningAlg != "EdDSA" {
+		problems = append(problems, "auth.signingAlg must be RS256 or EdDSA")
+	}
+	if c.Auth.RevocationStore != "memory" && c.Auth.RevocationStore != "database" {
+		problems = append(problems, "auth.revocationStore must be memory or database")
+	}
+	if !containsAll(c.Auth.Roles, "Admin", "User") {
+		problems = append(problems, "auth.roles must include the built-in Admin and User roles")
+	}
+	if len(c.Auth.Authenticators) == 0 {
+		problems = append(problems, "auth.authenticators must list at least one method")
+	}
+	for _, method := range c.Auth.Authenticators {
+		if method != "bearer" && method != "apiKey" && method != "session" {
+			problems = append(problems, fmt.Sprintf("auth.authenticators: unknown method %q, use bearer, apiKey or session", method))
+		}
+	}
+	if hasDuplicates(c.Auth.Roles) {
+		problems = append(problems, "auth.roles must not list a role twice")
+	}
+
+	if c.API.DefaultPageSize < 1 || c.API.MaxPageSize < c.API.DefaultPageSize {
+		problems = append(problems, "api.defaultPageSize must be positive and api.maxPageSize at least as large")
+	}
+	if c.API.CursorSecret != "" && len(c.API.CursorSecret) < 32 {
+		problems = append(problems, "api.cursorSecret must be at least 32 characters (API_CURSOR_SECRET)")
+	}
+
+	if c.Trash.Retention <= 0 || c.Trash.PurgeInterval <= 0 {
+		problems = append(problems, "trash.retention and trash.purgeInterval must be positive")
+	}
+
+	if c.Login.MaxFailures < 1 || c.Login.MaxIPFailures < 1 {
+		problems = append(problems, "login.maxFailures and login.maxIpFailures must be positive")
+	}
+	if c.Login.BackoffBase <= 0 || c.Login.BackoffMax < c.Login.BackoffBase {
+		problems = append(problems, "login.backoffBase must be positive and login.backoffMax at least as long")
+	}
+	if c.Login.LockoutDuration <= 0 || c.Login.Window <= 0 || c.Login.PruneInterval <= 0 {
+		problems = append(problems, "login.lockoutDuration, login.window and login.pruneInterval must be positive")
+	}
+
+	if len(problems) > 0 {
+		return errors.New("invalid configuration:\n  " + strings.Join(problems, "\n  "))
+	}
+	return nil
+}
+
+func containsAll(values []string, wanted ...string) bool {
+	for _, w := range wanted {
+		found := false
+		for _, v := range values {
+			if v == w {
+				found = true
+				break
+			}
+		}
+		if !found {
+			return false
+		}
+	}
+	return true
+}
+
+func hasDuplicates(values []string) bool {
+	seen := make(map[string]bool, len(values))
+	for _, v := range values {
+		if seen[v] {
+			return true
+		}
+		seen[v] = true
+	}
+	return false
+}
+
+type configField struct {
+	value reflect.Value
+	env   string
+	flag  string
+	usage string
+}
+
+// collectFields walks the config struct and returns every settable leaf field
+func collectFields(v reflect.Value) []configField {
+	var fields []configField
+	for i := 0; i < v.NumField(); i++ {
+		field := v.Type().Field(i)
+		if field.Type.Kind() == reflect.Struct {
+			fields = append(fields, collectFields(v.Field(i))...)
+			continue
+		}
+		fields = append(fields, configField{
+			value: v.Field(i),
+			env:   field.Tag.Get("env"),
+			flag:  field.Tag.Get("flag"),
+			usage: field.Tag.Get("usage"),
+		})
+	}
+	return fields
+}
+
+// setField parses raw into the field according to its type
+func setField(v reflect.Value, raw string) error {
+	switch v.Interface().(type) {
+	case time.Duration:
+		d, err := time.ParseDuration(raw)
+		if err != nil {
+			return err
+		}
+		v.SetInt(int64(d))
+		return nil
+	case []string:
+		var values []string
+		for _, value := range strings.Split(raw, ",") {
+			if value = strings.TrimSpace(value); value != "" {
+				values = append(values, value)
+			}
+		}
+		v.Set(reflect.ValueOf(values))
+		return nil
+	}
+
+	switch v.Kind() {
+	case reflect.String:
+		v.SetString(raw)
+	case reflect.Int:
+		n, err := strconv.Atoi(raw)
+		if err != nil {
+			return err
+		}
+		v.SetInt(int64(n))
+	case reflect.Bool:
+		b, err := strconv.ParseBool(raw)
+		if err != nil {
+			return err
+		}
+		v.SetBool(b)
+	default:
+		return fmt.Errorf("unsupported config type %s", v.Type())
+	}
+	return nil
+}
@@ -8,22 +8,19 @@ import (
 
 	"example.com/go-project/helper"
 	"gorm.io/driver/postgres"
+	"gorm.io/driver/sqlite"
 	"gorm.io/gorm"
 	"gorm.io/gorm/logger"
 )
 
+// Supported database drivers
 const (
-	host     = "localhost" // adjust as needed
-	port     = 5432        // adjust as needed
-	user     = "root"      // adjust as needed
-	password = "root"      // adjust as needed
-	dbname   = "test"      // adjust as needed
+	DriverPostgres     = "postgres"
+	DriverSQLite       = "sqlite"
+	DriverSQLiteMemory = "sqlite-memory"
 )
 
-func DatabaseConnection() *gorm.DB {
-	// Create the PostgreSQL connection string (DSN)
-	sqlInfo := fmt.Sprintf("host=%s port=%d user=%s password=%s dbname=%s sslmode=disable", host, port, user, password, dbname)
-
+func DatabaseConnection(cfg DatabaseConfig) *gorm.DB {
 	// Set up custom logger for GORM
 	newLogger := logger.New(
 		log.New(os.Stdout, "\r\n", log.LstdFlags), // Output to Stdout
@@ -35,24 +32,70 @@ func DatabaseConnection() *gorm.DB {
 		},
 	)
 
-	// Connect to the database
-	db, err := gorm.Open(postgres.Open(sqlInfo), &gorm.Config{
+	db, err := OpenDatabase(cfg, &gorm.Config{
 		Logger: newLogger, // Attach the custom logger
 	})
 	helper.ErrorPanic(err)
+	return db
+}
+
+// OpenDatabase connects to the database of the configured driver and sets up the connection pool
+func OpenDatabase(cfg DatabaseConfig, gormConfig *gorm.Config) (*gorm.DB, error) {
+	dialector, err := newDialector(cfg)
+	if err != nil {
+		return nil, err
+	}
+
+	// Report constraint violations as gorm.ErrDuplicatedKey and friends on every driver
+	gormConfig.TranslateError = true
+
+	// Connect to the database
+	db, err := gorm.Open(dialector, gormConfig)
+	if err != nil {
+		return nil, err
+	}
 
 	// Optional: Setup connection pooling (highly recommended for production)
 	sqlDB, err := db.DB()
-	helper.ErrorPanic(err)
+	if err != nil {
+		return nil, err
+	}
+
+	if cfg.Driver == DriverSQLiteMemory {
+		// Every connection to ":memory:" is a new empty database, so keep
+		// exactly one connection open for the lifetime of the process
+		sqlDB.SetMaxOpenConns(1)
+		sqlDB.SetMaxIdleConns(1)
+		sqlDB.SetConnMaxLifetime(0)
+		return db, nil
+	}
 
 	// Set max open connections to the database
-	sqlDB.SetMaxOpenConns(100)
+	sqlDB.SetMaxOpenConns(cfg.MaxOpenConns)
 
 	// Set max idle connections
-	sqlDB.SetMaxIdleConns(10)
+	sqlDB.SetMaxIdleConns(cfg.MaxIdleConns)
 
 	// Set max lifetime of a connection
-	sqlDB.SetConnMaxLifetime(time.Hour)
+	sqlDB.SetConnMaxLifetime(cfg.ConnMaxLifetime)
 
-	return db
+	return db, nil
+}
+
+func newDialector(cfg DatabaseConfig) (gorm.Dialector, error) {
+	switch cfg.Driver {
+	case DriverPostgres:
+		// Create the PostgreSQL connection string (DSN)
+		sqlInfo := fmt.Sprintf("host=%s port=%d user=%s password=%s dbname=%s sslmode=%s", cfg.Host, cfg.Port, cfg.User, cfg.Password, cfg.Name, cfg.SSLMode)
+		return postgres.Open(sqlInfo), nil
+	case DriverSQLite:
+		// SQLite ignores foreign keys unless asked per connection, which would
+		// break the ON DELETE CASCADE constraints. WAL and a busy timeout let
+		// readers and the single writer get along.
+		return sqlite.Open(fmt.Sprintf("file:%s?_foreign_keys=on&_journal_mode=WAL&_busy_timeout=5000", cfg.Path)), nil
+	case DriverSQLiteMemory:
+		return sqlite.Open("file::memory:?_foreign_keys=on"), nil
+	default:
+		return nil, fmt.Errorf("unsupported database driver %q", cfg.Driver)
+	}
 }
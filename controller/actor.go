@@ -0,0 +1,16 @@
+package controller
+
+import (
+	"example.com/go-project/auth"
+	"github.com/gin-gonic/gin"
+)
+
+// actorId is the user the request acts for, revisions record it. 0 when the
+// route isn't authenticated.
+func actorId(ctx *gin.Context) int {
+	principal, ok := auth.CurrentPrincipal(ctx)
+	if !ok {
+		return 0
+	}
+	return principal.UserId
+}
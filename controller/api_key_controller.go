@@ -0,0 +1,97 @@
+package controller
+
+import (
+	"net/http"
+	"strconv"
+
+	"example.com/go-project/auth"
+	"example.com/go-project/data/request"
+	"example.com/go-project/data/response"
+	"example.com/go-project/helper"
+	"example.com/go-project/services"
+	"github.com/gin-gonic/gin"
+)
+
+// ApiKeysController lets users manage the API keys of their own account
+type ApiKeysController struct {
+	apiKeyService *services.ApiKeyService
+}
+
+func NewApiKeysController(service *services.ApiKeyService) *ApiKeysController {
+	return &ApiKeysController{apiKeyService: service}
+}
+
+func (controller *ApiKeysController) Create(ctx *gin.Context) {
+	var keyRequest request.CreateApiKeyRequest
+	if err := ctx.ShouldBindJSON(&keyRequest); err != nil {
+		ctx.Error(helper.BadRequest("Invalid request body: %s", err))
+		return
+	}
+
+	// A key can't do more than the user creating it
+	principal, _ := auth.CurrentPrincipal(ctx)
+	allowed, err := principal.HasPermission(keyRequest.Scopes...)
+	if err != nil {
+		ctx.Error(err)
+		return
+	}
+	if !allowed {
+		ctx.Error(helper.Forbidden("Forbidden: scopes exceed your permissions"))
+		return
+	}
+
+	raw, key, err := controller.apiKeyService.Create(principal.UserId, keyRequest)
+	if err != nil {
+		ctx.Error(err)
+		return
+	}
+
+	data := response.NewApiKeyResponse(key)
+	data.Key = raw
+	ctx.JSON(http.StatusOK, response.Response{
+		Code:   http.StatusOK,
+		Status: "ok",
+		Data:   data,
+		Msg:    "API key created, it won't be shown again.",
+	})
+}
+
+func (controller *ApiKeysController) FindAll(ctx *gin.Context) {
+	principal, _ := auth.CurrentPrincipal(ctx)
+	keys, err := controller.apiKeyService.FindByUser(principal.UserId)
+	if err != nil {
+		ctx.Error(err)
+		return
+	}
+
+	data := []response.ApiKeyResponse{}
+	for i := range keys {
+		data = append(data, response.NewApiKeyResponse(&keys[i]))
+	}
+	ctx.JSON(http.StatusOK, response.Response{
+		Code:   http.StatusOK,
+		Status: "ok",
+		Data:   data,
+	})
+}
+
+func (controller *ApiKeysController) Revoke(ctx *gin.Context) {
+	keyId, err := strconv.Atoi(ctx.Param("keyId"))
+	if err != nil {
+		ctx.Error(helper.BadRequest("Invalid key ID: %s", ctx.Param("keyId")))
+		return
+	}
+
+	principal, _ := auth.CurrentPrincipal(ctx)
+	err = controller.apiKeyService.Revoke(keyId, principal.UserId)
+	if err != nil {
+		ctx.Error(err)
+		return
+	}
+
+	ctx.JSON(http.StatusOK, response.Response{
+		Code:   http.StatusOK,
+		Status: "ok",
+		Msg:    "API key revoked.",
+	})
+}
@@ -0,0 +1,70 @@
+package controller
+
+import (
+	"log"
+	"net/http"
+
+	"example.com/go-project/data/request"
+	"example.com/go-project/helper"
+	"example.com/go-project/services"
+	"github.com/gin-gonic/gin"
+)
+
+// AuditController serves the audit log to admins
+type AuditController struct {
+	auditService *services.AuditService
+}
+
+func NewAuditController(service *services.AuditService) *AuditController {
+	return &AuditController{auditService: service}
+}
+
+// FindAll lists the audit log with pagination, newest first, filtered by
+// userId, action and a from/to time range
+func (controller *AuditController) FindAll(ctx *gin.Context) {
+	page := paginationParams(ctx)
+	queryRequest, ok := auditQuery(ctx)
+	if !ok {
+		return
+	}
+	queryRequest.PageRequest = page.Request()
+
+	events, err := controller.auditService.Find(queryRequest)
+	if err != nil {
+		ctx.Error(err)
+		return
+	}
+	respondPage(ctx, page, events.Events, events.Total, events.Cursors, "Fetched audit events successfully.")
+}
+
+// Export streams every event matching the filters as JSON Lines, oldest first
+func (controller *AuditController) Export(ctx *gin.Context) {
+	queryRequest, ok := auditQuery(ctx)
+	if !ok {
+		return
+	}
+
+	ctx.Header("Content-Type", "application/x-ndjson")
+	ctx.Header("Content-Disposition", `attachment; filename="audit.jsonl"`)
+	ctx.Status(http.StatusOK)
+	err := controller.auditService.Export(queryRequest, ctx.Writer)
+	if err != nil && !ctx.Writer.Written() {
+		// The filters were refused, the error replaces the export
+		ctx.Writer.Header().Del("Content-Type")
+		ctx.Writer.Header().Del("Content-Disposition")
+		ctx.Error(err)
+		return
+	}
+	if err != nil {
+		log.Println("Error exporting the audit log:", err)
+	}
+}
+
+func auditQuery(ctx *gin.Context) (request.AuditQueryRequest, bool) {
+	queryRequest := request.AuditQueryRequest{}
+	if err := ctx.ShouldBindQuery(&queryRequest); err != nil {
+		ctx.Error(helper.BadRequest("Invalid query parameters: %s", err))
+		return queryRequest, false
+	}
+	return queryRequest, true
+}
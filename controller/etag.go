@@ -0,0 +1,82 @@
+package controller
+
+import (
+	"net/http"
+	"slices"
+	"strconv"
+	"strings"
+
+	"example.com/go-project/helper"
+	"github.com/gin-gonic/gin"
+)
+
+// RequireIfMatch makes updates and deletes without an If-Match header fail
+// with 428, so clients can't overwrite changes they haven't seen
+var RequireIfMatch = true
+
+// etag is the entity tag of a version of a resource
+func etag(version int) string {
+	return `"` + strconv.Itoa(version) + `"`
+}
+
+// notModified sets the ETag header of the version and answers 304 when the
+// client's If-None-Match already names it
+func notModified(ctx *gin.Context, version int) bool {
+	ctx.Header("ETag", etag(version))
+
+	for _, tag := range strings.Split(ctx.GetHeader("If-None-Match"), ",") {
+		// If-None-Match uses the weak comparison
+		tag = strings.TrimPrefix(strings.TrimSpace(tag), "W/")
+		if tag == "*" || tag == etag(version) {
+			ctx.Status(http.StatusNotModified)
+			return true
+		}
+	}
+	return false
+}
+
+// ifMatchVersion reads the version an update or delete is based on from the
+// If-Match header, 0 for any version. A header listing several ETags is
+// resolved with the current version, the update still only applies to that
+// version. It adds an error to the context and returns false when the header
+// is missing but required, or can't match.
+func ifMatchVersion(ctx *gin.Context, current func() (int, error)) (int, bool) {
+	header := strings.TrimSpace(ctx.GetHeader("If-Match"))
+	if header == "" {
+		if RequireIfMatch {
+			ctx.Error(helper.PreconditionRequired("If-Match header with the ETag of the resource is required"))
+			return 0, false
+		}
+		return 0, true
+	}
+
+	// If-Match uses the strong comparison, a weak tag never matches
+	var versions []int
+	for _, tag := range strings.Split(header, ",") {
+		tag = strings.TrimSpace(tag)
+		if tag == "*" {
+			return 0, true
+		}
+		version, err := strconv.Atoi(strings.Trim(tag, `"`))
+		if err == nil && version > 0 && tag == etag(version) {
+			versions = append(versions, version)
+		}
+	}
+
+	switch len(versions) {
+	case 0:
+	case 1:
+		return versions[0], true
+	default:
+		version, err := current()
+		if err != nil {
+			ctx.Error(err)
+			return 0, false
+		}
+		if slices.Contains(versions, version) {
+			return version, true
+		}
+	}
+	ctx.Error(helper.PreconditionFailed("If-Match doesn't match the current version"))
+	return 0, false
+}
@@ -26,59 +26,121 @@ func (controller *NecheController) Create(ctx *gin.Context) {
 	createNecheRequest := request.CreateNecheRequest{}
 	err := ctx.ShouldBindJSON(&createNecheRequest)
 	if err != nil {
-		helper.ErrorPanic(err)
+		ctx.Error(helper.BadRequest("Invalid request body: %s", err))
 		return
 	}
 
-	if createNecheRequest.TagID == 0 {
-		helper.ErrorPanic(err)
-		return
-	}
-
-	err = controller.necheService.Create(createNecheRequest)
+	neche, err := controller.necheService.Create(actorId(ctx), createNecheRequest)
 	if err != nil {
-		helper.ErrorPanic(err)
+		ctx.Error(err)
 		return
 	}
+	ctx.Header("ETag", etag(neche.Version))
 
 	webresponse := response.Response{
 		Code:   http.StatusOK,
 		Status: "ok",
-		Data:   createNecheRequest,
+		Data:   neche,
 		Msg:    "Neche Created Successfully",
 	}
 	ctx.JSON(http.StatusOK, webresponse)
 }
 
-// Find all Neches
-func (controller *NecheController) FindAll(ctx *gin.Context) {
-	neches, err := controller.necheService.FindAll()
+// Update Neche, renaming it or moving it to another Tag
+func (controller *NecheController) Update(ctx *gin.Context) {
+	necheId, ok := necheIdParam(ctx)
+	if !ok {
+		return
+	}
+
+	updateNecheRequest := request.UpdateNecheRequest{}
+	err := ctx.ShouldBindJSON(&updateNecheRequest)
+	if err != nil {
+		ctx.Error(helper.BadRequest("Invalid request body: %s", err))
+		return
+	}
+	updateNecheRequest.Id = necheId
+
+	// Only the version the client read can be updated
+	updateNecheRequest.Version, ok = ifMatchVersion(ctx, controller.necheVersion(necheId))
+	if !ok {
+		return
+	}
+
+	neche, err := controller.necheService.Update(actorId(ctx), updateNecheRequest)
 	if err != nil {
-		helper.ErrorPanic(err)
+		ctx.Error(err)
 		return
 	}
+	respondUpdatedNeche(ctx, neche)
+}
+
+// Patch Neche with a JSON Merge Patch or a JSON Patch
+func (controller *NecheController) Patch(ctx *gin.Context) {
+	necheId, ok := necheIdParam(ctx)
+	if !ok {
+		return
+	}
+
+	patchRequest, ok := patchRequest(ctx, necheId, controller.necheVersion(necheId))
+	if !ok {
+		return
+	}
+
+	neche, err := controller.necheService.Patch(actorId(ctx), patchRequest)
+	if err != nil {
+		ctx.Error(err)
+		return
+	}
+	respondUpdatedNeche(ctx, neche)
+}
+
+// necheVersion looks up the current version of a neche for an If-Match listing several ETags
+func (controller *NecheController) necheVersion(id int) func() (int, error) {
+	return func() (int, error) {
+		neche, err := controller.necheService.FindById(id)
+		return neche.Version, err
+	}
+}
+
+func respondUpdatedNeche(ctx *gin.Context, neche response.NecheResponse) {
+	ctx.Header("ETag", etag(neche.Version))
 
 	webresponse := response.Response{
 		Code:   http.StatusOK,
 		Status: "ok",
-		Data:   neches,
-		Msg:    "Neches Fetched Successfully.",
+		Data:   neche,
+		Msg:    "Neche Updated Successfully",
 	}
 	ctx.JSON(http.StatusOK, webresponse)
 }
 
+// Find all Neches with pagination
+func (controller *NecheController) FindAll(ctx *gin.Context) {
+	page := paginationParams(ctx)
+
+	neches, err := controller.necheService.FindAll(page.Request())
+	if err != nil {
+		ctx.Error(err)
+		return
+	}
+
+	respondPage(ctx, page, neches.Neches, neches.Total, neches.Cursors, "Neches Fetched Successfully.")
+}
+
 // Find Neche by ID
 func (controller *NecheController) FindById(ctx *gin.Context) {
-	necheIdStr := ctx.Param("necheId")
-	necheId, err := strconv.Atoi(necheIdStr)
-	if err != nil {
-		helper.ErrorPanic(err)
+	necheId, ok := necheIdParam(ctx)
+	if !ok {
 		return
 	}
 
 	neche, err := controller.necheService.FindById(necheId)
 	if err != nil {
-		helper.ErrorPanic(err)
+		ctx.Error(err)
+		return
+	}
+	if notModified(ctx, neche.Version) {
 		return
 	}
 
@@ -90,18 +152,50 @@ func (controller *NecheController) FindById(ctx *gin.Context) {
 	ctx.JSON(http.StatusOK, webresponse)
 }
 
+// Find the Neches of a Tag, or of its whole subtree, with pagination
+func (controller *NecheController) FindByTagId(ctx *gin.Context) {
+	tagId, err := strconv.Atoi(ctx.Param("tagId"))
+	if err != nil {
+		ctx.Error(helper.BadRequest("Invalid tag ID: %s", ctx.Param("tagId")))
+		return
+	}
+
+	// includeDescendants=true adds the Neches of every Tag under this one
+	descendants := false
+	if value := ctx.Query("includeDescendants"); value != "" {
+		descendants, err = strconv.ParseBool(value)
+		if err != nil {
+			ctx.Error(helper.BadRequest("Invalid includeDescendants: %s", value))
+			return
+		}
+	}
+
+	page := paginationParams(ctx)
+
+	neches, err := controller.necheService.FindByTagId(tagId, descendants, page.Request())
+	if err != nil {
+		ctx.Error(err)
+		return
+	}
+
+	respondPage(ctx, page, neches.Neches, neches.Total, neches.Cursors, "Neches Fetched Successfully.")
+}
+
 // Delete Neche by ID
 func (controller *NecheController) Delete(ctx *gin.Context) {
-	necheIdStr := ctx.Param("necheId")
-	necheId, err := strconv.Atoi(necheIdStr)
-	if err != nil {
-		helper.ErrorPanic(err)
+	necheId, ok := necheIdParam(ctx)
+	if !ok {
+		return
+	}
+
+	version, ok := ifMatchVersion(ctx, controller.necheVersion(necheId))
+	if !ok {
 		return
 	}
 
-	err = controller.necheService.Delete(necheId)
+	err := controller.necheService.Delete(actorId(ctx), necheId, version)
 	if err != nil {
-		helper.ErrorPanic(err)
+		ctx.Error(err)
 		return
 	}
 
@@ -112,3 +206,91 @@ func (controller *NecheController) Delete(ctx *gin.Context) {
 	}
 	ctx.JSON(http.StatusOK, webresponse)
 }
+
+// Attach a Neche to a Tag, attaching it again changes nothing
+func (controller *NecheController) Attach(ctx *gin.Context) {
+	tagId, ok := tagIdParam(ctx)
+	if !ok {
+		return
+	}
+	necheId, ok := necheIdParam(ctx)
+	if !ok {
+		return
+	}
+
+	err := controller.necheService.Attach(actorId(ctx), tagId, request.AttachNechesRequest{NecheIDs: []int{necheId}})
+	if err != nil {
+		ctx.Error(err)
+		return
+	}
+
+	webresponse := response.Response{
+		Code:   http.StatusOK,
+		Status: "ok",
+		Msg:    "Neche Attached Successfully",
+	}
+	ctx.JSON(http.StatusOK, webresponse)
+}
+
+// Attach several Neches to a Tag, none is attached unless all of them exist
+func (controller *NecheController) AttachMany(ctx *gin.Context) {
+	tagId, ok := tagIdParam(ctx)
+	if !ok {
+		return
+	}
+
+	attachRequest := request.AttachNechesRequest{}
+	err := ctx.ShouldBindJSON(&attachRequest)
+	if err != nil {
+		ctx.Error(helper.BadRequest("Invalid request body: %s", err))
+		return
+	}
+
+	err = controller.necheService.Attach(actorId(ctx), tagId, attachRequest)
+	if err != nil {
+		ctx.Error(err)
+		return
+	}
+
+	webresponse := response.Response{
+		Code:   http.StatusOK,
+		Status: "ok",
+		Msg:    "Neches Attached Successfully",
+	}
+	ctx.JSON(http.StatusOK, webresponse)
+}
+
+// Detach a Neche from a Tag, the Neche itself stays
+func (controller *NecheController) Detach(ctx *gin.Context) {
+	tagId, ok := tagIdParam(ctx)
+	if !ok {
+		return
+	}
+	necheId, ok := necheIdParam(ctx)
+	if !ok {
+		return
+	}
+
+	err := controller.necheService.Detach(actorId(ctx), tagId, necheId)
+	if err != nil {
+		ctx.Error(err)
+		return
+	}
+
+	webresponse := response.Response{
+		Code:   http.StatusOK,
+		Status: "ok",
+		Msg:    "Neche Detached Successfully",
+	}
+	ctx.JSON(http.StatusOK, webresponse)
+}
+
+func necheIdParam(ctx *gin.Context) (int, bool) {
+	necheIdStr := ctx.Param("necheId")
+	necheId, err := strconv.Atoi(necheIdStr)
+	if err != nil {
+		ctx.Error(helper.BadRequest("Invalid neche ID: %s", necheIdStr))
+		return 0, false
+	}
+	return necheId, true
+}
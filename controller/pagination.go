@@ -0,0 +1,139 @@
+package controller
+
+import (
+	"fmt"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+
+	"example.com/go-project/data/request"
+	"example.com/go-project/data/response"
+	"github.com/gin-gonic/gin"
+)
+
+// Page sizes of listings, set from the configuration
+var (
+	DefaultPageSize = 10
+	MaxPageSize     = 100
+)
+
+// pagination is the page a client asked for with the page and pageSize
+// parameters, or with a cursor from a previous page
+type pagination struct {
+	Page     int
+	PageSize int
+	Cursor   string
+}
+
+// paginationParams reads the page parameters, falling back to the first page
+// of the default size and capping the page size
+func paginationParams(ctx *gin.Context) pagination {
+	page, err := strconv.Atoi(ctx.Query("page"))
+	if err != nil || page < 1 {
+		page = 1
+	}
+
+	pageSize, err := strconv.Atoi(ctx.Query("pageSize"))
+	if err != nil || pageSize < 1 {
+		pageSize = DefaultPageSize
+	}
+	if pageSize > MaxPageSize {
+		pageSize = MaxPageSize
+	}
+	// A cursor takes precedence, the page number is meaningless then
+	cursor := ctx.Query("cursor")
+	if cursor != "" {
+		page = 0
+	}
+	return pagination{Page: page, PageSize: pageSize, Cursor: cursor}
+}
+
+func (p pagination) Offset() int {
+	if p.Cursor != "" {
+		return 0
+	}
+	return (p.Page - 1) * p.PageSize
+}
+
+func (p pagination) Request() request.PageRequest {
+	return request.PageRequest{Limit: p.PageSize, Offset: p.Offset(), Cursor: p.Cursor}
+}
+
+// respondPage writes a page of results with its navigation links, both in the
+// body and as RFC 8288 Link headers. Pages reached by cursor link to the
+// pages around them by cursor too.
+func respondPage(ctx *gin.Context, p pagination, data interface{}, total int64, cursors response.Cursors, msg string) {
+	totalPages := int((total + int64(p.PageSize) - 1) / int64(p.PageSize))
+
+	webresponse := response.PaginatedResponse{
+		Code:       http.StatusOK,
+		Status:     "ok",
+		Data:       data,
+		Limit:      p.PageSize,
+		Offset:     p.Offset(),
+		Total:      total,
+		Page:       p.Page,
+		PageSize:   p.PageSize,
+		TotalPages: totalPages,
+		NextCursor: cursors.Next,
+		PrevCursor: cursors.Prev,
+		Msg:        msg,
+	}
+
+	var links []string
+	if p.Cursor != "" {
+		if cursors.Next != "" {
+			webresponse.Next = cursorLink(ctx, cursors.Next, p.PageSize)
+			links = append(links, fmt.Sprintf(`<%s>; rel="next"`, webresponse.Next))
+		}
+		if cursors.Prev != "" {
+			webresponse.Prev = cursorLink(ctx, cursors.Prev, p.PageSize)
+			links = append(links, fmt.Sprintf(`<%s>; rel="prev"`, webresponse.Prev))
+		}
+		links = append(links, fmt.Sprintf(`<%s>; rel="first"`, pageLink(ctx, 1, p.PageSize)))
+
+		ctx.Header("Link", strings.Join(links, ", "))
+		ctx.JSON(http.StatusOK, webresponse)
+		return
+	}
+
+	if p.Page < totalPages {
+		webresponse.Next = pageLink(ctx, p.Page+1, p.PageSize)
+		links = append(links, fmt.Sprintf(`<%s>; rel="next"`, webresponse.Next))
+	}
+	if p.Page > 1 {
+		// Past the end the previous link leads back to the last page
+		webresponse.Prev = pageLink(ctx, min(p.Page-1, max(totalPages, 1)), p.PageSize)
+		links = append(links, fmt.Sprintf(`<%s>; rel="prev"`, webresponse.Prev))
+	}
+	links = append(links, fmt.Sprintf(`<%s>; rel="first"`, pageLink(ctx, 1, p.PageSize)))
+	if totalPages > 0 {
+		links = append(links, fmt.Sprintf(`<%s>; rel="last"`, pageLink(ctx, totalPages, p.PageSize)))
+	}
+
+	ctx.Header("Link", strings.Join(links, ", "))
+	ctx.JSON(http.StatusOK, webresponse)
+}
+
+// pageLink is the request URL with other page parameters, filters and sorting are kept
+func pageLink(ctx *gin.Context, page int, pageSize int) string {
+	query := ctx.Request.URL.Query()
+	query.Del("cursor")
+	query.Set("page", strconv.Itoa(page))
+	query.Set("pageSize", strconv.Itoa(pageSize))
+
+	link := url.URL{Path: ctx.Request.URL.Path, RawQuery: query.Encode()}
+	return link.String()
+}
+
+// cursorLink is the request URL paging from the cursor instead
+func cursorLink(ctx *gin.Context, cursor string, pageSize int) string {
+	query := ctx.Request.URL.Query()
+	query.Del("page")
+	query.Set("cursor", cursor)
+	query.Set("pageSize", strconv.Itoa(pageSize))
+
+	link := url.URL{Path: ctx.Request.URL.Path, RawQuery: query.Encode()}
+	return link.String()
+}
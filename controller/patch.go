@@ -0,0 +1,33 @@
+package controller
+
+import (
+	"example.com/go-project/data/request"
+	"example.com/go-project/helper"
+	"github.com/gin-gonic/gin"
+)
+
+// patchRequest reads the patch in the body of a PATCH request and the version
+// it applies to, current looks the version up. The Content-Type tells which
+// kind of patch it is.
+func patchRequest(ctx *gin.Context, id int, current func() (int, error)) (request.PatchRequest, bool) {
+	// Clients find the patch formats in every PATCH response, 415s included
+	ctx.Header("Accept-Patch", helper.MergePatchType+", "+helper.JSONPatchType)
+
+	version, ok := ifMatchVersion(ctx, current)
+	if !ok {
+		return request.PatchRequest{}, false
+	}
+
+	body, err := ctx.GetRawData()
+	if err != nil {
+		ctx.Error(helper.BadRequest("Invalid request body: %s", err))
+		return request.PatchRequest{}, false
+	}
+
+	return request.PatchRequest{
+		Id:        id,
+		Version:   version,
+		MediaType: ctx.ContentType(),
+		Patch:     body,
+	}, true
+}
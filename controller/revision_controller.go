@@ -0,0 +1,114 @@
+package controller
+
+import (
+	"strconv"
+
+	"example.com/go-project/auth"
+	"example.com/go-project/data/request"
+	"example.com/go-project/helper"
+	"example.com/go-project/services"
+	"github.com/gin-gonic/gin"
+)
+
+// RevisionController serves the history of tags and neches and reverts them
+type RevisionController struct {
+	revisionService services.RevisionService
+}
+
+func NewRevisionController(service services.RevisionService) *RevisionController {
+	return &RevisionController{
+		revisionService: service,
+	}
+}
+
+// TagHistory lists the revisions of a tag with pagination, newest first
+func (controller *RevisionController) TagHistory(ctx *gin.Context) {
+	id, ok := tagIdParam(ctx)
+	if !ok {
+		return
+	}
+	page := paginationParams(ctx)
+
+	revisions, err := controller.revisionService.FindTagHistory(id, page.Request())
+	if err != nil {
+		ctx.Error(err)
+		return
+	}
+	respondPage(ctx, page, revisions.Revisions, revisions.Total, revisions.Cursors, "Fetched tag history successfully.")
+}
+
+// NecheHistory lists the revisions of a neche with pagination, newest first
+func (controller *RevisionController) NecheHistory(ctx *gin.Context) {
+	id, ok := necheIdParam(ctx)
+	if !ok {
+		return
+	}
+	page := paginationParams(ctx)
+
+	revisions, err := controller.revisionService.FindNecheHistory(id, page.Request())
+	if err != nil {
+		ctx.Error(err)
+		return
+	}
+	respondPage(ctx, page, revisions.Revisions, revisions.Total, revisions.Cursors, "Fetched neche history successfully.")
+}
+
+// RevertTag brings a tag back to the state of one of its revisions
+func (controller *RevisionController) RevertTag(ctx *gin.Context) {
+	id, ok := tagIdParam(ctx)
+	if !ok {
+		return
+	}
+	revert, ok := revertRequest(ctx, id, func() (int, error) {
+		return controller.revisionService.TagVersion(id)
+	})
+	if !ok {
+		return
+	}
+
+	tag, err := controller.revisionService.RevertTag(actorId(ctx), revert)
+	if err != nil {
+		ctx.Error(err)
+		return
+	}
+	auth.SetAuditDetail(ctx, "revision "+strconv.Itoa(revert.RevisionId))
+	respondUpdatedTag(ctx, tag)
+}
+
+// RevertNeche brings a neche back to the state of one of its revisions
+func (controller *RevisionController) RevertNeche(ctx *gin.Context) {
+	id, ok := necheIdParam(ctx)
+	if !ok {
+		return
+	}
+	revert, ok := revertRequest(ctx, id, func() (int, error) {
+		return controller.revisionService.NecheVersion(id)
+	})
+	if !ok {
+		return
+	}
+
+	neche, err := controller.revisionService.RevertNeche(actorId(ctx), revert)
+	if err != nil {
+		ctx.Error(err)
+		return
+	}
+	auth.SetAuditDetail(ctx, "revision "+strconv.Itoa(revert.RevisionId))
+	respondUpdatedNeche(ctx, neche)
+}
+
+// revertRequest reads the revision path parameter and the version the revert is based on
+func revertRequest(ctx *gin.Context, id int, current func() (int, error)) (request.RevertRequest, bool) {
+	revisionId, err := strconv.Atoi(ctx.Param("revisionId"))
+	if err != nil {
+		ctx.Error(helper.BadRequest("Invalid revision ID: %s", ctx.Param("revisionId")))
+		return request.RevertRequest{}, false
+	}
+
+	// Only the version the client read can be reverted
+	version, ok := ifMatchVersion(ctx, current)
+	if !ok {
+		return request.RevertRequest{}, false
+	}
+	return request.RevertRequest{Id: id, RevisionId: revisionId, Version: version}, true
+}
@@ -0,0 +1,181 @@
+package controller
+
+import (
+	"net/http"
+
+	"example.com/go-project/auth"
+	"example.com/go-project/data/request"
+	"example.com/go-project/data/response"
+	"example.com/go-project/helper"
+	"example.com/go-project/services"
+	"github.com/gin-gonic/gin"
+)
+
+// RolesController lets admins change what each role is allowed to do at runtime
+type RolesController struct {
+	permissionService *services.PermissionService
+}
+
+func NewRolesController(service *services.PermissionService) *RolesController {
+	return &RolesController{permissionService: service}
+}
+
+func (controller *RolesController) FindAllRoles(ctx *gin.Context) {
+	roles, err := controller.permissionService.FindAllRoles()
+	if err != nil {
+		ctx.Error(err)
+		return
+	}
+
+	data := []response.RoleResponse{}
+	for i := range roles {
+		data = append(data, response.NewRoleResponse(&roles[i]))
+	}
+	ctx.JSON(http.StatusOK, response.Response{
+		Code:   http.StatusOK,
+		Status: "ok",
+		Data:   data,
+	})
+}
+
+func (controller *RolesController) FindRole(ctx *gin.Context) {
+	role, err := controller.permissionService.FindRole(ctx.Param("role"))
+	if err != nil {
+		ctx.Error(err)
+		return
+	}
+
+	ctx.JSON(http.StatusOK, response.Response{
+		Code:   http.StatusOK,
+		Status: "ok",
+		Data:   response.NewRoleResponse(role),
+	})
+}
+
+func (controller *RolesController) CreateRole(ctx *gin.Context) {
+	var roleRequest request.CreateRoleRequest
+	if err := ctx.ShouldBindJSON(&roleRequest); err != nil {
+		ctx.Error(helper.BadRequest("Invalid request body: %s", err))
+		return
+	}
+	auth.SetAuditTarget(ctx, roleRequest.Name)
+
+	role, err := controller.permissionService.CreateRole(roleRequest)
+	if err != nil {
+		ctx.Error(err)
+		return
+	}
+	auth.SetAuditTarget(ctx, role.Name)
+
+	ctx.JSON(http.StatusOK, response.Response{
+		Code:   http.StatusOK,
+		Status: "ok",
+		Data:   response.NewRoleResponse(role),
+		Msg:    "Role added successfully.",
+	})
+}
+
+func (controller *RolesController) DeleteRole(ctx *gin.Context) {
+	err := controller.permissionService.DeleteRole(ctx.Param("role"))
+	if err != nil {
+		ctx.Error(err)
+		return
+	}
+	auth.InvalidatePermissions()
+
+	ctx.JSON(http.StatusOK, response.Response{
+		Code:   http.StatusOK,
+		Status: "ok",
+		Msg:    "Role deleted successfully.",
+	})
+}
+
+func (controller *RolesController) FindAllPermissions(ctx *gin.Context) {
+	permissions, err := controller.permissionService.FindAllPermissions()
+	if err != nil {
+		ctx.Error(err)
+		return
+	}
+
+	data := []response.PermissionResponse{}
+	for i := range permissions {
+		data = append(data, response.NewPermissionResponse(&permissions[i]))
+	}
+	ctx.JSON(http.StatusOK, response.Response{
+		Code:   http.StatusOK,
+		Status: "ok",
+		Data:   data,
+	})
+}
+
+func (controller *RolesController) CreatePermission(ctx *gin.Context) {
+	var permissionRequest request.CreatePermissionRequest
+	if err := ctx.ShouldBindJSON(&permissionRequest); err != nil {
+		ctx.Error(helper.BadRequest("Invalid request body: %s", err))
+		return
+	}
+	auth.SetAuditTarget(ctx, permissionRequest.Name)
+
+	permission, err := controller.permissionService.CreatePermission(permissionRequest)
+	if err != nil {
+		ctx.Error(err)
+		return
+	}
+	auth.SetAuditTarget(ctx, permission.Name)
+
+	ctx.JSON(http.StatusOK, response.Response{
+		Code:   http.StatusOK,
+		Status: "ok",
+		Data:   response.NewPermissionResponse(permission),
+		Msg:    "Permission added successfully.",
+	})
+}
+
+func (controller *RolesController) DeletePermission(ctx *gin.Context) {
+	err := controller.permissionService.DeletePermission(ctx.Param("permission"))
+	if err != nil {
+		ctx.Error(err)
+		return
+	}
+	auth.InvalidatePermissions()
+
+	ctx.JSON(http.StatusOK, response.Response{
+		Code:   http.StatusOK,
+		Status: "ok",
+		Msg:    "Permission deleted successfully.",
+	})
+}
+
+// Grant gives a permission to a role, it takes effect on the next request
+func (controller *RolesController) Grant(ctx *gin.Context) {
+	err := controller.permissionService.Grant(ctx.Param("role"), ctx.Param("permission"))
+	if err != nil {
+		ctx.Error(err)
+		return
+	}
+	auth.SetAuditDetail(ctx, "permission "+ctx.Param("permission"))
+	auth.InvalidatePermissions()
+
+	ctx.JSON(http.StatusOK, response.Response{
+		Code:   http.StatusOK,
+		Status: "ok",
+		Msg:    "Permission granted.",
+	})
+}
+
+// Revoke takes a permission away from a role, it takes effect on the next request
+func (controller *RolesController) Revoke(ctx *gin.Context) {
+	err := controller.permissionService.Revoke(ctx.Param("role"), ctx.Param("permission"))
+	if err != nil {
+		ctx.Error(err)
+		return
+	}
+	auth.SetAuditDetail(ctx, "permission "+ctx.Param("permission"))
+	auth.InvalidatePermissions()
+
+	ctx.JSON(http.StatusOK, response.Response{
+		Code:   http.StatusOK,
+		Status: "ok",
+		Msg:    "Permission revoked.",
+	})
+}
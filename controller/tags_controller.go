@@ -1,7 +1,6 @@
 package controller
 
 import (
-	"fmt"
 	"log"
 	"net/http"
 	"strconv"
@@ -27,13 +26,13 @@ func (controller *TagsController) Create(ctx *gin.Context) {
 	createTagsRequest := request.CreteTagsRequest{}
 	err := ctx.ShouldBindJSON(&createTagsRequest)
 	if err != nil {
-		helper.ErrorPanic(err)
+		ctx.Error(helper.BadRequest("Invalid request body: %s", err))
 		return
 	}
 
-	err = controller.tagsService.Create(createTagsRequest)
+	err = controller.tagsService.Create(actorId(ctx), createTagsRequest)
 	if err != nil {
-		helper.ErrorPanic(err)
+		ctx.Error(err)
 		return
 	}
 
@@ -47,100 +46,193 @@ func (controller *TagsController) Create(ctx *gin.Context) {
 }
 
 func (controller *TagsController) Update(ctxhttp *gin.Context) {
+	id, ok := tagIdParam(ctxhttp)
+	if !ok {
+		return
+	}
+
 	updateTagsRequest := request.UpdateTagsRequest{}
 	err := ctxhttp.ShouldBindJSON(&updateTagsRequest)
-	helper.ErrorPanic(err)
-
-	tagId := ctxhttp.Param("tagId")
-	id, err := strconv.Atoi(tagId)
-	helper.ErrorPanic(err)
+	if err != nil {
+		ctxhttp.Error(helper.BadRequest("Invalid request body: %s", err))
+		return
+	}
 	updateTagsRequest.Id = id
 
-	controller.tagsService.Update(updateTagsRequest)
+	// Only the version the client read can be updated
+	updateTagsRequest.Version, ok = ifMatchVersion(ctxhttp, controller.tagVersion(id))
+	if !ok {
+		return
+	}
+
+	tag, err := controller.tagsService.Update(actorId(ctxhttp), updateTagsRequest)
+	if err != nil {
+		ctxhttp.Error(err)
+		return
+	}
+	respondUpdatedTag(ctxhttp, tag)
+}
+
+// Patch changes some fields of a tag with a JSON Merge Patch or a JSON Patch
+func (controller *TagsController) Patch(ctxhttp *gin.Context) {
+	id, ok := tagIdParam(ctxhttp)
+	if !ok {
+		return
+	}
+
+	patchRequest, ok := patchRequest(ctxhttp, id, controller.tagVersion(id))
+	if !ok {
+		return
+	}
+
+	tag, err := controller.tagsService.Patch(actorId(ctxhttp), patchRequest)
+	if err != nil {
+		ctxhttp.Error(err)
+		return
+	}
+	respondUpdatedTag(ctxhttp, tag)
+}
+
+// tagVersion looks up the current version of a tag for an If-Match listing several ETags
+func (controller *TagsController) tagVersion(id int) func() (int, error) {
+	return func() (int, error) {
+		tag, err := controller.tagsService.FindById(id)
+		return tag.Version, err
+	}
+}
+
+func respondUpdatedTag(ctxhttp *gin.Context, tag response.TagsResponse) {
 	webresponse := response.Response{
 		Code:   http.StatusOK,
 		Status: "ok",
 		Data: map[string]interface{}{
-			"id":   id,
-			"name": updateTagsRequest.Name,
+			"id":       tag.Id,
+			"name":     tag.Name,
+			"parentId": tag.ParentId,
+			"version":  tag.Version,
 		},
 	}
 	ctxhttp.Header("Content-type", "application/json")
+	ctxhttp.Header("ETag", etag(tag.Version))
 	ctxhttp.JSON(http.StatusOK, webresponse)
 }
 
-func (controller *TagsController) Delete(ctxhttp *gin.Context) {
-	tagId := ctxhttp.Param("tagId")
-	id, err := strconv.Atoi(tagId)
+// Move puts a tag and its subtree under another tag, a null parentId makes it a root
+func (controller *TagsController) Move(ctxhttp *gin.Context) {
+	id, ok := tagIdParam(ctxhttp)
+	if !ok {
+		return
+	}
+
+	moveTagRequest := request.MoveTagRequest{}
+	err := ctxhttp.ShouldBindJSON(&moveTagRequest)
+	if err != nil {
+		ctxhttp.Error(helper.BadRequest("Invalid request body: %s", err))
+		return
+	}
+	moveTagRequest.Id = id
+
+	// Only the version the client read can be moved
+	moveTagRequest.Version, ok = ifMatchVersion(ctxhttp, controller.tagVersion(id))
+	if !ok {
+		return
+	}
+
+	tag, err := controller.tagsService.Move(actorId(ctxhttp), moveTagRequest)
 	if err != nil {
-		// Handle the error if the conversion fails
-		webresponse := response.Response{
-			Code:   http.StatusBadRequest,
-			Status: "error",
-			Data:   fmt.Sprintf("Invalid tag ID: %s", tagId),
-		}
-		ctxhttp.JSON(http.StatusBadRequest, webresponse)
+		ctxhttp.Error(err)
+		return
+	}
+	respondUpdatedTag(ctxhttp, tag)
+}
+
+// Subtree returns a tag with all its descendants nested under it
+func (controller *TagsController) Subtree(ctxhttp *gin.Context) {
+	id, ok := tagIdParam(ctxhttp)
+	if !ok {
 		return
 	}
 
-	// Call FindById and handle the two return values
+	tree, err := controller.tagsService.Subtree(id)
+	if err != nil {
+		ctxhttp.Error(err)
+		return
+	}
+
+	webresponse := response.Response{
+		Code:   http.StatusOK,
+		Status: "ok",
+		Data:   tree,
+	}
+	ctxhttp.JSON(http.StatusOK, webresponse)
+}
+
+// Ancestors returns the breadcrumb of a tag, from its root down to the tag
+func (controller *TagsController) Ancestors(ctxhttp *gin.Context) {
+	id, ok := tagIdParam(ctxhttp)
+	if !ok {
+		return
+	}
+
+	breadcrumb, err := controller.tagsService.Ancestors(id)
+	if err != nil {
+		ctxhttp.Error(err)
+		return
+	}
+
+	webresponse := response.Response{
+		Code:   http.StatusOK,
+		Status: "ok",
+		Data:   breadcrumb,
+	}
+	ctxhttp.JSON(http.StatusOK, webresponse)
+}
+
+func (controller *TagsController) Delete(ctxhttp *gin.Context) {
+	id, ok := tagIdParam(ctxhttp)
+	if !ok {
+		return
+	}
+
+	// Make sure the tag exists, deleting a missing tag is a 404
 	tag, err := controller.tagsService.FindById(id)
 	if err != nil {
-		// Tag not found
-		webresponse := response.Response{
-			Code:   http.StatusNotFound,
-			Status: "error",
-			Data:   fmt.Sprintf("Tag with id %d not found", id),
-		}
-		ctxhttp.JSON(http.StatusNotFound, webresponse)
+		ctxhttp.Error(err)
+		return
+	}
+
+	version, ok := ifMatchVersion(ctxhttp, func() (int, error) { return tag.Version, nil })
+	if !ok {
 		return
 	}
 
 	// Proceed to delete the tag
-	err = controller.tagsService.Delete(id)
+	err = controller.tagsService.Delete(actorId(ctxhttp), id, version)
 	if err != nil {
-		// Handle potential error from the Delete method
-		webresponse := response.Response{
-			Code:   http.StatusInternalServerError,
-			Status: "error",
-			Data:   "Failed to delete the tag",
-		}
-		ctxhttp.JSON(http.StatusInternalServerError, webresponse)
+		ctxhttp.Error(err)
 		return
 	}
 
 	webresponse := response.Response{
 		Code:   http.StatusOK,
 		Status: "ok",
-		Data:   fmt.Sprintf("Deleted tag with id %d", tag.Id),
+		Data:   "Deleted tag with id " + strconv.Itoa(tag.Id),
 	}
 	ctxhttp.JSON(http.StatusOK, webresponse)
 }
 
 func (controller *TagsController) FindById(ctxhttp *gin.Context) {
-	tagId := ctxhttp.Param("tagId")
-	id, err := strconv.Atoi(tagId)
-	if err != nil {
-		// Handle the error if the conversion fails
-		webresponse := response.Response{
-			Code:   http.StatusBadRequest,
-			Status: "error",
-			Data:   fmt.Sprintf("Invalid tag ID: %s", tagId),
-		}
-		ctxhttp.JSON(http.StatusBadRequest, webresponse)
+	id, ok := tagIdParam(ctxhttp)
+	if !ok {
 		return
 	}
 
-	// Call FindById and handle the two return values
 	tagResponse, err := controller.tagsService.FindById(id)
 	if err != nil {
-		// Tag not found
-		webresponse := response.Response{
-			Code:   http.StatusNotFound,
-			Status: "error",
-			Data:   fmt.Sprintf("Tag with id %d not found", id),
-		}
-		ctxhttp.JSON(http.StatusNotFound, webresponse)
+		ctxhttp.Error(err)
+		return
+	}
+	if notModified(ctxhttp, tagResponse.Version) {
 		return
 	}
 
@@ -154,90 +246,36 @@ func (controller *TagsController) FindById(ctxhttp *gin.Context) {
 }
 
 func (controller *TagsController) FindAll(ctx *gin.Context) {
-	// Default pagination values if not provided
-	page, err := strconv.Atoi(ctx.Query("page"))
-	if err != nil || page < 1 {
-		page = 1
-	}
+	page := paginationParams(ctx)
 
-	pageSize, err := strconv.Atoi(ctx.Query("pageSize"))
-	if err != nil || pageSize < 1 {
-		pageSize = 10
+	// Search, filter and sort parameters can be combined freely
+	searchRequest := request.SearchTagsRequest{}
+	err := ctx.ShouldBindQuery(&searchRequest)
+	if err != nil {
+		ctx.Error(helper.BadRequest("Invalid query parameters: %s", err))
+		return
 	}
-
-	offset := (page - 1) * pageSize
+	searchRequest.PageRequest = page.Request()
 
 	// Call the service to fetch tags
-	tagResponse, err := controller.tagsService.FindAll(pageSize, offset)
+	tags, err := controller.tagsService.Search(searchRequest)
 	if err != nil {
-		ctx.JSON(http.StatusInternalServerError, response.PaginatedResponse{
-			Code:   http.StatusInternalServerError,
-			Status: "error",
-			Data:   nil,
-			Limit:  pageSize,
-			Offset: offset,
-			Msg:    "Failed to fetch tags: " + err.Error(),
-		})
 		log.Println("Error fetching tags:", err)
+		ctx.Error(err)
 		return
 	}
 
-	// Prepare the successful response
-	webresponse := response.PaginatedResponse{
-		Code:   http.StatusOK,
-		Status: "ok",
-		Data:   tagResponse,
-		Limit:  pageSize,
-		Offset: offset,
-		Msg:    "Fetched tags successfully.",
-	}
-	ctx.Header("Content-type", "application/json")
-	ctx.JSON(http.StatusOK, webresponse)
-	log.Println("Fetched tags successfully", webresponse)
+	respondPage(ctx, page, tags.Tags, tags.Total, tags.Cursors, "Fetched tags successfully.")
+	log.Println("Fetched tags successfully, total:", tags.Total)
 }
 
-// func (controller *TagsController) FindPaginated(ctxhttp *gin.Context) {
-// 	page, _ := strconv.Atoi(ctxhttp.DefaultQuery("page", "1"))
-// 	pageSize, _ := strconv.Atoi(ctxhttp.DefaultQuery("pageSize", "10"))
-
-// 	tagResponse := controller.tagsService.FindPaginated(page, pageSize)
-// 	webresponse := response.Response{
-// 		Code:   http.StatusOK,
-// 		Status: "ok",
-// 		Data:   tagResponse,
-// 		Msg:    "Fetched paginated tags successfully.",
-// 	}
-// 	ctxhttp.Header("Content-type", "application/json")
-// 	ctxhttp.JSON(http.StatusOK, webresponse)
-// }
-
-// // Method for sorting tags by name
-// func (controller *TagsController) FindAllSorted(ctxhttp *gin.Context) {
-// 	sortBy := ctxhttp.DefaultQuery("sortBy", "id")
-// 	order := ctxhttp.DefaultQuery("order", "asc")
-
-// 	tagResponse := controller.tagsService.FindAllSorted(sortBy, order)
-// 	webresponse := response.Response{
-// 		Code:   http.StatusOK,
-// 		Status: "ok",
-// 		Data:   tagResponse,
-// 		Msg:    "Fetched sorted tags successfully.",
-// 	}
-// 	ctxhttp.Header("Content-type", "application/json")
-// 	ctxhttp.JSON(http.StatusOK, webresponse)
-// }
-
-// // Method for filtering tags by custom criteria (e.g., name starts with a specific letter)
-// func (controller *TagsController) FindByCustomFilter(ctxhttp *gin.Context) {
-// 	startsWith := ctxhttp.DefaultQuery("startsWith", "")
-
-// 	tagResponse := controller.tagsService.FindByCustomFilter(startsWith)
-// 	webresponse := response.Response{
-// 		Code:   http.StatusOK,
-// 		Status: "ok",
-// 		Data:   tagResponse,
-// 		Msg:    "Fetched filtered tags successfully.",
-// 	}
-// 	ctxhttp.Header("Content-type", "application/json")
-// 	ctxhttp.JSON(http.StatusOK, webresponse)
-// }
+// tagIdParam parses the tagId path parameter, adding an error to the context when it isn't a number
+func tagIdParam(ctx *gin.Context) (int, bool) {
+	tagId := ctx.Param("tagId")
+	id, err := strconv.Atoi(tagId)
+	if err != nil {
+		ctx.Error(helper.BadRequest("Invalid tag ID: %s", tagId))
+		return 0, false
+	}
+	return id, true
+}
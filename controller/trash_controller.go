@@ -0,0 +1,90 @@
+package controller
+
+import (
+	"net/http"
+
+	"example.com/go-project/data/response"
+	"example.com/go-project/services"
+	"github.com/gin-gonic/gin"
+)
+
+// TrashController serves the deleted tags and neches to admins
+type TrashController struct {
+	trashService services.TrashService
+}
+
+func NewTrashController(service services.TrashService) *TrashController {
+	return &TrashController{
+		trashService: service,
+	}
+}
+
+// FindTags lists the tags in the trash with pagination
+func (controller *TrashController) FindTags(ctx *gin.Context) {
+	page := paginationParams(ctx)
+
+	tags, err := controller.trashService.FindTags(page.Request())
+	if err != nil {
+		ctx.Error(err)
+		return
+	}
+	respondPage(ctx, page, tags.Tags, tags.Total, tags.Cursors, "Fetched deleted tags successfully.")
+}
+
+// FindNeches lists the neches in the trash with pagination
+func (controller *TrashController) FindNeches(ctx *gin.Context) {
+	page := paginationParams(ctx)
+
+	neches, err := controller.trashService.FindNeches(page.Request())
+	if err != nil {
+		ctx.Error(err)
+		return
+	}
+	respondPage(ctx, page, neches.Neches, neches.Total, neches.Cursors, "Fetched deleted neches successfully.")
+}
+
+// RestoreTag takes a tag out of the trash, with the neches deleted along with it
+func (controller *TrashController) RestoreTag(ctx *gin.Context) {
+	id, ok := tagIdParam(ctx)
+	if !ok {
+		return
+	}
+
+	tag, err := controller.trashService.RestoreTag(actorId(ctx), id)
+	if err != nil {
+		ctx.Error(err)
+		return
+	}
+	ctx.Header("ETag", etag(tag.Version))
+
+	webresponse := response.Response{
+		Code:   http.StatusOK,
+		Status: "ok",
+		Data:   tag,
+		Msg:    "Tag restored successfully.",
+	}
+	ctx.JSON(http.StatusOK, webresponse)
+}
+
+// RestoreNeche takes a neche out of the trash
+func (controller *TrashController) RestoreNeche(ctx *gin.Context) {
+	id, ok := necheIdParam(ctx)
+	if !ok {
+		return
+	}
+
+	neche, err := controller.trashService.RestoreNeche(actorId(ctx), id)
+	if err != nil {
+		ctx.Error(err)
+		return
+	}
+	ctx.Header("ETag", etag(neche.Version))
+
+	webresponse := response.Response{
+		Code:   http.StatusOK,
+		Status: "ok",
+		Data:   neche,
+		Msg:    "Neche restored successfully.",
+	}
+	ctx.JSON(http.StatusOK, webresponse)
+}
@@ -1,46 +1,66 @@
 package controller
 
 import (
+	"errors"
+	"fmt"
+	"math"
+	"net"
 	"net/http"
+	"strconv"
+	"time"
 
 	"example.com/go-project/auth"
+	"example.com/go-project/data/request"
+	"example.com/go-project/data/response"
+	"example.com/go-project/helper"
 	"example.com/go-project/model"
 	"example.com/go-project/services"
 	"github.com/gin-gonic/gin"
 )
 
 type UsersController struct {
-	usersService *services.UsersService
+	usersService  *services.UsersService
+	tokenService  *services.TokenService
+	loginThrottle *services.LoginThrottle
 }
 
-func NewUsersController(service *services.UsersService) *UsersController {
-	return &UsersController{usersService: service}
+func NewUsersController(service *services.UsersService, tokenService *services.TokenService, loginThrottle *services.LoginThrottle) *UsersController {
+	return &UsersController{usersService: service, tokenService: tokenService, loginThrottle: loginThrottle}
 }
 
 func (controller *UsersController) RegisterUser(ctx *gin.Context) {
-	var user model.Users
-	err := ctx.ShouldBindJSON(&user)
+	var registerRequest request.RegisterUserRequest
+	err := ctx.ShouldBindJSON(&registerRequest)
 	if err != nil {
-		ctx.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		ctx.Error(helper.BadRequest("Invalid request body: %s", err))
 		return
 	}
 
-	// Call the Create method
-	err = controller.usersService.Create(user)
+	// Call the Register method, the role is assigned by the service
+	user, err := controller.usersService.Register(registerRequest)
 	if err != nil {
-		if err.Error() == "email already exists" {
-			ctx.JSON(http.StatusConflict, gin.H{"error": "Email already exists"})
-			return
-		}
-		ctx.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		auth.Audit(ctx, model.AuditEvent{
+			Action:  model.AuditRegister,
+			Email:   registerRequest.Email,
+			Outcome: model.AuditFailure,
+			Detail:  helper.AsAppError(err).Message,
+		})
+		ctx.Error(err)
 		return
 	}
+	auth.Audit(ctx, model.AuditEvent{
+		Action:     model.AuditRegister,
+		Email:      user.Email,
+		TargetType: "user",
+		TargetId:   strconv.Itoa(user.Id),
+		Outcome:    model.AuditSuccess,
+	})
 
 	// User created successfully
 	ctx.JSON(http.StatusOK, gin.H{
 		"code":   http.StatusOK,
 		"status": "ok",
-		"data":   user,
+		"data":   response.NewUserResponse(user),
 		"msg":    "User added successfully.",
 	})
 }
@@ -54,33 +74,266 @@ func (controller *UsersController) Login(ctx *gin.Context) {
 	// Bind JSON data from request body to loginData struct
 	err := ctx.ShouldBindJSON(&loginData)
 	if err != nil {
-		ctx.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		ctx.Error(helper.BadRequest("Invalid request body: %s", err))
+		return
+	}
+
+	// Too many failed logins of the account or from the address have to wait
+	// first, the login is counted before the password is checked
+	now := time.Now()
+	wait, err := controller.loginThrottle.Reserve(loginData.Email, ctx.ClientIP(), now)
+	if err != nil {
+		ctx.Error(fmt.Errorf("could not check failed logins: %w", err))
+		return
+	}
+	if wait > 0 {
+		auth.Audit(ctx, model.AuditEvent{
+			Action:  model.AuditLogin,
+			Email:   loginData.Email,
+			Outcome: model.AuditFailure,
+			Detail:  services.ErrLoginThrottled.Message,
+		})
+		ctx.Header("Retry-After", strconv.Itoa(int(math.Ceil(wait.Seconds()))))
+		ctx.Error(services.ErrLoginThrottled)
 		return
 	}
 
 	// Authenticate user
 	user, err := controller.usersService.Authenticate(loginData.Email, loginData.Password)
 	if err != nil {
-		ctx.JSON(http.StatusUnauthorized, gin.H{"error": "invalid credentials"})
+		// Only a wrong password counts as a failed login
+		var recordErr error
+		if errors.Is(err, services.ErrInvalidCredentials) {
+			recordErr = controller.loginThrottle.Failed(loginData.Email, ctx.ClientIP(), now)
+		} else {
+			recordErr = controller.loginThrottle.Release(loginData.Email, ctx.ClientIP())
+		}
+		if recordErr != nil {
+			ctx.Error(fmt.Errorf("could not record failed login: %w", recordErr))
+			return
+		}
+		auth.Audit(ctx, model.AuditEvent{
+			Action:  model.AuditLogin,
+			Email:   loginData.Email,
+			Outcome: model.AuditFailure,
+			Detail:  helper.AsAppError(err).Message,
+		})
+		ctx.Error(err)
 		return
 	}
 
-	// Generate JWT token including user role
-	token, err := auth.GenerateJWT(user.Id, user.Email, user.Role)
+	err = controller.loginThrottle.Succeeded(loginData.Email, ctx.ClientIP())
 	if err != nil {
-		ctx.JSON(http.StatusInternalServerError, gin.H{"error": "could not generate token"})
+		ctx.Error(fmt.Errorf("could not reset failed logins: %w", err))
 		return
 	}
 
-	// Add Bearer prefix to token
-	bearerToken := "Bearer " + token
+	// Generate access token including user role, plus a refresh token
+	tokens, err := auth.IssueTokenPair(user, controller.tokenService)
+	if err != nil {
+		ctx.Error(fmt.Errorf("could not generate token: %w", err))
+		return
+	}
+
+	auth.Audit(ctx, model.AuditEvent{
+		ActorId:    &user.Id,
+		Email:      user.Email,
+		Action:     model.AuditLogin,
+		TargetType: "user",
+		TargetId:   strconv.Itoa(user.Id),
+		Outcome:    model.AuditSuccess,
+	})
 
 	// Set Authorization header in the response
-	ctx.Header("Authorization", bearerToken)
+	ctx.Header("Authorization", tokens.Token)
+
+	// Return tokens and user data
+	ctx.JSON(http.StatusOK, gin.H{
+		"token":        tokens.Token,
+		"refreshToken": tokens.RefreshToken,
+		"expiresIn":    tokens.ExpiresIn,
+		"user":         response.NewUserResponse(user),
+	})
+}
+
+func (controller *UsersController) RefreshToken(ctx *gin.Context) {
+	var refreshRequest request.RefreshTokenRequest
+	err := ctx.ShouldBindJSON(&refreshRequest)
+	if err != nil {
+		ctx.Error(helper.BadRequest("Invalid request body: %s", err))
+		return
+	}
+
+	// Exchange the refresh token for a new pair
+	tokens, err := auth.RefreshTokenPair(refreshRequest.RefreshToken, controller.tokenService)
+	if err != nil {
+		ctx.Error(err)
+		return
+	}
+
+	ctx.Header("Authorization", tokens.Token)
+	ctx.JSON(http.StatusOK, tokens)
+}
+
+func (controller *UsersController) Logout(ctx *gin.Context) {
+	var logoutRequest request.LogoutRequest
+	if ctx.Request.ContentLength > 0 {
+		err := ctx.ShouldBindJSON(&logoutRequest)
+		if err != nil {
+			ctx.Error(helper.BadRequest("Invalid request body: %s", err))
+			return
+		}
+	}
+
+	// Revoke the access token or session used for this request
+	principal, _ := auth.CurrentPrincipal(ctx)
+	err := auth.EndSession(ctx, principal)
+	if err != nil {
+		ctx.Error(fmt.Errorf("could not revoke token: %w", err))
+		return
+	}
+
+	// Revoke the refresh token family as well, if the client sent it
+	if logoutRequest.RefreshToken != "" {
+		err = controller.tokenService.Revoke(logoutRequest.RefreshToken)
+		if err != nil && !errors.Is(err, services.ErrInvalidRefreshToken) {
+			ctx.Error(fmt.Errorf("could not revoke refresh token: %w", err))
+			return
+		}
+	}
+
+	ctx.JSON(http.StatusOK, gin.H{
+		"code":   http.StatusOK,
+		"status": "ok",
+		"msg":    "Logged out successfully.",
+	})
+}
+
+// RevokeSessions revokes every access and refresh token of a user (admin only)
+func (controller *UsersController) RevokeSessions(ctx *gin.Context) {
+	userId, err := strconv.Atoi(ctx.Param("userId"))
+	if err != nil {
+		ctx.Error(helper.BadRequest("Invalid user ID: %s", ctx.Param("userId")))
+		return
+	}
+
+	err = auth.RevokeUserSessions(userId)
+	if err != nil {
+		ctx.Error(fmt.Errorf("could not revoke sessions: %w", err))
+		return
+	}
+
+	err = controller.tokenService.RevokeAllForUser(userId)
+	if err != nil {
+		ctx.Error(fmt.Errorf("could not revoke refresh tokens: %w", err))
+		return
+	}
+
+	ctx.JSON(http.StatusOK, gin.H{
+		"code":   http.StatusOK,
+		"status": "ok",
+		"msg":    "Revoked all sessions for user " + strconv.Itoa(userId),
+	})
+}
+
+// Unlock forgets the failed logins of a user so they can log in at once (admin only)
+func (controller *UsersController) Unlock(ctx *gin.Context) {
+	userId, err := strconv.Atoi(ctx.Param("userId"))
+	if err != nil {
+		ctx.Error(helper.BadRequest("Invalid user ID: %s", ctx.Param("userId")))
+		return
+	}
+
+	user, err := controller.usersService.FindUserById(userId)
+	if err != nil {
+		ctx.Error(err)
+		return
+	}
+	if user == nil {
+		ctx.Error(services.ErrUserNotFound)
+		return
+	}
+
+	err = controller.loginThrottle.Reset(user.Email)
+	if err != nil {
+		ctx.Error(fmt.Errorf("could not reset failed logins: %w", err))
+		return
+	}
+
+	ctx.JSON(http.StatusOK, gin.H{
+		"code":   http.StatusOK,
+		"status": "ok",
+		"msg":    "Unlocked user " + strconv.Itoa(userId),
+	})
+}
+
+// UnlockAddress forgets the failed logins from a client address, the accounts
+// that were tried from it stay locked until they are unlocked as well
+func (controller *UsersController) UnlockAddress(ctx *gin.Context) {
+	ip := net.ParseIP(ctx.Param("ip"))
+	if ip == nil {
+		ctx.Error(helper.BadRequest("Invalid address: %s", ctx.Param("ip")))
+		return
+	}
+
+	err := controller.loginThrottle.ResetAddress(ip.String())
+	if err != nil {
+		ctx.Error(fmt.Errorf("could not reset failed logins: %w", err))
+		return
+	}
 
-	// Return token and user data
 	ctx.JSON(http.StatusOK, gin.H{
-		"token": bearerToken,
-		"user":  user,
+		"code":   http.StatusOK,
+		"status": "ok",
+		"msg":    "Unlocked address " + ip.String(),
+	})
+}
+
+// UpdateRole grants a role to a user (admin only)
+func (controller *UsersController) UpdateRole(ctx *gin.Context) {
+	var roleRequest request.UpdateUserRoleRequest
+	err := ctx.ShouldBindJSON(&roleRequest)
+	if err != nil {
+		ctx.Error(helper.BadRequest("Invalid request body: %s", err))
+		return
+	}
+
+	userId, err := strconv.Atoi(ctx.Param("userId"))
+	if err != nil {
+		ctx.Error(helper.BadRequest("Invalid user ID: %s", ctx.Param("userId")))
+		return
+	}
+	roleRequest.Id = userId
+
+	// Only existing roles can be granted
+	known, err := auth.IsKnownRole(roleRequest.Role)
+	if err != nil {
+		ctx.Error(err)
+		return
+	}
+	if !known {
+		ctx.Error(helper.Validation("Unknown role: %s", roleRequest.Role))
+		return
+	}
+
+	err = controller.usersService.UpdateRole(roleRequest)
+	if err != nil {
+		ctx.Error(err)
+		return
+	}
+	auth.SetAuditDetail(ctx, "role "+roleRequest.Role)
+
+	// Tokens carry the old role, make the user pick up the new one
+	err = auth.RevokeUserSessions(userId)
+	if err != nil {
+		ctx.Error(fmt.Errorf("could not revoke sessions: %w", err))
+		return
+	}
+
+	ctx.JSON(http.StatusOK, gin.H{
+		"code":   http.StatusOK,
+		"status": "ok",
+		"data":   gin.H{"id": userId, "role": roleRequest.Role},
+		"msg":    "User role updated successfully.",
 	})
 }
@@ -0,0 +1,6 @@
+package request
+
+// AttachNechesRequest attaches several Neches to a Tag at once
+type AttachNechesRequest struct {
+	NecheIDs []int `validate:"required,min=1,max=100,dive,min=1" json:"necheIds"`
+}
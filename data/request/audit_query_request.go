@@ -0,0 +1,12 @@
+package request
+
+import "time"
+
+// AuditQueryRequest holds the filters of the audit log listing and export
+type AuditQueryRequest struct {
+	UserId *int       `form:"userId" validate:"omitempty,min=1"` // Events the user did or was the target of
+	Action string     `form:"action" validate:"max=50"`
+	From   *time.Time `form:"from" time_format:"2006-01-02T15:04:05Z07:00"` // RFC 3339, inclusive
+	To     *time.Time `form:"to" time_format:"2006-01-02T15:04:05Z07:00"`   // RFC 3339, exclusive
+	PageRequest
+}
@@ -0,0 +1,9 @@
+package request
+
+import "time"
+
+type CreateApiKeyRequest struct {
+	Name      string     `validate:"required,min=1,max=100" json:"name"`
+	Scopes    []string   `validate:"required,min=1,dive,required,max=100" json:"scopes"`
+	ExpiresAt *time.Time `json:"expiresAt"`
+}
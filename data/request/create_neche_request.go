@@ -1,6 +1,9 @@
 package request
 
 type CreateNecheRequest struct {
-	Name  string `validate:"required,min=1,max=200" json:"name"`
-	TagID    int    `gorm:"not null" json:"tagId"`
+	Name   string `validate:"required,min=1,max=200" json:"name"`
+	TagIDs []int  `validate:"required,min=1,max=100,dive,min=1" json:"tagIds"`
+	// Deprecated: clients from before neches had several tags send one tag
+	// here, it is only read when tagIds is missing
+	TagID int `validate:"omitempty,min=1" json:"tagId,omitempty"`
 }
@@ -0,0 +1,7 @@
+package request
+
+// CreatePermissionRequest names a permission as "resource:action", e.g. "tags:create"
+type CreatePermissionRequest struct {
+	Name        string `validate:"required,min=3,max=100,contains=:" json:"name"`
+	Description string `validate:"max=255" json:"description"`
+}
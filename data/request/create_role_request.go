@@ -0,0 +1,6 @@
+package request
+
+type CreateRoleRequest struct {
+	Name        string `validate:"required,min=1,max=100" json:"name"`
+	Description string `validate:"max=255" json:"description"`
+}
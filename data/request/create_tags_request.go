@@ -1,5 +1,6 @@
 package request
 
 type CreteTagsRequest struct {
-	Name string `validate:"required,min=1,max=200" json:"name"`
+	Name     string `validate:"required,min=1,max=200" json:"name"`
+	ParentId *int   `validate:"omitempty,min=1" json:"parentId"` // A root tag when missing
 }
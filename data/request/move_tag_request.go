@@ -0,0 +1,9 @@
+package request
+
+// MoveTagRequest moves a tag and its subtree under another tag
+type MoveTagRequest struct {
+	Id       int  `validate:"required" json:"-"`
+	ParentId *int `validate:"omitempty,min=1" json:"parentId"` // null makes the tag a root
+	// Version the client read, the move fails when it's stale. 0 moves any version.
+	Version int `json:"-"`
+}
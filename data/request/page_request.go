@@ -0,0 +1,8 @@
+package request
+
+// PageRequest selects a page of a listing, by offset or by cursor
+type PageRequest struct {
+	Limit  int    `form:"-" json:"-"`
+	Offset int    `form:"-" json:"-"`
+	Cursor string `form:"cursor" json:"cursor"` // Offset is ignored when set
+}
@@ -0,0 +1,9 @@
+package request
+
+// PatchRequest changes some fields of a resource with a JSON Merge Patch or a JSON Patch
+type PatchRequest struct {
+	Id        int
+	Version   int    // Version the client read, 0 patches any version
+	MediaType string // helper.MergePatchType or helper.JSONPatchType
+	Patch     []byte
+}
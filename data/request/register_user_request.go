@@ -0,0 +1,7 @@
+package request
+
+type RegisterUserRequest struct {
+	Name     string `validate:"required,min=1,max=255" json:"name"`
+	Email    string `validate:"required,email,max=255" json:"email"`
+	Password string `validate:"required,min=8,max=72" json:"password"`
+}
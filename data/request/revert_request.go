@@ -0,0 +1,9 @@
+package request
+
+// RevertRequest brings a tag or a neche back to the state a revision left it in
+type RevertRequest struct {
+	Id         int `json:"-"`
+	RevisionId int `json:"-"`
+	// Version the client read, the revert fails when it's stale. 0 reverts any version.
+	Version int `json:"-"`
+}
@@ -0,0 +1,12 @@
+package request
+
+// SearchTagsRequest holds the query parameters of the tags listing
+type SearchTagsRequest struct {
+	Q         string `form:"q" json:"q" validate:"max=200"`
+	Match     string `form:"match" json:"match" validate:"omitempty,oneof=contains prefix"`
+	Sort      string `form:"sort" json:"sort" validate:"max=200"` // e.g. "-necheCount,name"
+	HasNeches *bool  `form:"hasNeches" json:"hasNeches"`
+	MinNeches *int   `form:"minNeches" json:"minNeches" validate:"omitempty,min=0"`
+	MaxNeches *int   `form:"maxNeches" json:"maxNeches" validate:"omitempty,min=0"`
+	PageRequest
+}
@@ -0,0 +1,12 @@
+package request
+
+// UpdateNecheRequest renames a Neche and replaces its Tags
+type UpdateNecheRequest struct {
+	Id     int    `validate:"required"`
+	Name   string `validate:"required,min=1,max=200" json:"name"`
+	TagIDs []int  `validate:"required,min=1,max=100,dive,min=1" json:"tagIds"`
+	// Deprecated: like CreateNecheRequest.TagID, only read when tagIds is missing
+	TagID int `validate:"omitempty,min=1" json:"tagId,omitempty"`
+	// Version the client read, the update fails when it's stale. 0 updates any version.
+	Version int `json:"-"`
+}
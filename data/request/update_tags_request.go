@@ -1,6 +1,8 @@
 package request
 
 type UpdateTagsRequest struct {
-	Id   int    `validate:"required"`
-	Name string `validate:"required, max=200, min=1" json:"name"`
+	Id   int    `validate:"required" json:"id"`
+	Name string `validate:"required,min=1,max=200" json:"name"`
+	// Version the client read, the update fails when it's stale. 0 updates any version.
+	Version int `json:"-"`
 }
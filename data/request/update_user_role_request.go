@@ -0,0 +1,6 @@
+package request
+
+type UpdateUserRoleRequest struct {
+	Id   int    `validate:"required"`
+	Role string `validate:"required,max=255" json:"role"`
+}
@@ -0,0 +1,36 @@
+package response
+
+import (
+	"strings"
+	"time"
+
+	"example.com/go-project/model"
+)
+
+// ApiKeyResponse describes a key, the secret itself is only returned once on creation
+type ApiKeyResponse struct {
+	Id         int        `json:"id"`
+	Name       string     `json:"name"`
+	Prefix     string     `json:"prefix"`
+	Scopes     []string   `json:"scopes"`
+	ExpiresAt  *time.Time `json:"expiresAt"`
+	LastUsedAt *time.Time `json:"lastUsedAt"`
+	CreatedAt  time.Time  `json:"createdAt"`
+	Key        string     `json:"key,omitempty"`
+}
+
+func NewApiKeyResponse(key *model.ApiKey) ApiKeyResponse {
+	scopes := []string{}
+	if key.Scopes != "" {
+		scopes = strings.Split(key.Scopes, ",")
+	}
+	return ApiKeyResponse{
+		Id:         key.Id,
+		Name:       key.Name,
+		Prefix:     key.Prefix,
+		Scopes:     scopes,
+		ExpiresAt:  key.ExpiresAt,
+		LastUsedAt: key.LastUsedAt,
+		CreatedAt:  key.CreatedAt,
+	}
+}
@@ -0,0 +1,45 @@
+package response
+
+import (
+	"time"
+
+	"example.com/go-project/model"
+)
+
+// AuditEventResponse is one entry of the audit log, the export writes one per line
+type AuditEventResponse struct {
+	Id         int       `json:"id"`
+	ActorId    *int      `json:"actorId"`
+	Email      string    `json:"email,omitempty"`
+	Ip         string    `json:"ip"`
+	UserAgent  string    `json:"userAgent"`
+	Action     string    `json:"action"`
+	TargetType string    `json:"targetType,omitempty"`
+	TargetId   string    `json:"targetId,omitempty"`
+	Outcome    string    `json:"outcome"`
+	Detail     string    `json:"detail,omitempty"`
+	CreatedAt  time.Time `json:"createdAt"`
+}
+
+func NewAuditEventResponse(event model.AuditEvent) AuditEventResponse {
+	return AuditEventResponse{
+		Id:         event.Id,
+		ActorId:    event.ActorId,
+		Email:      event.Email,
+		Ip:         event.Ip,
+		UserAgent:  event.UserAgent,
+		Action:     event.Action,
+		TargetType: event.TargetType,
+		TargetId:   event.TargetId,
+		Outcome:    event.Outcome,
+		Detail:     event.Detail,
+		CreatedAt:  event.CreatedAt,
+	}
+}
+
+// AuditEventsPage is one page of the audit log, newest first
+type AuditEventsPage struct {
+	Events []AuditEventResponse
+	Total  int64
+	Cursors
+}
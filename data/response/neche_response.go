@@ -1,7 +1,16 @@
 package response
 
 type NecheResponse struct {
-	Id     int    `json:"id"`
-	Name   string `json:"name"`
-	TagsID int    `json:"tagId"`
+	Id   int                `json:"id"`
+	Name string             `json:"name"`
+	Tags []NecheTagResponse `json:"tags"`
+	// Deprecated: the first of the tags, for clients from before neches had several
+	TagId   int `json:"tagId"`
+	Version int `json:"version"`
+}
+
+// NecheTagResponse is a Tag a Neche belongs to
+type NecheTagResponse struct {
+	Id   int    `json:"id"`
+	Name string `json:"name"`
 }
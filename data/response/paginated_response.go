@@ -1,10 +1,38 @@
 package response
 
 type PaginatedResponse struct {
-	Code   int         `json:"code"`
-	Status string      `json:"status"`
-	Data   interface{} `json:"data"`
-	Limit  int         `json:"limit"`
-	Offset int         `json:"offset"`
-	Msg    string      `json:"msg"`
-}
\ No newline at end of file
+	Code       int         `json:"code"`
+	Status     string      `json:"status"`
+	Data       interface{} `json:"data"`
+	Limit      int         `json:"limit"`
+	Offset     int         `json:"offset"`
+	Total      int64       `json:"total"`
+	Page       int         `json:"page"`
+	PageSize   int         `json:"pageSize"`
+	TotalPages int         `json:"totalPages"`
+	Next       string      `json:"next,omitempty"` // Link to the next page, empty on the last page
+	Prev       string      `json:"prev,omitempty"` // Link to the previous page, empty on the first page
+	NextCursor string      `json:"nextCursor,omitempty"`
+	PrevCursor string      `json:"prevCursor,omitempty"`
+	Msg        string      `json:"msg"`
+}
+
+// Cursors are the tokens of the pages around a page, empty when there is none
+type Cursors struct {
+	Next string
+	Prev string
+}
+
+// TagsPage is one page of a tags listing
+type TagsPage struct {
+	Tags  []TagsResponse
+	Total int64
+	Cursors
+}
+
+// NechesPage is one page of a neches listing
+type NechesPage struct {
+	Neches []NecheResponse
+	Total  int64
+	Cursors
+}
@@ -0,0 +1,25 @@
+package response
+
+import (
+	"encoding/json"
+	"time"
+)
+
+// RevisionResponse is one recorded change of a tag or a neche
+type RevisionResponse struct {
+	Id         int             `json:"id"`
+	EntityType string          `json:"entityType"`
+	EntityId   int             `json:"entityId"`
+	Action     string          `json:"action"`
+	ActorId    *int            `json:"actorId"`
+	Before     json.RawMessage `json:"before"` // null when the entity didn't exist or was in the trash
+	After      json.RawMessage `json:"after"`  // null when the entity was deleted
+	CreatedAt  time.Time       `json:"createdAt"`
+}
+
+// RevisionsPage is one page of the history of a tag or a neche, newest first
+type RevisionsPage struct {
+	Revisions []RevisionResponse
+	Total     int64
+	Cursors
+}
@@ -0,0 +1,37 @@
+package response
+
+import "example.com/go-project/model"
+
+type RoleResponse struct {
+	Id          int      `json:"id"`
+	Name        string   `json:"name"`
+	Description string   `json:"description"`
+	Permissions []string `json:"permissions"`
+}
+
+type PermissionResponse struct {
+	Id          int    `json:"id"`
+	Name        string `json:"name"`
+	Description string `json:"description"`
+}
+
+func NewRoleResponse(role *model.Role) RoleResponse {
+	permissions := []string{}
+	for _, permission := range role.Permissions {
+		permissions = append(permissions, permission.Name)
+	}
+	return RoleResponse{
+		Id:          role.Id,
+		Name:        role.Name,
+		Description: role.Description,
+		Permissions: permissions,
+	}
+}
+
+func NewPermissionResponse(permission *model.Permission) PermissionResponse {
+	return PermissionResponse{
+		Id:          permission.Id,
+		Name:        permission.Name,
+		Description: permission.Description,
+	}
+}
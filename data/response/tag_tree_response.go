@@ -0,0 +1,14 @@
+package response
+
+// TagTreeResponse is a tag with its descendants nested under it
+type TagTreeResponse struct {
+	Id       int               `json:"id"`
+	Name     string            `json:"name"`
+	Children []TagTreeResponse `json:"children"`
+}
+
+// BreadcrumbResponse is one tag on the path from a root tag down to a tag
+type BreadcrumbResponse struct {
+	Id   int    `json:"id"`
+	Name string `json:"name"`
+}
@@ -3,5 +3,7 @@ package response
 type TagsResponse struct{
 	Id int `json:"id"`
 	Name string `json:"name"`
+	ParentId *int `json:"parentId"`
 	Neches []NecheResponse `json:"neches"`
-}
\ No newline at end of file
+	Version int `json:"version"`
+}
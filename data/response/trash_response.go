@@ -0,0 +1,36 @@
+package response
+
+import "time"
+
+// TrashedTagResponse is a tag in the trash
+type TrashedTagResponse struct {
+	Id        int       `json:"id"`
+	Name      string    `json:"name"`
+	ParentId  *int      `json:"parentId"`
+	DeletedAt time.Time `json:"deletedAt"`
+	PurgeAt   time.Time `json:"purgeAt"` // When it's deleted for good
+}
+
+// TrashedNecheResponse is a neche in the trash
+type TrashedNecheResponse struct {
+	Id               int                `json:"id"`
+	Name             string             `json:"name"`
+	Tags             []NecheTagResponse `json:"tags"`
+	DeletedWithTagId *int               `json:"deletedWithTagId"` // Set when deleting the tag took the neche along
+	DeletedAt        time.Time          `json:"deletedAt"`
+	PurgeAt          time.Time          `json:"purgeAt"`
+}
+
+// TrashedTagsPage is one page of the tags in the trash
+type TrashedTagsPage struct {
+	Tags  []TrashedTagResponse
+	Total int64
+	Cursors
+}
+
+// TrashedNechesPage is one page of the neches in the trash
+type TrashedNechesPage struct {
+	Neches []TrashedNecheResponse
+	Total  int64
+	Cursors
+}
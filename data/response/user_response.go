@@ -0,0 +1,20 @@
+package response
+
+import "example.com/go-project/model"
+
+// UserResponse is the public view of a user, it never contains the password
+type UserResponse struct {
+	Id    int    `json:"id"`
+	Name  string `json:"name"`
+	Email string `json:"email"`
+	Role  string `json:"role"`
+}
+
+func NewUserResponse(user *model.Users) UserResponse {
+	return UserResponse{
+		Id:    user.Id,
+		Name:  user.Name,
+		Email: user.Email,
+		Role:  user.Role,
+	}
+}
@@ -1,8 +1,12 @@
 package response
 
+import "example.com/go-project/helper"
+
 type Response struct {
-	Code   int         `json:"code"`
-	Status string      `json:"status"`
-	Data   interface{} `json:"data, omitempty"`
-	Msg    string      `json:"msg"`
+	Code      int                 `json:"code"`
+	Status    string              `json:"status"`
+	Data      interface{}         `json:"data"`
+	Msg       string              `json:"msg"`
+	ErrorCode string              `json:"errorCode,omitempty"` // Machine readable, set on errors only
+	Errors    []helper.FieldError `json:"errors,omitempty"`    // Failed fields of a validation error
 }
@@ -0,0 +1,159 @@
+package helper
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+
+	"github.com/go-playground/validator"
+	"gorm.io/gorm"
+)
+
+// ErrorKind classifies errors so they can be mapped to a status code
+type ErrorKind int
+
+const (
+	KindInternal ErrorKind = iota
+	KindBadRequest
+	KindValidation
+	KindUnauthorized
+	KindForbidden
+	KindNotFound
+	KindConflict
+	KindPreconditionFailed
+	KindPreconditionRequired
+	KindUnsupportedMediaType
+	KindTooManyRequests
+)
+
+var kinds = map[ErrorKind]struct {
+	status int
+	code   string
+}{
+	KindInternal:     {http.StatusInternalServerError, "internal_error"},
+	KindBadRequest:   {http.StatusBadRequest, "bad_request"},
+	KindValidation:   {http.StatusBadRequest, "validation_failed"},
+	KindUnauthorized: {http.StatusUnauthorized, "unauthorized"},
+	KindForbidden:    {http.StatusForbidden, "forbidden"},
+	KindNotFound:     {http.StatusNotFound, "not_found"},
+	KindConflict:     {http.StatusConflict, "conflict"},
+
+	KindPreconditionFailed:   {http.StatusPreconditionFailed, "precondition_failed"},
+	KindPreconditionRequired: {http.StatusPreconditionRequired, "precondition_required"},
+	KindUnsupportedMediaType: {http.StatusUnsupportedMediaType, "unsupported_media_type"},
+	KindTooManyRequests:      {http.StatusTooManyRequests, "too_many_requests"},
+}
+
+// AppError is an error services and repositories return to tell the caller what went wrong
+type AppError struct {
+	Kind    ErrorKind
+	Message string       // Safe to show to the client
+	Details []FieldError // The failed fields of a validation error
+	Err     error        // The underlying cause, if any
+}
+
+// Errors of each kind, errors.Is(err, helper.ErrNotFound) matches any not found error
+var (
+	ErrBadRequest   = &AppError{Kind: KindBadRequest}
+	ErrValidation   = &AppError{Kind: KindValidation}
+	ErrUnauthorized = &AppError{Kind: KindUnauthorized}
+	ErrForbidden    = &AppError{Kind: KindForbidden}
+	ErrNotFound     = &AppError{Kind: KindNotFound}
+	ErrConflict     = &AppError{Kind: KindConflict}
+
+	ErrPreconditionFailed   = &AppError{Kind: KindPreconditionFailed}
+	ErrPreconditionRequired = &AppError{Kind: KindPreconditionRequired}
+	ErrUnsupportedMediaType = &AppError{Kind: KindUnsupportedMediaType}
+	ErrTooManyRequests      = &AppError{Kind: KindTooManyRequests}
+)
+
+func (e *AppError) Error() string {
+	if e.Message != "" {
+		return e.Message
+	}
+	if e.Err != nil {
+		return e.Err.Error()
+	}
+	return e.Code()
+}
+
+func (e *AppError) Unwrap() error {
+	return e.Err
+}
+
+// Is matches the bare kind errors like ErrNotFound against any error of that kind
+func (e *AppError) Is(target error) bool {
+	t, ok := target.(*AppError)
+	return ok && t.Message == "" && t.Err == nil && t.Kind == e.Kind
+}
+
+// Status is the HTTP status code of the error
+func (e *AppError) Status() int {
+	return kinds[e.Kind].status
+}
+
+// Code is the machine readable error code sent to clients
+func (e *AppError) Code() string {
+	return kinds[e.Kind].code
+}
+
+func BadRequest(format string, args ...interface{}) *AppError {
+	return &AppError{Kind: KindBadRequest, Message: fmt.Sprintf(format, args...)}
+}
+
+func Validation(format string, args ...interface{}) *AppError {
+	return &AppError{Kind: KindValidation, Message: fmt.Sprintf(format, args...)}
+}
+
+func Unauthorized(format string, args ...interface{}) *AppError {
+	return &AppError{Kind: KindUnauthorized, Message: fmt.Sprintf(format, args...)}
+}
+
+func Forbidden(format string, args ...interface{}) *AppError {
+	return &AppError{Kind: KindForbidden, Message: fmt.Sprintf(format, args...)}
+}
+
+func NotFound(format string, args ...interface{}) *AppError {
+	return &AppError{Kind: KindNotFound, Message: fmt.Sprintf(format, args...)}
+}
+
+func Conflict(format string, args ...interface{}) *AppError {
+	return &AppError{Kind: KindConflict, Message: fmt.Sprintf(format, args...)}
+}
+
+func PreconditionFailed(format string, args ...interface{}) *AppError {
+	return &AppError{Kind: KindPreconditionFailed, Message: fmt.Sprintf(format, args...)}
+}
+
+func PreconditionRequired(format string, args ...interface{}) *AppError {
+	return &AppError{Kind: KindPreconditionRequired, Message: fmt.Sprintf(format, args...)}
+}
+
+func UnsupportedMediaType(format string, args ...interface{}) *AppError {
+	return &AppError{Kind: KindUnsupportedMediaType, Message: fmt.Sprintf(format, args...)}
+}
+
+func TooManyRequests(format string, args ...interface{}) *AppError {
+	return &AppError{Kind: KindTooManyRequests, Message: fmt.Sprintf(format, args...)}
+}
+
+// AsAppError classifies any error. Errors that aren't known are internal,
+// their message is not shown to the client.
+func AsAppError(err error) *AppError {
+	var appErr *AppError
+	if errors.As(err, &appErr) {
+		// Keep the context added by wrapping, e.g. "tag with ID 5: tag not found"
+		return &AppError{Kind: appErr.Kind, Message: err.Error(), Details: appErr.Details, Err: err}
+	}
+
+	var validationErrors validator.ValidationErrors
+	switch {
+	case errors.As(err, &validationErrors):
+		return &AppError{Kind: KindValidation, Message: "validation failed", Details: FieldErrors(validationErrors), Err: err}
+	case errors.Is(err, gorm.ErrRecordNotFound):
+		return &AppError{Kind: KindNotFound, Message: "record not found", Err: err}
+	case errors.Is(err, gorm.ErrDuplicatedKey):
+		return &AppError{Kind: KindConflict, Message: "record already exists", Err: err}
+	}
+	return &AppError{Kind: KindInternal, Message: "internal server error", Err: err}
+}
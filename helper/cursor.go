@@ -0,0 +1,87 @@
+package helper
+
+import (
+	"bytes"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"strings"
+)
+
+// Cursor is the position of a row in a sorted listing. Clients get it as an
+// opaque token, the signature keeps them from forging positions.
+type Cursor struct {
+	Scope    string        `json:"s"`           // The listing and sort the cursor belongs to
+	Values   []interface{} `json:"v"`           // Sort key values of the row, its id last
+	Backward bool          `json:"b,omitempty"` // Page towards the start of the listing
+}
+
+// ErrInvalidCursor is returned for cursors that were tampered with or belong to another listing
+var ErrInvalidCursor = BadRequest("invalid cursor")
+
+var cursorKey = newCursorKey()
+
+// newCursorKey generates a key, cursors signed with it don't survive a restart
+func newCursorKey() []byte {
+	key := make([]byte, 32)
+	_, err := rand.Read(key)
+	ErrorPanic(err)
+	return key
+}
+
+// UseCursorKey sets the key cursors are signed with
+func UseCursorKey(key []byte) {
+	cursorKey = key
+}
+
+// EncodeCursor signs the cursor and returns its token
+func EncodeCursor(cursor Cursor) (string, error) {
+	payload, err := json.Marshal(cursor)
+	if err != nil {
+		return "", err
+	}
+	return base64.RawURLEncoding.EncodeToString(payload) + "." + base64.RawURLEncoding.EncodeToString(signCursor(payload)), nil
+}
+
+// DecodeCursor checks the signature of a token and that it belongs to the scope
+func DecodeCursor(token string, scope string) (Cursor, error) {
+	encodedPayload, encodedSignature, ok := strings.Cut(token, ".")
+	if !ok {
+		return Cursor{}, ErrInvalidCursor
+	}
+	payload, err := base64.RawURLEncoding.DecodeString(encodedPayload)
+	if err != nil {
+		return Cursor{}, ErrInvalidCursor
+	}
+	signature, err := base64.RawURLEncoding.DecodeString(encodedSignature)
+	if err != nil || !hmac.Equal(signature, signCursor(payload)) {
+		return Cursor{}, ErrInvalidCursor
+	}
+
+	// Keep numbers exact, ids don't fit a float64 on every database
+	decoder := json.NewDecoder(bytes.NewReader(payload))
+	decoder.UseNumber()
+	var cursor Cursor
+	if err := decoder.Decode(&cursor); err != nil || cursor.Scope != scope {
+		return Cursor{}, ErrInvalidCursor
+	}
+
+	for i, value := range cursor.Values {
+		if number, ok := value.(json.Number); ok {
+			if n, err := number.Int64(); err == nil {
+				cursor.Values[i] = n
+			} else if f, err := number.Float64(); err == nil {
+				cursor.Values[i] = f
+			}
+		}
+	}
+	return cursor, nil
+}
+
+func signCursor(payload []byte) []byte {
+	mac := hmac.New(sha256.New, cursorKey)
+	mac.Write(payload)
+	return mac.Sum(nil)
+}
@@ -0,0 +1,370 @@
+package helper
+
+import (
+	"bytes"
+	"encoding/json"
+	"reflect"
+	"strconv"
+	"strings"
+)
+
+// Media types of the patch formats PATCH endpoints accept
+const (
+	MergePatchType = "application/merge-patch+json" // RFC 7386
+	JSONPatchType  = "application/json-patch+json"  // RFC 6902
+)
+
+// ApplyPatch applies a patch of the media type to a JSON document. Plain JSON
+// is read as a merge patch, it replaces the fields it names like it always did.
+func ApplyPatch(mediaType string, document []byte, patch []byte) ([]byte, error) {
+	if mediaType != MergePatchType && mediaType != JSONPatchType && mediaType != "application/json" {
+		return nil, UnsupportedMediaType("PATCH accepts %s or %s, not %q", MergePatchType, JSONPatchType, mediaType)
+	}
+
+	doc, err := decodeJSON(document)
+	if err != nil {
+		return nil, err
+	}
+	patchValue, err := decodeJSON(patch)
+	if err != nil {
+		return nil, BadRequest("Invalid patch: %s", err)
+	}
+
+	if mediaType == JSONPatchType {
+		doc, err = jsonPatch(doc, patch)
+		if err != nil {
+			return nil, err
+		}
+	} else {
+		doc = mergePatch(doc, patchValue)
+	}
+	return json.Marshal(doc)
+}
+
+// decodeJSON keeps numbers exact, a patch shouldn't change values it doesn't touch
+func decodeJSON(data []byte) (interface{}, error) {
+	decoder := json.NewDecoder(bytes.NewReader(data))
+	decoder.UseNumber()
+	var value interface{}
+	if err := decoder.Decode(&value); err != nil {
+		return nil, err
+	}
+	if decoder.More() {
+		return nil, BadRequest("unexpected data after the JSON value")
+	}
+	return value, nil
+}
+
+// mergePatch merges the patch into the target, null removes a member
+func mergePatch(target, patch interface{}) interface{} {
+	patchObject, ok := patch.(map[string]interface{})
+	if !ok {
+		return patch
+	}
+	targetObject, ok := target.(map[string]interface{})
+	if !ok {
+		targetObject = map[string]interface{}{}
+	}
+	for name, value := range patchObject {
+		if value == nil {
+			delete(targetObject, name)
+		} else {
+			targetObject[name] = mergePatch(targetObject[name], value)
+		}
+	}
+	return targetObject
+}
+
+// patchOperation is one operation of a JSON Patch
+type patchOperation struct {
+	Op    string          `json:"op"`
+	Path  *string         `json:"path"`
+	From  *string         `json:"from"`
+	Value json.RawMessage `json:"value"` // nil when missing, "null" for null
+}
+
+// jsonPatch applies the operations in order. The patch fails as a whole if one of them does.
+func jsonPatch(doc interface{}, patch []byte) (interface{}, error) {
+	var operations []patchOperation
+	if err := json.Unmarshal(patch, &operations); err != nil {
+		return nil, BadRequest("Invalid JSON Patch: %s", err)
+	}
+
+	for i, operation := range operations {
+		var err error
+		doc, err = operation.apply(doc)
+		if err != nil {
+			if appErr, ok := err.(*AppError); ok {
+				appErr.Message = "operation " + strconv.Itoa(i) + ": " + appErr.Message
+			}
+			return nil, err
+		}
+	}
+	return doc, nil
+}
+
+func (o patchOperation) apply(doc interface{}) (interface{}, error) {
+	if o.Path == nil {
+		return nil, BadRequest("%s needs a path", o.Op)
+	}
+	path, err := parsePointer(*o.Path)
+	if err != nil {
+		return nil, err
+	}
+
+	switch o.Op {
+	case "add", "replace", "test":
+		if o.Value == nil {
+			return nil, BadRequest("%s needs a value", o.Op)
+		}
+		value, err := decodeJSON(o.Value)
+		if err != nil {
+			return nil, BadRequest("Invalid value: %s", err)
+		}
+		if o.Op == "test" {
+			current, err := path.get(doc)
+			if err != nil {
+				return nil, err
+			}
+			if !jsonEqual(current, value) {
+				return nil, Conflict("test failed, %s has another value", *o.Path)
+			}
+			return doc, nil
+		}
+		return path.set(doc, value, o.Op == "replace")
+	case "remove":
+		doc, _, err = path.remove(doc)
+		return doc, err
+	case "move", "copy":
+		if o.From == nil {
+			return nil, BadRequest("%s needs a from", o.Op)
+		}
+		from, err := parsePointer(*o.From)
+		if err != nil {
+			return nil, err
+		}
+		var value interface{}
+		if o.Op == "move" {
+			if from.isProperPrefixOf(path) {
+				return nil, Conflict("can't move %s into one of its children", *o.From)
+			}
+			doc, value, err = from.remove(doc)
+		} else {
+			value, err = from.get(doc)
+			value = copyJSON(value)
+		}
+		if err != nil {
+			return nil, err
+		}
+		return path.set(doc, value, false)
+	default:
+		return nil, BadRequest("unknown operation %q", o.Op)
+	}
+}
+
+// jsonPointer is a parsed RFC 6901 pointer, no tokens points at the whole document
+type jsonPointer []string
+
+func parsePointer(pointer string) (jsonPointer, error) {
+	if pointer == "" {
+		return jsonPointer{}, nil
+	}
+	if !strings.HasPrefix(pointer, "/") {
+		return nil, BadRequest("invalid JSON pointer %q", pointer)
+	}
+	tokens := strings.Split(pointer[1:], "/")
+	for i, token := range tokens {
+		tokens[i] = strings.NewReplacer("~1", "/", "~0", "~").Replace(token)
+	}
+	return tokens, nil
+}
+
+func (p jsonPointer) String() string {
+	var b strings.Builder
+	for _, token := range p {
+		b.WriteString("/" + strings.NewReplacer("~", "~0", "/", "~1").Replace(token))
+	}
+	return b.String()
+}
+
+func (p jsonPointer) isProperPrefixOf(other jsonPointer) bool {
+	return len(p) < len(other) && reflect.DeepEqual([]string(p), []string(other[:len(p)]))
+}
+
+func (p jsonPointer) get(doc interface{}) (interface{}, error) {
+	for i, token := range p {
+		switch container := doc.(type) {
+		case map[string]interface{}:
+			value, ok := container[token]
+			if !ok {
+				return nil, p[:i+1].missing()
+			}
+			doc = value
+		case []interface{}:
+			index, err := arrayIndex(token, len(container)-1)
+			if err != nil {
+				return nil, p[:i+1].missing()
+			}
+			doc = container[index]
+		default:
+			return nil, p[:i+1].missing()
+		}
+	}
+	return doc, nil
+}
+
+// set adds the value at the pointer, or replaces the value there. It returns
+// the document, which is the value itself when the pointer is the root.
+func (p jsonPointer) set(doc interface{}, value interface{}, replace bool) (interface{}, error) {
+	if len(p) == 0 {
+		return value, nil
+	}
+	parent, err := p[:len(p)-1].get(doc)
+	if err != nil {
+		return nil, err
+	}
+	token := p[len(p)-1]
+
+	switch container := parent.(type) {
+	case map[string]interface{}:
+		if _, ok := container[token]; replace && !ok {
+			return nil, p.missing()
+		}
+		container[token] = value
+		return doc, nil
+	case []interface{}:
+		if replace {
+			index, err := arrayIndex(token, len(container)-1)
+			if err != nil {
+				return nil, p.missing()
+			}
+			container[index] = value
+			return doc, nil
+		}
+
+		// Adding to an array shifts the elements after the index, "-" appends
+		index := len(container)
+		if token != "-" {
+			index, err = arrayIndex(token, len(container))
+			if err != nil {
+				return nil, p.missing()
+			}
+		}
+		grown := append(container[:index:index], value)
+		grown = append(grown, container[index:]...)
+		return p[:len(p)-1].set(doc, grown, true)
+	default:
+		return nil, p.missing()
+	}
+}
+
+// remove removes the value at the pointer and returns the document and the removed value
+func (p jsonPointer) remove(doc interface{}) (interface{}, interface{}, error) {
+	if len(p) == 0 {
+		return nil, nil, Conflict("can't remove the whole document")
+	}
+	parent, err := p[:len(p)-1].get(doc)
+	if err != nil {
+		return nil, nil, err
+	}
+	token := p[len(p)-1]
+
+	switch container := parent.(type) {
+	case map[string]interface{}:
+		value, ok := container[token]
+		if !ok {
+			return nil, nil, p.missing()
+		}
+		delete(container, token)
+		return doc, value, nil
+	case []interface{}:
+		index, err := arrayIndex(token, len(container)-1)
+		if err != nil {
+			return nil, nil, p.missing()
+		}
+		value := container[index]
+		shrunk := append(container[:index:index], container[index+1:]...)
+		doc, err = p[:len(p)-1].set(doc, shrunk, true)
+		return doc, value, err
+	default:
+		return nil, nil, p.missing()
+	}
+}
+
+func (p jsonPointer) missing() error {
+	return Conflict("path %s doesn't exist", p)
+}
+
+// arrayIndex parses an array index up to max, leading zeros aren't allowed
+func arrayIndex(token string, max int) (int, error) {
+	if token == "" || (len(token) > 1 && token[0] == '0') || strings.Trim(token, "0123456789") != "" {
+		return 0, BadRequest("invalid array index %q", token)
+	}
+	index, err := strconv.Atoi(token)
+	if err != nil || index > max {
+		return 0, BadRequest("array index %q out of range", token)
+	}
+	return index, nil
+}
+
+// jsonEqual compares JSON values, numbers by value so 1 equals 1.0
+func jsonEqual(a, b interface{}) bool {
+	switch a := a.(type) {
+	case json.Number:
+		b, ok := b.(json.Number)
+		if !ok {
+			return false
+		}
+		x, errA := a.Float64()
+		y, errB := b.Float64()
+		if errA != nil || errB != nil {
+			return a == b
+		}
+		return x == y
+	case map[string]interface{}:
+		b, ok := b.(map[string]interface{})
+		if !ok || len(a) != len(b) {
+			return false
+		}
+		for name, value := range a {
+			other, ok := b[name]
+			if !ok || !jsonEqual(value, other) {
+				return false
+			}
+		}
+		return true
+	case []interface{}:
+		b, ok := b.([]interface{})
+		if !ok || len(a) != len(b) {
+			return false
+		}
+		for i := range a {
+			if !jsonEqual(a[i], b[i]) {
+				return false
+			}
+		}
+		return true
+	default:
+		return a == b
+	}
+}
+
+// copyJSON deep copies a value, copy mustn't share objects between two places
+func copyJSON(value interface{}) interface{} {
+	switch value := value.(type) {
+	case map[string]interface{}:
+		copied := make(map[string]interface{}, len(value))
+		for name, member := range value {
+			copied[name] = copyJSON(member)
+		}
+		return copied
+	case []interface{}:
+		copied := make([]interface{}, len(value))
+		for i, element := range value {
+			copied[i] = copyJSON(element)
+		}
+		return copied
+	default:
+		return value
+	}
+}
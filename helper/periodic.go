@@ -0,0 +1,23 @@
+package helper
+
+import "time"
+
+// StartPeriodic calls fn with the current time every interval until stop is called
+func StartPeriodic(interval time.Duration, fn func(now time.Time)) (stop func()) {
+	ticker := time.NewTicker(interval)
+	done := make(chan struct{})
+
+	go func() {
+		for {
+			select {
+			case now := <-ticker.C:
+				fn(now)
+			case <-done:
+				ticker.Stop()
+				return
+			}
+		}
+	}()
+
+	return func() { close(done) }
+}
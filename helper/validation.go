@@ -0,0 +1,178 @@
+package helper
+
+import (
+	"reflect"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/go-playground/locales"
+	"github.com/go-playground/locales/en"
+	ut "github.com/go-playground/universal-translator"
+	"github.com/go-playground/validator"
+)
+
+// FieldError tells the client which field failed which rule
+type FieldError struct {
+	Field   string `json:"field"`
+	Rule    string `json:"rule"`
+	Param   string `json:"param,omitempty"`
+	Message string `json:"message"`
+}
+
+// English messages keyed by rule. min and max read differently for strings,
+// numbers and lists, so they get a key per kind. {0} is the field, {1} the param.
+var englishMessages = map[string]string{
+	"default":     "{0} failed on the {1} rule",
+	"required":    "{0} is a required field",
+	"email":       "{0} must be a valid email address",
+	"contains":    "{0} must contain the text '{1}'",
+	"oneof":       "{0} must be one of [{1}]",
+	"min-string":  "{0} must be at least {1} characters long",
+	"min-number":  "{0} must be {1} or greater",
+	"min-items":   "{0} must contain at least {1} items",
+	"max-string":  "{0} must be at most {1} characters long",
+	"max-number":  "{0} must be {1} or less",
+	"max-items":   "{0} must contain at most {1} items",
+	"gt-number":   "{0} must be greater than {1}",
+	"gte-number":  "{0} must be {1} or greater",
+	"lt-number":   "{0} must be less than {1}",
+	"lte-number":  "{0} must be {1} or less",
+	"len-string":  "{0} must be {1} characters long",
+	"len-items":   "{0} must contain {1} items",
+	"len-number":  "{0} must be equal to {1}",
+	"uuid":        "{0} must be a valid UUID",
+	"url":         "{0} must be a valid URL",
+	"alphanum":    "{0} can only contain alphanumeric characters",
+	"excludesall": "{0} cannot contain any of the characters '{1}'",
+}
+
+// Messages are rendered through the translator of the locale the client asks
+// for, English is the fallback
+var (
+	english      = en.New()
+	translations = ut.New(english, english)
+)
+
+func init() {
+	ErrorPanic(RegisterMessages(english, englishMessages))
+}
+
+// RegisterMessages adds the validation messages of a locale, keyed like the
+// English ones. Keys it leaves out are rendered in English.
+func RegisterMessages(locale locales.Translator, messages map[string]string) error {
+	if err := translations.AddTranslator(locale, true); err != nil {
+		return err
+	}
+	trans, _ := translations.GetTranslator(locale.Locale())
+	for key, text := range messages {
+		if err := trans.Add(key, text, true); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Translator picks the translator for an Accept-Language header, the most
+// preferred locale with messages wins, e.g. "de-CH, en;q=0.8" is "de_CH" or
+// "de" before "en"
+func Translator(acceptLanguage string) ut.Translator {
+	trans, _ := translations.FindTranslator(acceptedLocales(acceptLanguage)...)
+	return trans
+}
+
+func acceptedLocales(acceptLanguage string) []string {
+	type accepted struct {
+		locale string
+		q      float64
+	}
+	var languages []accepted
+	for _, part := range strings.Split(acceptLanguage, ",") {
+		locale, params, _ := strings.Cut(strings.TrimSpace(part), ";")
+		q := 1.0
+		if value, ok := strings.CutPrefix(strings.TrimSpace(params), "q="); ok {
+			if parsed, err := strconv.ParseFloat(value, 64); err == nil {
+				q = parsed
+			}
+		}
+		if locale != "" && locale != "*" && q > 0 {
+			languages = append(languages, accepted{strings.ReplaceAll(locale, "-", "_"), q})
+		}
+	}
+	sort.SliceStable(languages, func(i, j int) bool { return languages[i].q > languages[j].q })
+
+	// A region falls back to its language, "de_CH" to "de"
+	var locales []string
+	for _, language := range languages {
+		locales = append(locales, language.locale)
+		if base, _, ok := strings.Cut(language.locale, "_"); ok {
+			locales = append(locales, base)
+		}
+	}
+	return locales
+}
+
+// NewValidator returns a validator that reports fields by their JSON name
+func NewValidator() *validator.Validate {
+	validate := validator.New()
+	validate.RegisterTagNameFunc(jsonFieldName)
+	return validate
+}
+
+func jsonFieldName(field reflect.StructField) string {
+	name := strings.SplitN(field.Tag.Get("json"), ",", 2)[0]
+	if name == "-" {
+		return ""
+	}
+	return name
+}
+
+// FieldErrors describes each failed field of a validation error in English
+func FieldErrors(validationErrors validator.ValidationErrors) []FieldError {
+	return TranslateFieldErrors(validationErrors, translations.GetFallback())
+}
+
+// TranslateFieldErrors describes each failed field of a validation error with
+// the messages of the translator
+func TranslateFieldErrors(validationErrors validator.ValidationErrors, trans ut.Translator) []FieldError {
+	fieldErrors := make([]FieldError, 0, len(validationErrors))
+	for _, fe := range validationErrors {
+		// Drop the request struct name, nested fields keep their path
+		field := fe.Namespace()
+		if i := strings.Index(field, "."); i >= 0 {
+			field = field[i+1:]
+		}
+
+		fieldErrors = append(fieldErrors, FieldError{
+			Field:   field,
+			Rule:    fe.Tag(),
+			Param:   fe.Param(),
+			Message: fieldMessage(trans, field, fe),
+		})
+	}
+	return fieldErrors
+}
+
+func fieldMessage(trans ut.Translator, field string, fe validator.FieldError) string {
+	for _, t := range []ut.Translator{trans, translations.GetFallback()} {
+		if message, err := t.T(fe.Tag()+"-"+kindOf(fe.Kind()), field, fe.Param()); err == nil {
+			return message
+		}
+		if message, err := t.T(fe.Tag(), field, fe.Param()); err == nil {
+			return message
+		}
+	}
+	message, _ := translations.GetFallback().T("default", field, fe.Tag())
+	return message
+}
+
+func kindOf(kind reflect.Kind) string {
+	switch kind {
+	case reflect.String:
+		return "string"
+	case reflect.Slice, reflect.Map, reflect.Array:
+		return "items"
+	default:
+		return "number"
+	}
+}
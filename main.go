@@ -78,27 +78,54 @@
 package main
 
 import (
+	"log"
+	"os"
+	"time"
+
 	"example.com/go-project/auth"
-	"example.com/go-project/authrequired"
 	"example.com/go-project/config"
 	"example.com/go-project/controller"
 	"example.com/go-project/helper"
+	"example.com/go-project/middleware"
+	"example.com/go-project/migrations"
 	"example.com/go-project/model"
 	"example.com/go-project/model/repository"
 	"example.com/go-project/services"
 	"github.com/gin-gonic/gin"
-	"github.com/go-playground/validator"
 )
 
 func main() {
-	print("Server Started.\n\n\n")
+	// "go-project migrate ..." manages the schema instead of serving requests
+	args := os.Args[1:]
+	migrateCommand := len(args) > 0 && args[0] == "migrate"
+	if migrateCommand {
+		args = args[1:]
+	}
+
+	// Load the configuration, refusing to start when it is incomplete
+	cfg, args, err := config.Load(args)
+	if err != nil {
+		log.Fatal(err)
+	}
 
 	// Setup the database and validation
-	db := config.DatabaseConnection()
-	validate := validator.New()
+	db := config.DatabaseConnection(cfg.Database)
+	validate := helper.NewValidator()
 
-	// AutoMigrate tables
-	db.AutoMigrate(&model.Tags{}, &model.Neche{}, &model.Users{})
+	if migrateCommand {
+		if err := runMigrate(db, args); err != nil {
+			log.Fatal(err)
+		}
+		return
+	}
+
+	print("Server Started.\n\n\n")
+
+	// Apply pending schema migrations
+	if cfg.Database.MigrateOnStart {
+		_, err = migrations.New(db).Up()
+		helper.ErrorPanic(err)
+	}
 
 	// Tags setup
 	tagsRepository := repository.NewTagsRepositoryImpl(db)
@@ -110,43 +137,138 @@ func main() {
 	nechesService := services.NewNecheServiceImpl(nechesRepository, validate, tagsRepository)
 	nechesController := controller.NewNecheController(nechesService)
 
+	// Deleted tags and neches stay in the trash until the retention is over
+	trashService := services.NewTrashServiceImpl(tagsRepository, nechesRepository, cfg.Trash.Retention)
+	trashController := controller.NewTrashController(trashService)
+	stopPurging := services.StartTrashPurging(trashService, cfg.Trash.PurgeInterval)
+	defer stopPurging()
+
+	// Every change of a tag or a neche is recorded as a revision
+	revisionService := services.NewRevisionServiceImpl(repository.NewRevisionRepositoryImpl(db), tagsRepository, nechesRepository)
+	revisionController := controller.NewRevisionController(revisionService)
+
 	// User setup
 	userRepo := repository.NewUsersRepository(db)
-	userService := services.NewUsersService(userRepo)
-	userController := controller.NewUsersController(userService)
+	userService := services.NewUsersService(userRepo, validate)
+	refreshTokenRepo := repository.NewRefreshTokenRepository(db)
+	tokenService := services.NewTokenService(refreshTokenRepo, userRepo, cfg.Auth.RefreshTokenTTL)
 
-	// Create the base router
-	router := gin.Default()
-	router.SetTrustedProxies(nil)
+	// Failed logins slow down further attempts and lock the account or the address
+	loginThrottle := services.NewLoginThrottle(repository.NewLoginAttemptRepository(db), cfg.Login)
+	stopLoginPruning := services.StartLoginAttemptPruning(loginThrottle, cfg.Login.PruneInterval)
+	defer stopLoginPruning()
+	userController := controller.NewUsersController(userService, tokenService, loginThrottle)
+	apiKeyRepo := repository.NewApiKeyRepository(db)
+	apiKeyService := services.NewApiKeyService(apiKeyRepo, userRepo, validate)
+	apiKeysController := controller.NewApiKeysController(apiKeyService)
 
-	// Initialize Google OAuth
+	// Roles and permissions
+	permissionRepo := repository.NewPermissionRepository(db)
+	permissionService := services.NewPermissionService(permissionRepo, validate)
+	rolesController := controller.NewRolesController(permissionService)
+	auth.UsePermissionStore(permissionRepo)
+
+	// Logins, registrations, role changes and admin deletes go to the audit log
+	auditService := services.NewAuditService(repository.NewAuditRepository(db), validate)
+	auditController := controller.NewAuditController(auditService)
+	auth.UseAuditLog(auditService)
+
+	// Revoked access tokens are kept until they expire
+	if cfg.Auth.RevocationStore == "database" {
+		auth.UseRevocationStore(repository.NewRevocationRepository(db))
+	}
+	stopPruning := auth.StartRevocationPruning(time.Hour)
+	defer stopPruning()
+
+	// Listings are paginated, clients can't ask for more than the max page size
+	controller.DefaultPageSize = cfg.API.DefaultPageSize
+	controller.MaxPageSize = cfg.API.MaxPageSize
+	if cfg.API.CursorSecret != "" {
+		helper.UseCursorKey([]byte(cfg.API.CursorSecret))
+	}
+
+	// Updates and deletes must name the version they change, lost updates get a 412
+	controller.RequireIfMatch = cfg.API.RequireIfMatch
+
+	// Create the base router, errors added with ctx.Error are rendered as the error envelope
+	router := gin.New()
+	router.Use(gin.Logger(), middleware.Recovery(), middleware.ErrorHandler())
+	router.SetTrustedProxies(nil)
 
 	// Public routes (no authentication required)
 	publicRouter := router.Group("/user")
 	publicRouter.POST("/register", userController.RegisterUser)
 	publicRouter.POST("/login", userController.Login)
+	publicRouter.POST("/token/refresh", userController.RefreshToken)
 
-	// Admin routes (requires Admin role)
+	// Admin routes, each one requires its own permission
 	adminRouter := router.Group("/admin")
-	adminRouter.Use(authrequired.RoleBasedAuth("Admin"))
 	{
-		adminRouter.DELETE("/neches/:necheId", nechesController.Delete)
-		adminRouter.DELETE("/tags/:tagId", tagsController.Delete)
+		adminRouter.DELETE("/neches/:necheId", auth.Audited(model.AuditNecheDelete, "neche", "necheId"), auth.RequirePermission("neches:delete"), nechesController.Delete)
+		adminRouter.DELETE("/tags/:tagId", auth.Audited(model.AuditTagDelete, "tag", "tagId"), auth.RequirePermission("tags:delete"), tagsController.Delete)
+		adminRouter.GET("/trash/tags", auth.RequirePermission("tags:delete"), trashController.FindTags)
+		adminRouter.POST("/trash/tags/:tagId/restore", auth.Audited(model.AuditTagRestore, "tag", "tagId"), auth.RequirePermission("tags:delete"), trashController.RestoreTag)
+		adminRouter.GET("/trash/neches", auth.RequirePermission("neches:delete"), trashController.FindNeches)
+		adminRouter.POST("/trash/neches/:necheId/restore", auth.Audited(model.AuditNecheRestore, "neche", "necheId"), auth.RequirePermission("neches:delete"), trashController.RestoreNeche)
+		adminRouter.POST("/tags/:tagId/revisions/:revisionId/revert", auth.Audited(model.AuditTagRevert, "tag", "tagId"), auth.RequirePermission("revisions:revert"), revisionController.RevertTag)
+		adminRouter.POST("/neches/:necheId/revisions/:revisionId/revert", auth.Audited(model.AuditNecheRevert, "neche", "necheId"), auth.RequirePermission("revisions:revert"), revisionController.RevertNeche)
+		adminRouter.POST("/users/:userId/sessions/revoke", auth.Audited(model.AuditSessionsRevoke, "user", "userId"), auth.RequirePermission("users:manage"), userController.RevokeSessions)
+		adminRouter.POST("/users/:userId/unlock", auth.Audited(model.AuditUserUnlock, "user", "userId"), auth.RequirePermission("users:manage"), userController.Unlock)
+		adminRouter.POST("/addresses/:ip/unlock", auth.Audited(model.AuditAddressUnlock, "address", "ip"), auth.RequirePermission("users:manage"), userController.UnlockAddress)
+		adminRouter.PUT("/users/:userId/role", auth.Audited(model.AuditRoleChange, "user", "userId"), auth.RequirePermission("users:manage"), userController.UpdateRole)
+		adminRouter.GET("/audit", auth.RequirePermission("audit:read"), auditController.FindAll)
+		adminRouter.GET("/audit/export", auth.RequirePermission("audit:read"), auditController.Export)
+		adminRouter.POST("/keys/rotate", auth.RequirePermission("keys:rotate"), auth.RotateKeysHandler)
+	}
+
+	// Role and permission management
+	rolesRouter := router.Group("/admin")
+	rolesRouter.Use(auth.RequirePermission("roles:manage"))
+	{
+		rolesRouter.GET("/roles", rolesController.FindAllRoles)
+		rolesRouter.POST("/roles", auth.Audited(model.AuditRoleCreate, "role", ""), rolesController.CreateRole)
+		rolesRouter.GET("/roles/:role", rolesController.FindRole)
+		rolesRouter.DELETE("/roles/:role", auth.Audited(model.AuditRoleDelete, "role", "role"), rolesController.DeleteRole)
+		rolesRouter.PUT("/roles/:role/permissions/:permission", auth.Audited(model.AuditPermissionGrant, "role", "role"), rolesController.Grant)
+		rolesRouter.DELETE("/roles/:role/permissions/:permission", auth.Audited(model.AuditPermissionRevoke, "role", "role"), rolesController.Revoke)
+		rolesRouter.GET("/permissions", rolesController.FindAllPermissions)
+		rolesRouter.POST("/permissions", auth.Audited(model.AuditPermissionCreate, "permission", ""), rolesController.CreatePermission)
+		rolesRouter.DELETE("/permissions/:permission", auth.Audited(model.AuditPermissionDelete, "permission", "permission"), rolesController.DeletePermission)
 	}
 
-	// User routes (requires User or Admin role)
+	// User routes, each one requires its own permission
 	userRouter := router.Group("/user")
-	userRouter.Use(authrequired.RoleBasedAuth("User"))
 	{
-		userRouter.GET("/tags", tagsController.FindAll)
-		userRouter.GET("/neches", nechesController.FindAll)
-		userRouter.PATCH("/tags/:tagId", tagsController.Update)
-		userRouter.POST("/tags", tagsController.Create)
-		userRouter.GET("/tags/:tagId", tagsController.FindById)
+		userRouter.GET("/tags", auth.RequirePermission("tags:read"), tagsController.FindAll)
+		userRouter.GET("/neches", auth.RequirePermission("neches:read"), nechesController.FindAll)
+		userRouter.GET("/neches/:necheId", auth.RequirePermission("neches:read"), nechesController.FindById)
+		userRouter.GET("/neches/:necheId/history", auth.RequirePermission("neches:read"), revisionController.NecheHistory)
+		userRouter.POST("/neches", auth.RequirePermission("neches:create"), nechesController.Create)
+		userRouter.PUT("/neches/:necheId", auth.RequirePermission("neches:update"), nechesController.Update)
+		userRouter.PATCH("/neches/:necheId", auth.RequirePermission("neches:update"), nechesController.Patch)
+		userRouter.GET("/tags/:tagId/neches", auth.RequirePermission("tags:read", "neches:read"), nechesController.FindByTagId)
+		userRouter.POST("/tags/:tagId/neches", auth.RequirePermission("tags:update", "neches:update"), nechesController.AttachMany)
+		userRouter.POST("/tags/:tagId/neches/:necheId", auth.RequirePermission("tags:update", "neches:update"), nechesController.Attach)
+		userRouter.DELETE("/tags/:tagId/neches/:necheId", auth.RequirePermission("tags:update", "neches:update"), nechesController.Detach)
+		userRouter.PUT("/tags/:tagId", auth.RequirePermission("tags:update"), tagsController.Update)
+		userRouter.PATCH("/tags/:tagId", auth.RequirePermission("tags:update"), tagsController.Patch)
+		userRouter.POST("/tags/:tagId/move", auth.RequirePermission("tags:update"), tagsController.Move)
+		userRouter.GET("/tags/:tagId/subtree", auth.RequirePermission("tags:read"), tagsController.Subtree)
+		userRouter.GET("/tags/:tagId/ancestors", auth.RequirePermission("tags:read"), tagsController.Ancestors)
+		userRouter.GET("/tags/:tagId/history", auth.RequirePermission("tags:read"), revisionController.TagHistory)
+		userRouter.POST("/tags", auth.RequirePermission("tags:create"), tagsController.Create)
+		userRouter.GET("/tags/:tagId", auth.RequirePermission("tags:read"), tagsController.FindById)
+		userRouter.POST("/logout", auth.RequireAuth(), userController.Logout)
+		userRouter.GET("/api-keys", auth.RequireAuth(), apiKeysController.FindAll)
+		userRouter.POST("/api-keys", auth.RequireAuth(), apiKeysController.Create)
+		userRouter.DELETE("/api-keys/:keyId", auth.RequireAuth(), apiKeysController.Revoke)
 	}
 
-	auth.NewAuth(router, userService)
+	// Initialize signing keys, sessions and Google OAuth
+	err = auth.NewAuth(router, cfg.Auth, userService, tokenService, apiKeyService)
+	helper.ErrorPanic(err)
+
 	// Start the server
-	err := router.Run(":8888")
+	err = router.Run(cfg.Server.Addr())
 	helper.ErrorPanic(err)
 }
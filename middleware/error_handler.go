@@ -0,0 +1,61 @@
+package middleware
+
+import (
+	"errors"
+	"log"
+	"net/http"
+
+	"example.com/go-project/data/response"
+	"example.com/go-project/helper"
+	"github.com/gin-gonic/gin"
+	"github.com/go-playground/validator"
+)
+
+// ErrorHandler writes the last error a handler added with ctx.Error as the
+// response envelope, with the status code and error code of its kind
+func ErrorHandler() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		c.Next()
+
+		if len(c.Errors) == 0 || c.Writer.Written() {
+			return
+		}
+		AbortWithError(c, c.Errors.Last().Err)
+	}
+}
+
+// Recovery turns a panic into an internal error instead of an empty 500
+func Recovery() gin.HandlerFunc {
+	return gin.CustomRecovery(func(c *gin.Context, recovered interface{}) {
+		log.Println("Recovered from panic:", recovered)
+		c.AbortWithStatusJSON(http.StatusInternalServerError, response.Response{
+			Code:      http.StatusInternalServerError,
+			Status:    "error",
+			Data:      "internal server error",
+			ErrorCode: "internal_error",
+		})
+	})
+}
+
+// AbortWithError stops the request and responds with the error envelope
+func AbortWithError(c *gin.Context, err error) {
+	appErr := helper.AsAppError(err)
+	if appErr.Kind == helper.KindInternal {
+		log.Println("Internal error:", err)
+	}
+
+	// Validation messages are written in the language the client prefers
+	details := appErr.Details
+	var validationErrors validator.ValidationErrors
+	if errors.As(err, &validationErrors) {
+		details = helper.TranslateFieldErrors(validationErrors, helper.Translator(c.GetHeader("Accept-Language")))
+	}
+
+	c.AbortWithStatusJSON(appErr.Status(), response.Response{
+		Code:      appErr.Status(),
+		Status:    "error",
+		Data:      appErr.Message,
+		ErrorCode: appErr.Code(),
+		Errors:    details,
+	})
+}
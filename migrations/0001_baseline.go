@@ -0,0 +1,104 @@
+package migrations
+
+import (
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// The baseline captures the schema as it was created by AutoMigrate. The
+// structs are snapshots, later changes to the models need their own migration.
+
+type baselineTag struct {
+	Id     int             `gorm:"primary_key;autoIncrement"`
+	Name   string          `gorm:"type:varchar(255);not null"`
+	Neches []baselineNeche `gorm:"foreignKey:TagID;constraint:OnDelete:CASCADE;"`
+}
+
+func (baselineTag) TableName() string { return "tags" }
+
+type baselineNeche struct {
+	Id        int         `gorm:"primary_key;autoIncrement"`
+	NecheType string      `gorm:"type:varchar(255);not null"`
+	TagID     int         `gorm:"not null"`
+	Tag       baselineTag `gorm:"foreignKey:TagID"`
+}
+
+func (baselineNeche) TableName() string { return "neches" }
+
+type baselineUser struct {
+	Id       int    `gorm:"primary_key;autoIncrement"`
+	Name     string `gorm:"type:varchar(255);not null"`
+	Email    string `gorm:"type:varchar(255);not null"`
+	Password string `gorm:"type:varchar(255);not null"`
+	Role     string `gorm:"type:varchar(255);not null"`
+}
+
+func (baselineUser) TableName() string { return "users" }
+
+type baselineRefreshToken struct {
+	Id         int        `gorm:"primary_key;autoIncrement"`
+	UserId     int        `gorm:"not null;index"`
+	FamilyId   string     `gorm:"type:varchar(64);not null;index"`
+	TokenHash  string     `gorm:"type:varchar(64);not null;uniqueIndex"`
+	ExpiresAt  time.Time  `gorm:"not null"`
+	CreatedAt  time.Time  `gorm:"not null"`
+	RevokedAt  *time.Time `gorm:"index"`
+	ReplacedBy *int
+	User       baselineUser `gorm:"foreignKey:UserId;constraint:OnDelete:CASCADE;"`
+}
+
+func (baselineRefreshToken) TableName() string { return "refresh_tokens" }
+
+type baselineRevokedToken struct {
+	Jti       string    `gorm:"type:varchar(64);primary_key"`
+	UserId    int       `gorm:"not null;index"`
+	ExpiresAt time.Time `gorm:"not null;index"`
+}
+
+func (baselineRevokedToken) TableName() string { return "revoked_tokens" }
+
+type baselineUserRevocation struct {
+	UserId    int       `gorm:"primary_key;autoIncrement:false"`
+	RevokedAt time.Time `gorm:"not null"`
+	ExpiresAt time.Time `gorm:"not null;index"`
+}
+
+func (baselineUserRevocation) TableName() string { return "user_revocations" }
+
+// baselineTables are in creation order, parents before children
+var baselineTables = []interface{}{
+	&baselineTag{},
+	&baselineNeche{},
+	&baselineUser{},
+	&baselineRefreshToken{},
+	&baselineRevokedToken{},
+	&baselineUserRevocation{},
+}
+
+func init() {
+	register(Migration{
+		Version: 1,
+		Name:    "baseline",
+		Up: func(tx *gorm.DB) error {
+			for _, table := range baselineTables {
+				// Databases created by AutoMigrate already have the tables
+				if tx.Migrator().HasTable(table) {
+					continue
+				}
+				if err := tx.Migrator().CreateTable(table); err != nil {
+					return err
+				}
+			}
+			return nil
+		},
+		Down: func(tx *gorm.DB) error {
+			for i := len(baselineTables) - 1; i >= 0; i-- {
+				if err := tx.Migrator().DropTable(baselineTables[i]); err != nil {
+					return err
+				}
+			}
+			return nil
+		},
+	})
+}
@@ -0,0 +1,37 @@
+package migrations
+
+import (
+	"fmt"
+	"strings"
+
+	"gorm.io/gorm"
+)
+
+func init() {
+	register(Migration{
+		Version: 2,
+		Name:    "unique_user_email",
+		Up: func(tx *gorm.DB) error {
+			// Refuse to guess which account wins when two only differ by case
+			var duplicates []string
+			err := tx.Raw("SELECT LOWER(TRIM(email)) FROM users GROUP BY LOWER(TRIM(email)) HAVING COUNT(*) > 1").Scan(&duplicates).Error
+			if err != nil {
+				return err
+			}
+			if len(duplicates) > 0 {
+				return fmt.Errorf("users share an email address, merge them first: %s", strings.Join(duplicates, ", "))
+			}
+
+			if err := tx.Exec("UPDATE users SET email = LOWER(TRIM(email))").Error; err != nil {
+				return err
+			}
+			if tx.Migrator().HasIndex("users", "idx_users_email") {
+				return nil
+			}
+			return tx.Exec("CREATE UNIQUE INDEX idx_users_email ON users (email)").Error
+		},
+		Down: func(tx *gorm.DB) error {
+			return tx.Exec("DROP INDEX idx_users_email").Error
+		},
+	})
+}
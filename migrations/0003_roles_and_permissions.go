@@ -0,0 +1,106 @@
+package migrations
+
+import (
+	"gorm.io/gorm"
+)
+
+type permissionsRole struct {
+	Id          int    `gorm:"primary_key;autoIncrement"`
+	Name        string `gorm:"type:varchar(100);not null;uniqueIndex"`
+	Description string `gorm:"type:varchar(255);not null;default:''"`
+}
+
+func (permissionsRole) TableName() string { return "roles" }
+
+type permissionsPermission struct {
+	Id          int    `gorm:"primary_key;autoIncrement"`
+	Name        string `gorm:"type:varchar(100);not null;uniqueIndex"`
+	Description string `gorm:"type:varchar(255);not null;default:''"`
+}
+
+func (permissionsPermission) TableName() string { return "permissions" }
+
+type permissionsRolePermission struct {
+	RoleId       int                   `gorm:"primary_key;autoIncrement:false"`
+	PermissionId int                   `gorm:"primary_key;autoIncrement:false"`
+	Role         permissionsRole       `gorm:"foreignKey:RoleId;constraint:OnDelete:CASCADE;"`
+	Permission   permissionsPermission `gorm:"foreignKey:PermissionId;constraint:OnDelete:CASCADE;"`
+}
+
+func (permissionsRolePermission) TableName() string { return "role_permissions" }
+
+// defaultPermissions reproduce what the hard-wired route groups allowed
+var defaultPermissions = []permissionsPermission{
+	{Name: "tags:read", Description: "List and view tags"},
+	{Name: "tags:create", Description: "Create tags"},
+	{Name: "tags:update", Description: "Rename tags"},
+	{Name: "tags:delete", Description: "Delete tags"},
+	{Name: "neches:read", Description: "List and view neches"},
+	{Name: "neches:create", Description: "Create neches"},
+	{Name: "neches:update", Description: "Edit neches"},
+	{Name: "neches:delete", Description: "Delete neches"},
+	{Name: "users:manage", Description: "Change user roles and revoke their sessions"},
+	{Name: "roles:manage", Description: "Manage roles and their permissions"},
+	{Name: "keys:rotate", Description: "Rotate the token signing key"},
+}
+
+var defaultRoles = []struct {
+	role        permissionsRole
+	permissions []string
+}{
+	{permissionsRole{Name: "Admin", Description: "Full access"}, []string{
+		"tags:read", "tags:create", "tags:update", "tags:delete",
+		"neches:read", "neches:create", "neches:update", "neches:delete",
+		"users:manage", "roles:manage", "keys:rotate",
+	}},
+	{permissionsRole{Name: "Editor", Description: "Edits tags and neches"}, []string{
+		"tags:read", "tags:create", "tags:update",
+		"neches:read", "neches:create", "neches:update",
+	}},
+	{permissionsRole{Name: "User", Description: "Default role of new accounts"}, []string{
+		"tags:read", "tags:create", "tags:update", "neches:read",
+	}},
+	{permissionsRole{Name: "Viewer", Description: "Read only access"}, []string{
+		"tags:read", "neches:read",
+	}},
+}
+
+func init() {
+	register(Migration{
+		Version: 3,
+		Name:    "roles_and_permissions",
+		Up: func(tx *gorm.DB) error {
+			err := tx.Migrator().CreateTable(&permissionsRole{}, &permissionsPermission{}, &permissionsRolePermission{})
+			if err != nil {
+				return err
+			}
+
+			permissionIds := make(map[string]int)
+			for _, permission := range defaultPermissions {
+				permission := permission
+				if err := tx.Create(&permission).Error; err != nil {
+					return err
+				}
+				permissionIds[permission.Name] = permission.Id
+			}
+
+			for _, seed := range defaultRoles {
+				role := seed.role
+				if err := tx.Create(&role).Error; err != nil {
+					return err
+				}
+				for _, name := range seed.permissions {
+					grant := permissionsRolePermission{RoleId: role.Id, PermissionId: permissionIds[name]}
+					if err := tx.Omit("Role", "Permission").Create(&grant).Error; err != nil {
+						return err
+					}
+				}
+			}
+			return nil
+		},
+		Down: func(tx *gorm.DB) error {
+			// DropTable drops in reverse order, children first
+			return tx.Migrator().DropTable(&permissionsRole{}, &permissionsPermission{}, &permissionsRolePermission{})
+		},
+	})
+}
@@ -0,0 +1,36 @@
+package migrations
+
+import (
+	"time"
+
+	"gorm.io/gorm"
+)
+
+type apiKeysApiKey struct {
+	Id         int    `gorm:"primary_key;autoIncrement"`
+	UserId     int    `gorm:"not null;index"`
+	Name       string `gorm:"type:varchar(100);not null"`
+	Prefix     string `gorm:"type:varchar(16);not null"`
+	KeyHash    string `gorm:"type:varchar(64);not null;uniqueIndex"`
+	Scopes     string `gorm:"type:varchar(1000);not null;default:''"`
+	ExpiresAt  *time.Time
+	LastUsedAt *time.Time
+	CreatedAt  time.Time `gorm:"not null"`
+	RevokedAt  *time.Time
+	User       baselineUser `gorm:"foreignKey:UserId;constraint:OnDelete:CASCADE;"`
+}
+
+func (apiKeysApiKey) TableName() string { return "api_keys" }
+
+func init() {
+	register(Migration{
+		Version: 4,
+		Name:    "api_keys",
+		Up: func(tx *gorm.DB) error {
+			return tx.Migrator().CreateTable(&apiKeysApiKey{})
+		},
+		Down: func(tx *gorm.DB) error {
+			return tx.Migrator().DropTable(&apiKeysApiKey{})
+		},
+	})
+}
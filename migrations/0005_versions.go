@@ -0,0 +1,47 @@
+package migrations
+
+import "gorm.io/gorm"
+
+// Tags and neches count their changes, updates must name the version they change
+type versionsTag struct {
+	Version int `gorm:"not null;default:1"`
+}
+
+func (versionsTag) TableName() string { return "tags" }
+
+type versionsNeche struct {
+	Version int `gorm:"not null;default:1"`
+}
+
+func (versionsNeche) TableName() string { return "neches" }
+
+var versionsTables = []interface{}{&versionsTag{}, &versionsNeche{}}
+
+func init() {
+	register(Migration{
+		Version: 5,
+		Name:    "versions",
+		Up: func(tx *gorm.DB) error {
+			for _, table := range versionsTables {
+				// Databases created by AutoMigrate already have the column
+				if tx.Migrator().HasColumn(table, "Version") {
+					continue
+				}
+				if err := tx.Migrator().AddColumn(table, "Version"); err != nil {
+					return err
+				}
+			}
+			return nil
+		},
+		Down: func(tx *gorm.DB) error {
+			// The SQLite migrator drops columns by copying the table, and dropping
+			// tags would cascade to its neches. Both databases can drop it in place.
+			for _, table := range []string{"neches", "tags"} {
+				if err := tx.Exec("ALTER TABLE " + table + " DROP COLUMN version").Error; err != nil {
+					return err
+				}
+			}
+			return nil
+		},
+	})
+}
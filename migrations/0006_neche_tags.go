@@ -0,0 +1,121 @@
+package migrations
+
+import "gorm.io/gorm"
+
+// Neches belong to any number of tags through neche_tags instead of the
+// tag_id column. Every neche keeps the tag it had.
+
+type necheTagsNeche struct {
+	Id        int    `gorm:"primary_key;autoIncrement"`
+	NecheType string `gorm:"type:varchar(255);not null"`
+	Version   int    `gorm:"not null;default:1"`
+}
+
+func (necheTagsNeche) TableName() string { return "neches" }
+
+// necheTagsRebuiltNeche is neches without tag_id, for SQLite to copy the rows into
+type necheTagsRebuiltNeche necheTagsNeche
+
+func (necheTagsRebuiltNeche) TableName() string { return "neches_rebuilt" }
+
+type necheTagsNecheTag struct {
+	NecheId int            `gorm:"primary_key;autoIncrement:false"`
+	TagId   int            `gorm:"primary_key;autoIncrement:false;index"`
+	Neche   necheTagsNeche `gorm:"foreignKey:NecheId;constraint:OnDelete:CASCADE;"`
+	Tag     baselineTag    `gorm:"foreignKey:TagId;constraint:OnDelete:CASCADE;"`
+}
+
+func (necheTagsNecheTag) TableName() string { return "neche_tags" }
+
+// necheTagsLegacyTag and necheTagsLegacyNeche bring tag_id back when rolling back
+type necheTagsLegacyTag struct {
+	Id     int                    `gorm:"primary_key;autoIncrement"`
+	Neches []necheTagsLegacyNeche `gorm:"foreignKey:TagID;constraint:OnDelete:CASCADE;"`
+}
+
+func (necheTagsLegacyTag) TableName() string { return "tags" }
+
+type necheTagsLegacyNeche struct {
+	Id    int `gorm:"primary_key;autoIncrement"`
+	TagID int
+}
+
+func (necheTagsLegacyNeche) TableName() string { return "neches" }
+
+func init() {
+	register(Migration{
+		Version: 6,
+		Name:    "neche_tags",
+		Up: func(tx *gorm.DB) error {
+			// Databases created by AutoMigrate already have the join table
+			if tx.Migrator().HasTable(&necheTagsNecheTag{}) {
+				return nil
+			}
+
+			// Keep the tag of every neche aside while tag_id is dropped
+			err := tx.Exec("CREATE TABLE neche_tags_backfill AS SELECT id AS neche_id, tag_id FROM neches").Error
+			if err != nil {
+				return err
+			}
+			if err := dropNecheTagId(tx); err != nil {
+				return err
+			}
+
+			if err := tx.Migrator().CreateTable(&necheTagsNecheTag{}); err != nil {
+				return err
+			}
+			err = tx.Exec("INSERT INTO neche_tags (neche_id, tag_id) SELECT neche_id, tag_id FROM neche_tags_backfill").Error
+			if err != nil {
+				return err
+			}
+			return tx.Migrator().DropTable("neche_tags_backfill")
+		},
+		Down: func(tx *gorm.DB) error {
+			// A neche goes back to the first of its tags, neches without tags can't be kept
+			err := tx.Exec("CREATE TABLE neche_tags_backfill AS SELECT neche_id, MIN(tag_id) AS tag_id FROM neche_tags GROUP BY neche_id").Error
+			if err != nil {
+				return err
+			}
+			if err := tx.Migrator().DropTable(&necheTagsNecheTag{}); err != nil {
+				return err
+			}
+			err = tx.Exec("DELETE FROM neches WHERE id NOT IN (SELECT neche_id FROM neche_tags_backfill)").Error
+			if err != nil {
+				return err
+			}
+
+			if err := tx.Migrator().AddColumn(&necheTagsLegacyNeche{}, "TagID"); err != nil {
+				return err
+			}
+			err = tx.Exec("UPDATE neches SET tag_id = (SELECT tag_id FROM neche_tags_backfill WHERE neche_tags_backfill.neche_id = neches.id)").Error
+			if err != nil {
+				return err
+			}
+			if err := tx.Migrator().CreateConstraint(&necheTagsLegacyTag{}, "Neches"); err != nil {
+				return err
+			}
+			return tx.Migrator().DropTable("neche_tags_backfill")
+		},
+	})
+}
+
+// dropNecheTagId drops neches.tag_id and its foreign key
+func dropNecheTagId(tx *gorm.DB) error {
+	if tx.Dialector.Name() != "sqlite" {
+		return tx.Migrator().DropColumn(&necheTagsLegacyNeche{}, "TagID")
+	}
+
+	// SQLite can't drop a column a foreign key uses, the rows are copied to a
+	// table without it. Nothing references neches yet, dropping it doesn't cascade.
+	if err := tx.Migrator().CreateTable(&necheTagsRebuiltNeche{}); err != nil {
+		return err
+	}
+	err := tx.Exec("INSERT INTO neches_rebuilt (id, neche_type, version) SELECT id, neche_type, version FROM neches").Error
+	if err != nil {
+		return err
+	}
+	if err := tx.Migrator().DropTable(&necheTagsNeche{}); err != nil {
+		return err
+	}
+	return tx.Migrator().RenameTable(&necheTagsRebuiltNeche{}, &necheTagsNeche{})
+}
@@ -0,0 +1,75 @@
+package migrations
+
+import "gorm.io/gorm"
+
+// Tags form trees, a tag without a parent is a root
+
+type tagParentsTag struct {
+	ParentId *int
+}
+
+func (tagParentsTag) TableName() string { return "tags" }
+
+// tagParentsRebuiltTag is tags without parent_id, SQLite copies the rows to it on rollback
+type tagParentsRebuiltTag struct {
+	Id      int    `gorm:"primary_key;autoIncrement"`
+	Name    string `gorm:"type:varchar(255);not null"`
+	Version int    `gorm:"not null;default:1"`
+}
+
+func (tagParentsRebuiltTag) TableName() string { return "tags_rebuilt" }
+
+func init() {
+	register(Migration{
+		Version: 7,
+		Name:    "tag_parents",
+		Up: func(tx *gorm.DB) error {
+			// Databases created by AutoMigrate already have the column
+			if tx.Migrator().HasColumn(&tagParentsTag{}, "ParentId") {
+				return nil
+			}
+
+			// Adding the column with its foreign key in place keeps SQLite from
+			// copying tags, dropping the old table would cascade to neche_tags
+			err := tx.Exec("ALTER TABLE tags ADD COLUMN parent_id INTEGER CONSTRAINT fk_tags_children REFERENCES tags(id)").Error
+			if err != nil {
+				return err
+			}
+			return tx.Exec("CREATE INDEX idx_tags_parent_id ON tags (parent_id)").Error
+		},
+		Down: func(tx *gorm.DB) error {
+			if err := tx.Exec("DROP INDEX idx_tags_parent_id").Error; err != nil {
+				return err
+			}
+			if tx.Dialector.Name() != "sqlite" {
+				return tx.Exec("ALTER TABLE tags DROP COLUMN parent_id").Error
+			}
+
+			// SQLite can't drop a column a foreign key uses, the rows are copied
+			// to a table without it. Dropping tags empties neche_tags, its rows
+			// are put back after.
+			err := tx.Exec("CREATE TABLE neche_tags_stash AS SELECT neche_id, tag_id FROM neche_tags").Error
+			if err != nil {
+				return err
+			}
+			if err := tx.Migrator().CreateTable(&tagParentsRebuiltTag{}); err != nil {
+				return err
+			}
+			err = tx.Exec("INSERT INTO tags_rebuilt (id, name, version) SELECT id, name, version FROM tags").Error
+			if err != nil {
+				return err
+			}
+			if err := tx.Migrator().DropTable(&tagParentsTag{}); err != nil {
+				return err
+			}
+			if err := tx.Migrator().RenameTable(&tagParentsRebuiltTag{}, &tagParentsTag{}); err != nil {
+				return err
+			}
+			err = tx.Exec("INSERT INTO neche_tags (neche_id, tag_id) SELECT neche_id, tag_id FROM neche_tags_stash").Error
+			if err != nil {
+				return err
+			}
+			return tx.Migrator().DropTable("neche_tags_stash")
+		},
+	})
+}
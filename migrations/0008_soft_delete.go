@@ -0,0 +1,75 @@
+package migrations
+
+import (
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// Deleted tags and neches go to the trash and can be restored until they are purged
+
+type softDeleteTag struct {
+	DeletedAt *time.Time `gorm:"index"`
+}
+
+func (softDeleteTag) TableName() string { return "tags" }
+
+type softDeleteNeche struct {
+	DeletedAt        *time.Time `gorm:"index"`
+	DeletedWithTagId *int       `gorm:"index"`
+}
+
+func (softDeleteNeche) TableName() string { return "neches" }
+
+var softDeleteColumns = []struct {
+	table   interface{}
+	columns []string
+}{
+	{&softDeleteTag{}, []string{"DeletedAt"}},
+	{&softDeleteNeche{}, []string{"DeletedAt", "DeletedWithTagId"}},
+}
+
+func init() {
+	register(Migration{
+		Version: 8,
+		Name:    "soft_delete",
+		Up: func(tx *gorm.DB) error {
+			for _, t := range softDeleteColumns {
+				for _, column := range t.columns {
+					// Databases created by AutoMigrate already have the column
+					if tx.Migrator().HasColumn(t.table, column) {
+						continue
+					}
+					if err := tx.Migrator().AddColumn(t.table, column); err != nil {
+						return err
+					}
+					if err := tx.Migrator().CreateIndex(t.table, column); err != nil {
+						return err
+					}
+				}
+			}
+			return nil
+		},
+		Down: func(tx *gorm.DB) error {
+			// Both databases drop unindexed columns in place, SQLite would
+			// otherwise copy tags and cascade to neche_tags
+			for _, t := range softDeleteColumns {
+				for _, column := range t.columns {
+					if err := tx.Migrator().DropIndex(t.table, column); err != nil {
+						return err
+					}
+				}
+			}
+			for _, statement := range []string{
+				"ALTER TABLE neches DROP COLUMN deleted_with_tag_id",
+				"ALTER TABLE neches DROP COLUMN deleted_at",
+				"ALTER TABLE tags DROP COLUMN deleted_at",
+			} {
+				if err := tx.Exec(statement).Error; err != nil {
+					return err
+				}
+			}
+			return nil
+		},
+	})
+}
@@ -0,0 +1,61 @@
+package migrations
+
+import (
+	"errors"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// Every change to a tag or a neche is recorded as a revision, admins can revert to one
+
+type revisionsRevision struct {
+	Id         int    `gorm:"primary_key;autoIncrement"`
+	EntityType string `gorm:"type:varchar(20);not null;index:idx_revisions_entity,priority:1"`
+	EntityId   int    `gorm:"not null;index:idx_revisions_entity,priority:2"`
+	Action     string `gorm:"type:varchar(20);not null"`
+	ActorId    *int
+	Before     *string   `gorm:"type:text"`
+	After      *string   `gorm:"type:text"`
+	CreatedAt  time.Time `gorm:"not null"`
+}
+
+func (revisionsRevision) TableName() string { return "revisions" }
+
+var revertPermission = permissionsPermission{Name: "revisions:revert", Description: "Revert tags and neches to an earlier revision"}
+
+func init() {
+	register(Migration{
+		Version: 9,
+		Name:    "revisions",
+		Up: func(tx *gorm.DB) error {
+			if err := tx.Migrator().CreateTable(&revisionsRevision{}); err != nil {
+				return err
+			}
+
+			// Only admins can revert, like they are the only ones who can delete
+			permission := revertPermission
+			if err := tx.Create(&permission).Error; err != nil {
+				return err
+			}
+			var admin permissionsRole
+			err := tx.Where("name = ?", "Admin").First(&admin).Error
+			if errors.Is(err, gorm.ErrRecordNotFound) {
+				return nil
+			}
+			if err != nil {
+				return err
+			}
+			grant := permissionsRolePermission{RoleId: admin.Id, PermissionId: permission.Id}
+			return tx.Omit("Role", "Permission").Create(&grant).Error
+		},
+		Down: func(tx *gorm.DB) error {
+			// Grants of the permission cascade
+			err := tx.Where("name = ?", revertPermission.Name).Delete(&permissionsPermission{}).Error
+			if err != nil {
+				return err
+			}
+			return tx.Migrator().DropTable(&revisionsRevision{})
+		},
+	})
+}
@@ -0,0 +1,63 @@
+package migrations
+
+import (
+	"errors"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// Security events are recorded in an append-only audit log only admins can read
+
+type auditLogAuditEvent struct {
+	Id         int       `gorm:"primary_key;autoIncrement"`
+	ActorId    *int      `gorm:"index"`
+	Email      string    `gorm:"type:varchar(255)"`
+	Ip         string    `gorm:"type:varchar(45)"`
+	UserAgent  string    `gorm:"type:varchar(512)"`
+	Action     string    `gorm:"type:varchar(50);not null;index"`
+	TargetType string    `gorm:"type:varchar(20)"`
+	TargetId   string    `gorm:"type:varchar(255)"`
+	Outcome    string    `gorm:"type:varchar(10);not null"`
+	Detail     string    `gorm:"type:varchar(512)"`
+	CreatedAt  time.Time `gorm:"not null;index"`
+}
+
+func (auditLogAuditEvent) TableName() string { return "audit_events" }
+
+var auditReadPermission = permissionsPermission{Name: "audit:read", Description: "Query and export the audit log"}
+
+func init() {
+	register(Migration{
+		Version: 10,
+		Name:    "audit_log",
+		Up: func(tx *gorm.DB) error {
+			if err := tx.Migrator().CreateTable(&auditLogAuditEvent{}); err != nil {
+				return err
+			}
+
+			permission := auditReadPermission
+			if err := tx.Create(&permission).Error; err != nil {
+				return err
+			}
+			var admin permissionsRole
+			err := tx.Where("name = ?", "Admin").First(&admin).Error
+			if errors.Is(err, gorm.ErrRecordNotFound) {
+				return nil
+			}
+			if err != nil {
+				return err
+			}
+			grant := permissionsRolePermission{RoleId: admin.Id, PermissionId: permission.Id}
+			return tx.Omit("Role", "Permission").Create(&grant).Error
+		},
+		Down: func(tx *gorm.DB) error {
+			// Grants of the permission cascade
+			err := tx.Where("name = ?", auditReadPermission.Name).Delete(&permissionsPermission{}).Error
+			if err != nil {
+				return err
+			}
+			return tx.Migrator().DropTable(&auditLogAuditEvent{})
+		},
+	})
+}
@@ -0,0 +1,31 @@
+package migrations
+
+import (
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// Failed logins are counted per account and per client address to slow down password guessing
+
+type loginAttemptsLoginAttempt struct {
+	Key           string    `gorm:"column:attempt_key;type:varchar(300);primary_key"`
+	Failures      int       `gorm:"not null"`
+	LastFailureAt time.Time `gorm:"not null;index"`
+	LockedUntil   *time.Time
+}
+
+func (loginAttemptsLoginAttempt) TableName() string { return "login_attempts" }
+
+func init() {
+	register(Migration{
+		Version: 11,
+		Name:    "login_attempts",
+		Up: func(tx *gorm.DB) error {
+			return tx.Migrator().CreateTable(&loginAttemptsLoginAttempt{})
+		},
+		Down: func(tx *gorm.DB) error {
+			return tx.Migrator().DropTable(&loginAttemptsLoginAttempt{})
+		},
+	})
+}
@@ -0,0 +1,255 @@
+package migrations
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"sort"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// Migration is one versioned, reversible change to the schema
+type Migration struct {
+	Version int64
+	Name    string
+	Up      func(tx *gorm.DB) error
+	Down    func(tx *gorm.DB) error
+}
+
+// MigrationStatus tells whether a migration has been applied and when
+type MigrationStatus struct {
+	Migration
+	AppliedAt *time.Time
+}
+
+// schemaMigration records an applied migration
+type schemaMigration struct {
+	Version   int64     `gorm:"primary_key;autoIncrement:false"`
+	Name      string    `gorm:"type:varchar(255);not null"`
+	AppliedAt time.Time `gorm:"not null"`
+}
+
+func (schemaMigration) TableName() string {
+	return "schema_migrations"
+}
+
+// schemaLock is a single row table that keeps two runners from migrating at the same time
+type schemaLock struct {
+	Id       int       `gorm:"primary_key;autoIncrement:false"`
+	Owner    string    `gorm:"type:varchar(255);not null"`
+	LockedAt time.Time `gorm:"not null"`
+}
+
+func (schemaLock) TableName() string {
+	return "schema_migrations_lock"
+}
+
+var ErrLocked = errors.New("migrations are locked by another runner")
+
+var registered []Migration
+
+// register adds a migration to the list, every migration file calls it from init
+func register(migration Migration) {
+	registered = append(registered, migration)
+}
+
+// All returns the registered migrations ordered by version
+func All() []Migration {
+	migrations := append([]Migration(nil), registered...)
+	sort.Slice(migrations, func(i, j int) bool { return migrations[i].Version < migrations[j].Version })
+	return migrations
+}
+
+type Migrator struct {
+	db         *gorm.DB
+	migrations []Migration
+	owner      string
+
+	LockTimeout    time.Duration // How long to wait for another runner to finish
+	StaleLockAfter time.Duration // A lock older than this is assumed to belong to a crashed runner
+}
+
+// New creates a migrator for the registered migrations
+func New(db *gorm.DB) *Migrator {
+	return NewWithMigrations(db, All())
+}
+
+func NewWithMigrations(db *gorm.DB, migrations []Migration) *Migrator {
+	host, _ := os.Hostname()
+	return &Migrator{
+		db:             db,
+		migrations:     migrations,
+		owner:          fmt.Sprintf("%s:%d", host, os.Getpid()),
+		LockTimeout:    time.Minute,
+		StaleLockAfter: 15 * time.Minute,
+	}
+}
+
+// Up applies every pending migration in order and returns the ones it applied
+func (m *Migrator) Up() ([]Migration, error) {
+	var applied []Migration
+	err := m.withLock(func() error {
+		versions, err := m.appliedVersions()
+		if err != nil {
+			return err
+		}
+
+		for _, migration := range m.migrations {
+			if _, ok := versions[migration.Version]; ok {
+				continue
+			}
+
+			err := m.db.Transaction(func(tx *gorm.DB) error {
+				if err := migration.Up(tx); err != nil {
+					return err
+				}
+				return tx.Create(&schemaMigration{Version: migration.Version, Name: migration.Name, AppliedAt: time.Now()}).Error
+			})
+			if err != nil {
+				return fmt.Errorf("migration %d_%s: %w", migration.Version, migration.Name, err)
+			}
+			applied = append(applied, migration)
+		}
+		return nil
+	})
+	return applied, err
+}
+
+// Down rolls back the given number of most recently applied migrations
+func (m *Migrator) Down(steps int) ([]Migration, error) {
+	var reverted []Migration
+	err := m.withLock(func() error {
+		versions, err := m.appliedVersions()
+		if err != nil {
+			return err
+		}
+
+		for i := len(m.migrations) - 1; i >= 0 && len(reverted) < steps; i-- {
+			migration := m.migrations[i]
+			if _, ok := versions[migration.Version]; !ok {
+				continue
+			}
+			if migration.Down == nil {
+				return fmt.Errorf("migration %d_%s can't be rolled back", migration.Version, migration.Name)
+			}
+
+			err := m.db.Transaction(func(tx *gorm.DB) error {
+				if err := migration.Down(tx); err != nil {
+					return err
+				}
+				return tx.Delete(&schemaMigration{}, migration.Version).Error
+			})
+			if err != nil {
+				return fmt.Errorf("rolling back %d_%s: %w", migration.Version, migration.Name, err)
+			}
+			reverted = append(reverted, migration)
+		}
+		return nil
+	})
+	return reverted, err
+}
+
+// Status lists every known migration and when it was applied
+func (m *Migrator) Status() ([]MigrationStatus, error) {
+	if err := m.ensureTables(); err != nil {
+		return nil, err
+	}
+
+	versions, err := m.appliedVersions()
+	if err != nil {
+		return nil, err
+	}
+
+	var statuses []MigrationStatus
+	for _, migration := range m.migrations {
+		status := MigrationStatus{Migration: migration}
+		if appliedAt, ok := versions[migration.Version]; ok {
+			status.AppliedAt = &appliedAt
+		}
+		statuses = append(statuses, status)
+	}
+	return statuses, nil
+}
+
+func (m *Migrator) appliedVersions() (map[int64]time.Time, error) {
+	var rows []schemaMigration
+	if err := m.db.Find(&rows).Error; err != nil {
+		return nil, err
+	}
+
+	versions := make(map[int64]time.Time, len(rows))
+	for _, row := range rows {
+		versions[row.Version] = row.AppliedAt
+	}
+	return versions, nil
+}
+
+func (m *Migrator) ensureTables() error {
+	for _, table := range []interface{}{&schemaMigration{}, &schemaLock{}} {
+		if m.db.Migrator().HasTable(table) {
+			continue
+		}
+		// Another runner may have created the table in the meantime
+		if err := m.db.Migrator().CreateTable(table); err != nil && !m.db.Migrator().HasTable(table) {
+			return err
+		}
+	}
+	return nil
+}
+
+// withLock runs fn while holding the migration lock
+func (m *Migrator) withLock(fn func() error) error {
+	if err := m.ensureTables(); err != nil {
+		return err
+	}
+	if err := m.lock(); err != nil {
+		return err
+	}
+	defer m.unlock()
+
+	return fn()
+}
+
+// lockRetryInterval is how long lock waits before it tries again
+const lockRetryInterval = 500 * time.Millisecond
+
+func (m *Migrator) lock() error {
+	deadline := time.Now().Add(m.LockTimeout)
+	for {
+		err := m.db.Create(&schemaLock{Id: 1, Owner: m.owner, LockedAt: time.Now()}).Error
+		if err == nil {
+			return nil
+		}
+
+		var current schemaLock
+		if findErr := m.db.First(&current, 1).Error; findErr != nil {
+			if !errors.Is(findErr, gorm.ErrRecordNotFound) {
+				return err
+			}
+			// Released between our insert and the lookup, unless the insert
+			// failed for another reason. Retry until the deadline either way.
+			if time.Now().After(deadline) {
+				return err
+			}
+			time.Sleep(lockRetryInterval)
+			continue
+		}
+
+		// Take over locks left behind by a runner that crashed
+		if time.Since(current.LockedAt) > m.StaleLockAfter {
+			m.db.Where("id = ? AND owner = ?", 1, current.Owner).Delete(&schemaLock{})
+			continue
+		}
+
+		if time.Now().After(deadline) {
+			return fmt.Errorf("%w (%s since %s)", ErrLocked, current.Owner, current.LockedAt.Format(time.RFC3339))
+		}
+		time.Sleep(lockRetryInterval)
+	}
+}
+
+func (m *Migrator) unlock() {
+	m.db.Where("id = ? AND owner = ?", 1, m.owner).Delete(&schemaLock{})
+}
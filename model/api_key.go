@@ -0,0 +1,18 @@
+package model
+
+import "time"
+
+// ApiKey lets scripts authenticate as a user, limited to a set of scopes
+type ApiKey struct {
+	Id         int    `gorm:"primary_key;autoIncrement"`
+	UserId     int    `gorm:"not null;index"`
+	Name       string `gorm:"type:varchar(100);not null"`
+	Prefix     string `gorm:"type:varchar(16);not null"` // Shown to the user to tell keys apart
+	KeyHash    string `gorm:"type:varchar(64);not null;uniqueIndex"`
+	Scopes     string `gorm:"type:varchar(1000);not null;default:''"` // Comma separated permission names
+	ExpiresAt  *time.Time
+	LastUsedAt *time.Time
+	CreatedAt  time.Time `gorm:"not null"`
+	RevokedAt  *time.Time
+	User       Users `gorm:"foreignKey:UserId;constraint:OnDelete:CASCADE;"`
+}
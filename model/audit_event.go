@@ -0,0 +1,47 @@
+package model
+
+import "time"
+
+// Security events the audit log records
+const (
+	AuditLogin            = "auth.login"
+	AuditRegister         = "auth.register"
+	AuditGoogleCallback   = "auth.google_callback"
+	AuditRoleChange       = "user.role_change"
+	AuditSessionsRevoke   = "user.sessions_revoke"
+	AuditUserUnlock       = "user.unlock"
+	AuditAddressUnlock    = "address.unlock"
+	AuditTagDelete        = "tag.delete"
+	AuditNecheDelete      = "neche.delete"
+	AuditTagRestore       = "tag.restore"
+	AuditNecheRestore     = "neche.restore"
+	AuditTagRevert        = "tag.revert"
+	AuditNecheRevert      = "neche.revert"
+	AuditRoleCreate       = "role.create"
+	AuditRoleDelete       = "role.delete"
+	AuditPermissionCreate = "permission.create"
+	AuditPermissionDelete = "permission.delete"
+	AuditPermissionGrant  = "role.permission_grant"
+	AuditPermissionRevoke = "role.permission_revoke"
+)
+
+// Outcomes of an audited event
+const (
+	AuditSuccess = "success"
+	AuditFailure = "failure"
+)
+
+// AuditEvent is one entry of the audit log. Entries are only ever added.
+type AuditEvent struct {
+	Id         int       `gorm:"primary_key;autoIncrement"`
+	ActorId    *int      `gorm:"index"`             // nil when the caller isn't authenticated
+	Email      string    `gorm:"type:varchar(255)"` // given at login or registration, or the actor's
+	Ip         string    `gorm:"type:varchar(45)"`
+	UserAgent  string    `gorm:"type:varchar(512)"`
+	Action     string    `gorm:"type:varchar(50);not null;index"`
+	TargetType string    `gorm:"type:varchar(20)"`
+	TargetId   string    `gorm:"type:varchar(255)"`
+	Outcome    string    `gorm:"type:varchar(10);not null"`
+	Detail     string    `gorm:"type:varchar(512)"`
+	CreatedAt  time.Time `gorm:"not null;index"`
+}
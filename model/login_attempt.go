@@ -0,0 +1,13 @@
+package model
+
+import "time"
+
+// LoginAttempt counts the failed logins of an account or a client address.
+// Keys are "account:<email>" and "ip:<address>", the email doesn't have to
+// belong to an account.
+type LoginAttempt struct {
+	Key           string     `gorm:"column:attempt_key;type:varchar(300);primary_key"`
+	Failures      int        `gorm:"not null"`
+	LastFailureAt time.Time  `gorm:"not null;index"`
+	LockedUntil   *time.Time // set once the failures reach the limit
+}
@@ -1,8 +1,13 @@
 package model
 
+import "gorm.io/gorm"
+
 type Neche struct {
-	Id        int    `gorm:"primary_key;autoIncrement"`
-	NecheType string `gorm:"type:varchar(255);not null"`
-	TagID     int    `gorm:"not null"`
-	Tag       Tags   `gorm:"foreignKey:TagID"`
-}
\ No newline at end of file
+	Id        int            `gorm:"primary_key;autoIncrement"`
+	NecheType string         `gorm:"type:varchar(255);not null"`
+	Tags      []Tags         `gorm:"many2many:neche_tags;joinForeignKey:NecheId;joinReferences:TagId;constraint:OnDelete:CASCADE;"`
+	Version   int            `gorm:"not null;default:1"` // Incremented by every update
+	DeletedAt gorm.DeletedAt `gorm:"index"`              // Set while the neche is in the trash
+	// The tag whose deletion took the neche along, restoring the tag restores it
+	DeletedWithTagId *int `gorm:"index"`
+}
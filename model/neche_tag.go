@@ -0,0 +1,7 @@
+package model
+
+// NecheTag attaches a neche to a tag, a neche can belong to any number of tags
+type NecheTag struct {
+	NecheId int `gorm:"primary_key;autoIncrement:false"`
+	TagId   int `gorm:"primary_key;autoIncrement:false;index"`
+}
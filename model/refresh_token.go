@@ -0,0 +1,15 @@
+package model
+
+import "time"
+
+type RefreshToken struct {
+	Id         int        `gorm:"primary_key;autoIncrement"`
+	UserId     int        `gorm:"not null;index"`
+	FamilyId   string     `gorm:"type:varchar(64);not null;index"`
+	TokenHash  string     `gorm:"type:varchar(64);not null;uniqueIndex"`
+	ExpiresAt  time.Time  `gorm:"not null"`
+	CreatedAt  time.Time  `gorm:"not null"`
+	RevokedAt  *time.Time `gorm:"index"`
+	ReplacedBy *int
+	User       Users `gorm:"foreignKey:UserId;constraint:OnDelete:CASCADE;"`
+}
@@ -0,0 +1,60 @@
+package repository
+
+import (
+	"errors"
+	"time"
+
+	"example.com/go-project/model"
+	"gorm.io/gorm"
+)
+
+type ApiKeyRepository struct {
+	Db *gorm.DB
+}
+
+func NewApiKeyRepository(db *gorm.DB) *ApiKeyRepository {
+	return &ApiKeyRepository{Db: db}
+}
+
+func (repo *ApiKeyRepository) Save(key *model.ApiKey) error {
+	return repo.Db.Omit("User").Create(key).Error
+}
+
+// FindByHash returns nil without error when no key matches
+func (repo *ApiKeyRepository) FindByHash(keyHash string) (*model.ApiKey, error) {
+	var key model.ApiKey
+	result := repo.Db.Where("key_hash = ?", keyHash).First(&key)
+	if result.Error != nil {
+		if errors.Is(result.Error, gorm.ErrRecordNotFound) {
+			return nil, nil
+		}
+		return nil, result.Error
+	}
+	return &key, nil
+}
+
+// FindByUser lists the keys of a user that haven't been revoked
+func (repo *ApiKeyRepository) FindByUser(userId int) ([]model.ApiKey, error) {
+	var keys []model.ApiKey
+	result := repo.Db.Where("user_id = ? AND revoked_at IS NULL", userId).Order("id").Find(&keys)
+	if result.Error != nil {
+		return nil, result.Error
+	}
+	return keys, nil
+}
+
+// Revoke revokes a key of the user, it returns false when the user has no such key
+func (repo *ApiKeyRepository) Revoke(id int, userId int) (bool, error) {
+	result := repo.Db.Model(&model.ApiKey{}).
+		Where("id = ? AND user_id = ? AND revoked_at IS NULL", id, userId).
+		Update("revoked_at", time.Now())
+	if result.Error != nil {
+		return false, result.Error
+	}
+	return result.RowsAffected > 0, nil
+}
+
+// Touch records when the key was last used
+func (repo *ApiKeyRepository) Touch(id int, usedAt time.Time) error {
+	return repo.Db.Model(&model.ApiKey{}).Where("id = ?", id).Update("last_used_at", usedAt).Error
+}
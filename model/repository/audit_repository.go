@@ -0,0 +1,79 @@
+package repository
+
+import (
+	"slices"
+	"strconv"
+	"time"
+
+	"example.com/go-project/model"
+	"gorm.io/gorm"
+)
+
+// AuditQuery filters the audit log, zero values don't filter
+type AuditQuery struct {
+	UserId *int // Events of the user as the actor or the target
+	Action string
+	From   *time.Time // Inclusive
+	To     *time.Time // Exclusive
+}
+
+// AuditRepository only ever adds events, nothing updates or deletes them
+type AuditRepository struct {
+	Db *gorm.DB
+}
+
+func NewAuditRepository(db *gorm.DB) *AuditRepository {
+	return &AuditRepository{Db: db}
+}
+
+func (repo *AuditRepository) Save(event *model.AuditEvent) error {
+	return repo.Db.Create(event).Error
+}
+
+// Find returns a page of the matching events, newest first, with the number of them
+func (repo *AuditRepository) Find(query AuditQuery, page PageQuery) ([]model.AuditEvent, int64, error) {
+	db := repo.filter(query).Session(&gorm.Session{})
+	var total int64
+	if err := db.Count(&total).Error; err != nil {
+		return nil, 0, err
+	}
+
+	db, err := applyPage(db, []keysetColumn{{expr: "audit_events.id", desc: true}}, page)
+	if err != nil {
+		return nil, 0, err
+	}
+	var events []model.AuditEvent
+	if err := db.Find(&events).Error; err != nil {
+		return nil, 0, err
+	}
+	if page.Keyset != nil && page.Keyset.Backward {
+		slices.Reverse(events)
+	}
+	return events, total, nil
+}
+
+// Each passes the matching events to fn in batches, oldest first
+func (repo *AuditRepository) Each(query AuditQuery, batchSize int, fn func([]model.AuditEvent) error) error {
+	var events []model.AuditEvent
+	result := repo.filter(query).Order("id").FindInBatches(&events, batchSize, func(tx *gorm.DB, batch int) error {
+		return fn(events)
+	})
+	return result.Error
+}
+
+func (repo *AuditRepository) filter(query AuditQuery) *gorm.DB {
+	db := repo.Db.Model(&model.AuditEvent{})
+	if query.UserId != nil {
+		db = db.Where("(actor_id = ? OR (target_type = ? AND target_id = ?))", *query.UserId, "user", strconv.Itoa(*query.UserId))
+	}
+	if query.Action != "" {
+		db = db.Where("action = ?", query.Action)
+	}
+	if query.From != nil {
+		db = db.Where("created_at >= ?", *query.From)
+	}
+	if query.To != nil {
+		db = db.Where("created_at < ?", *query.To)
+	}
+	return db
+}
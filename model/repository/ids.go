@@ -0,0 +1,31 @@
+package repository
+
+import (
+	"slices"
+
+	"gorm.io/gorm"
+)
+
+// missingIds returns the ids that have no row in the table of the model
+func missingIds(db *gorm.DB, value interface{}, ids []int) ([]int, error) {
+	var found []int
+	err := db.Model(value).Where("id IN ?", ids).Pluck("id", &found).Error
+	if err != nil {
+		return nil, err
+	}
+
+	var missing []int
+	for _, id := range ids {
+		if !slices.Contains(found, id) && !slices.Contains(missing, id) {
+			missing = append(missing, id)
+		}
+	}
+	return missing, nil
+}
+
+// orderById orders preloaded rows of the table by id, associations are listed in a stable order
+func orderById(table string) func(db *gorm.DB) *gorm.DB {
+	return func(db *gorm.DB) *gorm.DB {
+		return db.Order(table + ".id")
+	}
+}
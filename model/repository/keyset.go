@@ -0,0 +1,90 @@
+package repository
+
+import (
+	"fmt"
+	"strings"
+
+	"gorm.io/gorm"
+)
+
+// PageQuery selects a page of a listing, by offset or by keyset
+type PageQuery struct {
+	Limit  int
+	Offset int
+	Keyset *Keyset // Offset is ignored when set
+}
+
+// Keyset is the position of a row in a sorted listing: its sort values with
+// the id last. Paging from a position stays correct while rows are added or
+// removed, and doesn't scan the skipped rows like an offset does.
+type Keyset struct {
+	Values   []interface{}
+	Backward bool // Rows before the position, the listing order is kept
+}
+
+// keysetColumn is a column a listing is sorted by
+type keysetColumn struct {
+	expr  string // Trusted SQL for the column
+	param string // SQL the cursor value is compared as, "?" when empty
+	desc  bool
+}
+
+// applyPage orders the query by the columns, then limits it to the page
+func applyPage(db *gorm.DB, columns []keysetColumn, page PageQuery) (*gorm.DB, error) {
+	backward := page.Keyset != nil && page.Keyset.Backward
+
+	if page.Keyset != nil {
+		if len(page.Keyset.Values) != len(columns) {
+			return nil, fmt.Errorf("keyset has %d values for %d sort columns", len(page.Keyset.Values), len(columns))
+		}
+		condition, args := keysetCondition(columns, page.Keyset.Values, backward)
+		db = db.Where(condition, args...)
+	} else if page.Offset > 0 {
+		db = db.Offset(page.Offset)
+	}
+
+	// Paging backwards reads the rows in reverse, the caller flips them back
+	for _, column := range columns {
+		if column.desc != backward {
+			db = db.Order(column.expr + " DESC")
+		} else {
+			db = db.Order(column.expr)
+		}
+	}
+
+	if page.Limit > 0 {
+		db = db.Limit(page.Limit)
+	}
+	return db, nil
+}
+
+// keysetCondition matches the rows after the values in the sort order, or
+// before them when backward: (a > ?) OR (a = ? AND b > ?) OR ...
+func keysetCondition(columns []keysetColumn, values []interface{}, backward bool) (string, []interface{}) {
+	var clauses []string
+	var args []interface{}
+	for i, column := range columns {
+		var parts []string
+		for j := 0; j < i; j++ {
+			parts = append(parts, fmt.Sprintf("%s = %s", columns[j].expr, columns[j].placeholder()))
+			args = append(args, values[j])
+		}
+
+		operator := ">"
+		if column.desc != backward {
+			operator = "<"
+		}
+		parts = append(parts, fmt.Sprintf("%s %s %s", column.expr, operator, column.placeholder()))
+		args = append(args, values[i])
+
+		clauses = append(clauses, "("+strings.Join(parts, " AND ")+")")
+	}
+	return "(" + strings.Join(clauses, " OR ") + ")", args
+}
+
+func (c keysetColumn) placeholder() string {
+	if c.param == "" {
+		return "?"
+	}
+	return c.param
+}
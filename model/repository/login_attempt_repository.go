@@ -0,0 +1,81 @@
+package repository
+
+import (
+	"time"
+
+	"example.com/go-project/model"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+type LoginAttemptRepository struct {
+	Db *gorm.DB
+}
+
+func NewLoginAttemptRepository(db *gorm.DB) *LoginAttemptRepository {
+	return &LoginAttemptRepository{Db: db}
+}
+
+// Find returns the attempts recorded for the keys, keys without failures are left out
+func (repo *LoginAttemptRepository) Find(keys ...string) ([]model.LoginAttempt, error) {
+	var attempts []model.LoginAttempt
+	result := repo.Db.Where("attempt_key IN ?", keys).Find(&attempts)
+	return attempts, result.Error
+}
+
+// Reserve counts an attempt for the key in a single upsert, so concurrent
+// logins each see the attempts before them. A lockout that is over or a quiet
+// window starts the count again. Returns the attempts including this one.
+func (repo *LoginAttemptRepository) Reserve(key string, now time.Time, window time.Duration) (*model.LoginAttempt, error) {
+	expired := "(login_attempts.locked_until IS NOT NULL AND login_attempts.locked_until <= ?) OR " +
+		"(login_attempts.locked_until IS NULL AND login_attempts.last_failure_at <= ?)"
+	attempt := model.LoginAttempt{Key: key, Failures: 1, LastFailureAt: now}
+	err := repo.Db.Transaction(func(tx *gorm.DB) error {
+		err := tx.Clauses(clause.OnConflict{
+			Columns: []clause.Column{{Name: "attempt_key"}},
+			// MySQL assigns in order and later assignments see the new values,
+			// so last_failure_at has to come after the columns that read it
+			DoUpdates: clause.Set{
+				{Column: clause.Column{Name: "failures"}, Value: gorm.Expr("CASE WHEN "+expired+" THEN 1 ELSE login_attempts.failures + 1 END", now, now.Add(-window))},
+				{Column: clause.Column{Name: "locked_until"}, Value: gorm.Expr("CASE WHEN "+expired+" THEN NULL ELSE login_attempts.locked_until END", now, now.Add(-window))},
+				{Column: clause.Column{Name: "last_failure_at"}, Value: now},
+			},
+		}).Create(&attempt).Error
+		if err != nil {
+			return err
+		}
+		return tx.Where("attempt_key = ?", key).First(&attempt).Error
+	})
+	if err != nil {
+		return nil, err
+	}
+	return &attempt, nil
+}
+
+// Release takes back an attempt Reserve counted
+func (repo *LoginAttemptRepository) Release(key string) error {
+	return repo.Db.Model(&model.LoginAttempt{}).
+		Where("attempt_key = ? AND failures > 0", key).
+		UpdateColumn("failures", gorm.Expr("failures - 1")).Error
+}
+
+// Lock locks the key until the time if its attempts reached the limit
+func (repo *LoginAttemptRepository) Lock(key string, limit int, until time.Time) error {
+	return repo.Db.Model(&model.LoginAttempt{}).
+		Where("attempt_key = ? AND failures >= ? AND locked_until IS NULL", key, limit).
+		UpdateColumn("locked_until", until).Error
+}
+
+// Delete forgets the failures of the key
+func (repo *LoginAttemptRepository) Delete(key string) error {
+	return repo.Db.Where("attempt_key = ?", key).Delete(&model.LoginAttempt{}).Error
+}
+
+// Prune removes the attempts that failed last before the time and aren't locked any more
+func (repo *LoginAttemptRepository) Prune(before time.Time) (int64, error) {
+	result := repo.Db.
+		Where("last_failure_at < ?", before).
+		Where("locked_until IS NULL OR locked_until < ?", before).
+		Delete(&model.LoginAttempt{})
+	return result.RowsAffected, result.Error
+}
@@ -1,10 +1,23 @@
 package repository
 
-import "example.com/go-project/model"
+import (
+	"time"
+
+	"example.com/go-project/model"
+)
 
 type NecheRepository interface {
-	Save(neche model.Neche) error
-	FindAll() ([]model.Neche, error)
+	Save(actorId int, neche *model.Neche) error
+	Update(actorId int, neche model.Neche) error
+	Revert(actorId int, id int, to model.NecheSnapshot, version int) error
+	FindAll(page PageQuery) ([]model.Neche, int64, error)
 	FindById(id int) (*model.Neche, error)
-	Delete(id int) error
+	FindByTagId(tagId int, descendants bool, page PageQuery) ([]model.Neche, int64, error)
+	MissingIds(ids []int) ([]int, error)
+	Attach(actorId int, tagId int, necheIds []int) error
+	Detach(actorId int, tagId int, necheId int) error
+	Delete(actorId int, id int, version int) error
+	Trash(page PageQuery) ([]model.Neche, int64, error)
+	Restore(actorId int, id int) error
+	Purge(before time.Time) (int64, error)
 }
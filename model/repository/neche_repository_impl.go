@@ -1,10 +1,18 @@
 package repository
 
 import (
+	"slices"
+	"time"
+
+	"example.com/go-project/helper"
 	"example.com/go-project/model"
 	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
 )
 
+// ErrNecheHasNoTags is returned when restoring a Neche all of whose Tags are in the trash
+var ErrNecheHasNoTags = helper.Conflict("all tags of the neche are in the trash, restore one of them first")
+
 type NecheRepositoryImpl struct {
 	Db *gorm.DB
 }
@@ -13,40 +21,285 @@ func NewNecheRepositoryImpl(Db *gorm.DB) NecheRepository {
 	return &NecheRepositoryImpl{Db: Db}
 }
 
-// Save Neche
-func (n *NecheRepositoryImpl) Save(neche model.Neche) error {
-	result := n.Db.Create(&neche)
-	if result.Error != nil {
-		return result.Error
-	}
-	return nil
+// Save Neche and attach it to its Tags, which have to exist
+func (n *NecheRepositoryImpl) Save(actorId int, neche *model.Neche) error {
+	return n.Db.Transaction(func(tx *gorm.DB) error {
+		// Only the join rows are written, the Tags themselves are left alone
+		if err := tx.Omit("Tags.*").Create(neche).Error; err != nil {
+			return err
+		}
+		after, err := necheSnapshot(tx, neche.Id)
+		if err != nil {
+			return err
+		}
+		return recordRevision(tx, actorId, model.RevisionNeche, neche.Id, model.ActionCreate, nil, after)
+	})
 }
 
-// Find all Neches
-func (n *NecheRepositoryImpl) FindAll() ([]model.Neche, error) {
-	var neches []model.Neche
-	result := n.Db.Find(&neches)
-	if result.Error != nil {
-		return nil, result.Error
-	}
-	return neches, nil
+// Find all Neches one page at a time, with the total number of Neches
+func (n *NecheRepositoryImpl) FindAll(page PageQuery) ([]model.Neche, int64, error) {
+	return findNechePage(n.Db.Model(&model.Neche{}), page)
+}
+
+// MissingIds returns the ids that don't belong to a Neche
+func (n *NecheRepositoryImpl) MissingIds(ids []int) ([]int, error) {
+	return missingIds(n.Db, &model.Neche{}, ids)
 }
 
 // Find Neche by ID
 func (n *NecheRepositoryImpl) FindById(id int) (*model.Neche, error) {
 	var neche model.Neche
-	result := n.Db.First(&neche, id)
+	result := n.Db.Preload("Tags", orderById("tags")).First(&neche, id)
 	if result.Error != nil {
 		return nil, result.Error
 	}
 	return &neche, nil
 }
 
-// Delete Neche by ID
-func (n *NecheRepositoryImpl) Delete(id int) error {
-	result := n.Db.Delete(&model.Neche{}, id)
+// Find the Neches of a Tag, or of the Tag and all its descendants, one page
+// at a time with the total number of those Neches
+func (n *NecheRepositoryImpl) FindByTagId(tagId int, descendants bool, page PageQuery) ([]model.Neche, int64, error) {
+	query := n.Db.Model(&model.Neche{}).
+		Joins("JOIN neche_tags ON neche_tags.neche_id = neches.id").
+		Where("neche_tags.tag_id = ?", tagId)
+	if descendants {
+		// A neche with several tags of the subtree is listed once
+		query = n.Db.Model(&model.Neche{}).
+			Where("neches.id IN (SELECT neche_id FROM neche_tags WHERE tag_id IN ("+subtreeSQL+"))", tagId)
+	}
+	return findNechePage(query, page)
+}
+
+// findNechePage pages through neches by id
+func findNechePage(query *gorm.DB, page PageQuery) ([]model.Neche, int64, error) {
+	// The count and the page share the conditions
+	query = query.Session(&gorm.Session{})
+	var total int64
+	result := query.Count(&total)
+	if result.Error != nil {
+		return nil, 0, result.Error
+	}
+
+	query, err := applyPage(query, []keysetColumn{{expr: "neches.id"}}, page)
+	if err != nil {
+		return nil, 0, err
+	}
+
+	var neches []model.Neche
+	result = query.Preload("Tags", orderById("tags")).Find(&neches)
+	if result.Error != nil {
+		return nil, 0, result.Error
+	}
+	if page.Keyset != nil && page.Keyset.Backward {
+		slices.Reverse(neches)
+	}
+	return neches, total, nil
+}
+
+// Update Neche, renaming it and replacing its Tags, if it still has
+// neche.Version. Version 0 updates any version.
+func (n *NecheRepositoryImpl) Update(actorId int, neche model.Neche) error {
+	return n.change(actorId, neche.Id, model.ActionUpdate, func(tx *gorm.DB) error {
+		return replaceNeche(tx, neche.Id, neche.NecheType, tagIds(neche.Tags), neche.Version)
+	})
+}
+
+// Revert gives the Neche the name and the Tags of the snapshot if it still
+// has the version, 0 reverts any version. The Tags have to exist.
+func (n *NecheRepositoryImpl) Revert(actorId int, id int, to model.NecheSnapshot, version int) error {
+	return n.change(actorId, id, model.ActionRevert, func(tx *gorm.DB) error {
+		return replaceNeche(tx, id, to.Name, to.TagIds, version)
+	})
+}
+
+// change runs the update of the Neche in a transaction and records it as a
+// revision, gorm.ErrRecordNotFound when there is no such Neche
+func (n *NecheRepositoryImpl) change(actorId int, id int, action string, update func(tx *gorm.DB) error) error {
+	return n.Db.Transaction(func(tx *gorm.DB) error {
+		before, err := necheSnapshot(tx, id)
+		if err != nil {
+			return err
+		}
+		if before == nil {
+			return gorm.ErrRecordNotFound
+		}
+
+		if err := update(tx); err != nil {
+			return err
+		}
+		after, err := necheSnapshot(tx, id)
+		if err != nil {
+			return err
+		}
+		return recordRevision(tx, actorId, model.RevisionNeche, id, action, before, after)
+	})
+}
+
+// replaceNeche renames the Neche and replaces its Tags
+func replaceNeche(tx *gorm.DB, id int, name string, tagIds []int, version int) error {
+	err := updateVersioned(tx, &model.Neche{}, id, version, map[string]interface{}{
+		"neche_type": name,
+	})
+	if err != nil {
+		return err
+	}
+
+	// Tags in the trash keep the Neche, it comes back with them
+	result := tx.Where("neche_id = ? AND tag_id IN (SELECT id FROM tags WHERE deleted_at IS NULL)", id).
+		Delete(&model.NecheTag{})
 	if result.Error != nil {
 		return result.Error
 	}
-	return nil
+	var necheTags []model.NecheTag
+	for _, tagId := range tagIds {
+		necheTags = append(necheTags, model.NecheTag{NecheId: id, TagId: tagId})
+	}
+	if len(necheTags) == 0 {
+		return nil
+	}
+	return tx.Create(&necheTags).Error
+}
+
+func tagIds(tags []model.Tags) []int {
+	var ids []int
+	for _, tag := range tags {
+		ids = append(ids, tag.Id)
+	}
+	return ids
+}
+
+// Attach the Neches to the Tag, Neches that already have it are skipped. The
+// version of every Neche that gets the Tag is incremented, its Tags changed.
+func (n *NecheRepositoryImpl) Attach(actorId int, tagId int, necheIds []int) error {
+	return n.Db.Transaction(func(tx *gorm.DB) error {
+		var attached []int
+		err := tx.Model(&model.NecheTag{}).Where("tag_id = ? AND neche_id IN ?", tagId, necheIds).Pluck("neche_id", &attached).Error
+		if err != nil {
+			return err
+		}
+
+		var necheTags []model.NecheTag
+		var changed []int
+		for _, necheId := range necheIds {
+			if !slices.Contains(attached, necheId) && !slices.Contains(changed, necheId) {
+				necheTags = append(necheTags, model.NecheTag{NecheId: necheId, TagId: tagId})
+				changed = append(changed, necheId)
+			}
+		}
+		if len(necheTags) == 0 {
+			return nil
+		}
+
+		before := make([]*model.NecheSnapshot, len(changed))
+		for i, necheId := range changed {
+			if before[i], err = necheSnapshot(tx, necheId); err != nil {
+				return err
+			}
+		}
+		if err := tx.Create(&necheTags).Error; err != nil {
+			return err
+		}
+		err = tx.Model(&model.Neche{}).Where("id IN ?", changed).Update("version", gorm.Expr("version + 1")).Error
+		if err != nil {
+			return err
+		}
+		for i, necheId := range changed {
+			after, err := necheSnapshot(tx, necheId)
+			if err != nil {
+				return err
+			}
+			err = recordRevision(tx, actorId, model.RevisionNeche, necheId, model.ActionUpdate, before[i], after)
+			if err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+}
+
+// Detach the Neche from the Tag, gorm.ErrRecordNotFound when it isn't
+// attached or one of them is in the trash
+func (n *NecheRepositoryImpl) Detach(actorId int, tagId int, necheId int) error {
+	return n.change(actorId, necheId, model.ActionUpdate, func(tx *gorm.DB) error {
+		result := tx.Where("tag_id = ? AND neche_id = ?", tagId, necheId).
+			Where("tag_id IN (SELECT id FROM tags WHERE deleted_at IS NULL)").
+			Delete(&model.NecheTag{})
+		if result.Error != nil {
+			return result.Error
+		}
+		if result.RowsAffected == 0 {
+			return gorm.ErrRecordNotFound
+		}
+		return tx.Model(&model.Neche{}).Where("id = ?", necheId).Update("version", gorm.Expr("version + 1")).Error
+	})
+}
+
+// Delete moves the Neche to the trash if it still has the version, 0 deletes any version
+func (n *NecheRepositoryImpl) Delete(actorId int, id int, version int) error {
+	return n.Db.Transaction(func(tx *gorm.DB) error {
+		before, err := necheSnapshot(tx, id)
+		if err != nil {
+			return err
+		}
+		if before == nil {
+			return gorm.ErrRecordNotFound
+		}
+
+		if err := deleteVersioned(tx, &model.Neche{}, id, version); err != nil {
+			return err
+		}
+		return recordRevision(tx, actorId, model.RevisionNeche, id, model.ActionDelete, before, nil)
+	})
+}
+
+// Trash returns a page of the Neches in the trash ordered by id, with the number of them
+func (n *NecheRepositoryImpl) Trash(page PageQuery) ([]model.Neche, int64, error) {
+	// Unscoped reaches the preload, the Tags in the trash are listed too
+	query := n.Db.Unscoped().Model(&model.Neche{}).Where("neches.deleted_at IS NOT NULL")
+	return findNechePage(query, page)
+}
+
+// Restore takes the Neche out of the trash and increments its version.
+// gorm.ErrRecordNotFound when it isn't in the trash, ErrNecheHasNoTags when
+// all its Tags are.
+func (n *NecheRepositoryImpl) Restore(actorId int, id int) error {
+	return n.Db.Transaction(func(tx *gorm.DB) error {
+		var neche model.Neche
+		err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).Unscoped().
+			Where("deleted_at IS NOT NULL").First(&neche, id).Error
+		if err != nil {
+			return err
+		}
+
+		// The Tags stay locked, they can't go to the trash while the Neche comes back
+		var tagIds []int
+		err = tx.Clauses(clause.Locking{Strength: "UPDATE"}).Model(&model.Tags{}).
+			Where("id IN (SELECT tag_id FROM neche_tags WHERE neche_id = ?)", id).Pluck("id", &tagIds).Error
+		if err != nil {
+			return err
+		}
+		if len(tagIds) == 0 {
+			return ErrNecheHasNoTags
+		}
+
+		err = tx.Unscoped().Model(&model.Neche{}).Where("id = ?", id).Updates(map[string]interface{}{
+			"deleted_at":          nil,
+			"deleted_with_tag_id": nil,
+			"version":             gorm.Expr("version + 1"),
+		}).Error
+		if err != nil {
+			return err
+		}
+		after, err := necheSnapshot(tx, id)
+		if err != nil {
+			return err
+		}
+		return recordRevision(tx, actorId, model.RevisionNeche, id, model.ActionRestore, nil, after)
+	})
+}
+
+// Purge deletes the Neches that went to the trash before the time for good and returns how many
+func (n *NecheRepositoryImpl) Purge(before time.Time) (int64, error) {
+	result := n.Db.Unscoped().Where("deleted_at < ?", before).Delete(&model.Neche{})
+	return result.RowsAffected, result.Error
 }
@@ -0,0 +1,131 @@
+package repository
+
+import (
+	"errors"
+
+	"example.com/go-project/model"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// PermissionRepository stores roles, permissions and the grants between them.
+// It is also the database backed auth.PermissionStore.
+type PermissionRepository struct {
+	Db *gorm.DB
+}
+
+func NewPermissionRepository(db *gorm.DB) *PermissionRepository {
+	return &PermissionRepository{Db: db}
+}
+
+// FindAllRoles returns every role with its permissions
+func (repo *PermissionRepository) FindAllRoles() ([]model.Role, error) {
+	var roles []model.Role
+	result := repo.Db.Preload("Permissions", func(db *gorm.DB) *gorm.DB {
+		return db.Order("name")
+	}).Order("name").Find(&roles)
+	if result.Error != nil {
+		return nil, result.Error
+	}
+	return roles, nil
+}
+
+// FindRoleByName returns nil without error when the role doesn't exist
+func (repo *PermissionRepository) FindRoleByName(name string) (*model.Role, error) {
+	var role model.Role
+	result := repo.Db.Preload("Permissions").Where("name = ?", name).First(&role)
+	if result.Error != nil {
+		if errors.Is(result.Error, gorm.ErrRecordNotFound) {
+			return nil, nil
+		}
+		return nil, result.Error
+	}
+	return &role, nil
+}
+
+func (repo *PermissionRepository) SaveRole(role *model.Role) error {
+	return repo.Db.Omit("Permissions").Create(role).Error
+}
+
+// DeleteRole returns false when the role doesn't exist
+func (repo *PermissionRepository) DeleteRole(name string) (bool, error) {
+	result := repo.Db.Where("name = ?", name).Delete(&model.Role{})
+	if result.Error != nil {
+		return false, result.Error
+	}
+	return result.RowsAffected > 0, nil
+}
+
+// CountUsersWithRole counts the users that would lose their permissions with the role
+func (repo *PermissionRepository) CountUsersWithRole(name string) (int64, error) {
+	var count int64
+	result := repo.Db.Model(&model.Users{}).Where("role = ?", name).Count(&count)
+	return count, result.Error
+}
+
+func (repo *PermissionRepository) FindAllPermissions() ([]model.Permission, error) {
+	var permissions []model.Permission
+	result := repo.Db.Order("name").Find(&permissions)
+	if result.Error != nil {
+		return nil, result.Error
+	}
+	return permissions, nil
+}
+
+// FindPermissionByName returns nil without error when the permission doesn't exist
+func (repo *PermissionRepository) FindPermissionByName(name string) (*model.Permission, error) {
+	var permission model.Permission
+	result := repo.Db.Where("name = ?", name).First(&permission)
+	if result.Error != nil {
+		if errors.Is(result.Error, gorm.ErrRecordNotFound) {
+			return nil, nil
+		}
+		return nil, result.Error
+	}
+	return &permission, nil
+}
+
+func (repo *PermissionRepository) SavePermission(permission *model.Permission) error {
+	return repo.Db.Create(permission).Error
+}
+
+// DeletePermission returns false when the permission doesn't exist
+func (repo *PermissionRepository) DeletePermission(name string) (bool, error) {
+	result := repo.Db.Where("name = ?", name).Delete(&model.Permission{})
+	if result.Error != nil {
+		return false, result.Error
+	}
+	return result.RowsAffected > 0, nil
+}
+
+// Grant gives the permission to the role, granting it twice is not an error
+func (repo *PermissionRepository) Grant(roleId int, permissionId int) error {
+	grant := model.RolePermission{RoleId: roleId, PermissionId: permissionId}
+	return repo.Db.Clauses(clause.OnConflict{DoNothing: true}).Create(&grant).Error
+}
+
+// Revoke takes the permission away from the role
+func (repo *PermissionRepository) Revoke(roleId int, permissionId int) error {
+	return repo.Db.Where("role_id = ? AND permission_id = ?", roleId, permissionId).Delete(&model.RolePermission{}).Error
+}
+
+// PermissionsForRole returns the names of the permissions granted to the role
+func (repo *PermissionRepository) PermissionsForRole(role string) ([]string, error) {
+	var names []string
+	result := repo.Db.Model(&model.Permission{}).
+		Joins("JOIN role_permissions ON role_permissions.permission_id = permissions.id").
+		Joins("JOIN roles ON roles.id = role_permissions.role_id").
+		Where("roles.name = ?", role).
+		Pluck("permissions.name", &names)
+	if result.Error != nil {
+		return nil, result.Error
+	}
+	return names, nil
+}
+
+// RoleExists reports whether a role with the name exists
+func (repo *PermissionRepository) RoleExists(role string) (bool, error) {
+	var count int64
+	result := repo.Db.Model(&model.Role{}).Where("name = ?", role).Count(&count)
+	return count > 0, result.Error
+}
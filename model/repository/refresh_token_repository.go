@@ -0,0 +1,85 @@
+package repository
+
+import (
+	"errors"
+	"time"
+
+	"example.com/go-project/model"
+	"gorm.io/gorm"
+)
+
+type RefreshTokenRepository struct {
+	Db *gorm.DB
+}
+
+func NewRefreshTokenRepository(db *gorm.DB) *RefreshTokenRepository {
+	return &RefreshTokenRepository{Db: db}
+}
+
+func (repo *RefreshTokenRepository) Save(token *model.RefreshToken) error {
+	result := repo.Db.Create(token)
+	if result.Error != nil {
+		return result.Error
+	}
+	return nil
+}
+
+// FindByHash finds a refresh token by the hash of its raw value
+func (repo *RefreshTokenRepository) FindByHash(tokenHash string) (*model.RefreshToken, error) {
+	var token model.RefreshToken
+	result := repo.Db.Where("token_hash = ?", tokenHash).First(&token)
+	if result.Error != nil {
+		if errors.Is(result.Error, gorm.ErrRecordNotFound) {
+			return nil, nil // No token found, return nil without error
+		}
+		return nil, result.Error
+	}
+	return &token, nil
+}
+
+// Rotate marks the token as used and saves its replacement in one transaction.
+// It returns false when the token was already revoked by a concurrent request.
+func (repo *RefreshTokenRepository) Rotate(tokenId int, replacement *model.RefreshToken) (bool, error) {
+	rotated := false
+	err := repo.Db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Create(replacement).Error; err != nil {
+			return err
+		}
+
+		// Only revoke the token if nobody else has done it in the meantime
+		result := tx.Model(&model.RefreshToken{}).
+			Where("id = ? AND revoked_at IS NULL", tokenId).
+			Updates(map[string]interface{}{"revoked_at": time.Now(), "replaced_by": replacement.Id})
+		if result.Error != nil {
+			return result.Error
+		}
+		if result.RowsAffected == 0 {
+			return errAlreadyRotated
+		}
+
+		rotated = true
+		return nil
+	})
+	if errors.Is(err, errAlreadyRotated) {
+		return false, nil
+	}
+	return rotated, err
+}
+
+// RevokeFamily revokes every token that descends from the same login
+func (repo *RefreshTokenRepository) RevokeFamily(familyId string) error {
+	result := repo.Db.Model(&model.RefreshToken{}).
+		Where("family_id = ? AND revoked_at IS NULL", familyId).
+		Update("revoked_at", time.Now())
+	return result.Error
+}
+
+var errAlreadyRotated = errors.New("refresh token already rotated")
+
+// RevokeAllForUser revokes every active refresh token of the user
+func (repo *RefreshTokenRepository) RevokeAllForUser(userId int) error {
+	result := repo.Db.Model(&model.RefreshToken{}).
+		Where("user_id = ? AND revoked_at IS NULL", userId).
+		Update("revoked_at", time.Now())
+	return result.Error
+}
@@ -0,0 +1,123 @@
+package repository
+
+import (
+	"encoding/json"
+	"errors"
+	"slices"
+
+	"example.com/go-project/model"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+type RevisionRepository interface {
+	FindByEntity(entityType string, entityId int, page PageQuery) ([]model.Revision, int64, error)
+	FindById(id int) (model.Revision, error)
+}
+
+type RevisionRepositoryImpl struct {
+	Db *gorm.DB
+}
+
+func NewRevisionRepositoryImpl(Db *gorm.DB) RevisionRepository {
+	return &RevisionRepositoryImpl{Db: Db}
+}
+
+// FindByEntity returns a page of the revisions of the entity, newest first, with the number of them
+func (r *RevisionRepositoryImpl) FindByEntity(entityType string, entityId int, page PageQuery) ([]model.Revision, int64, error) {
+	query := r.Db.Model(&model.Revision{}).
+		Where("entity_type = ? AND entity_id = ?", entityType, entityId).
+		Session(&gorm.Session{})
+	var total int64
+	if err := query.Count(&total).Error; err != nil {
+		return nil, 0, err
+	}
+
+	query, err := applyPage(query, []keysetColumn{{expr: "revisions.id", desc: true}}, page)
+	if err != nil {
+		return nil, 0, err
+	}
+	var revisions []model.Revision
+	if err := query.Find(&revisions).Error; err != nil {
+		return nil, 0, err
+	}
+	if page.Keyset != nil && page.Keyset.Backward {
+		slices.Reverse(revisions)
+	}
+	return revisions, total, nil
+}
+
+// FindById returns the revision, gorm.ErrRecordNotFound when there is none
+func (r *RevisionRepositoryImpl) FindById(id int) (model.Revision, error) {
+	var revision model.Revision
+	err := r.Db.First(&revision, id).Error
+	return revision, err
+}
+
+// recordRevision adds a revision in the transaction of the change it records.
+// A nil snapshot means the entity wasn't there, actor 0 that nobody asked for the change.
+func recordRevision[T any](tx *gorm.DB, actorId int, entityType string, entityId int, action string, before, after *T) error {
+	revision := model.Revision{EntityType: entityType, EntityId: entityId, Action: action}
+	if actorId != 0 {
+		revision.ActorId = &actorId
+	}
+
+	var err error
+	if revision.Before, err = snapshotJSON(before); err != nil {
+		return err
+	}
+	if revision.After, err = snapshotJSON(after); err != nil {
+		return err
+	}
+	return tx.Create(&revision).Error
+}
+
+func snapshotJSON[T any](snapshot *T) (*string, error) {
+	if snapshot == nil {
+		return nil, nil
+	}
+	data, err := json.Marshal(snapshot)
+	if err != nil {
+		return nil, err
+	}
+	value := string(data)
+	return &value, nil
+}
+
+// tagSnapshot locks the tag and reads it for a revision, nil when it doesn't
+// exist or is in the trash
+func tagSnapshot(tx *gorm.DB, id int) (*model.TagSnapshot, error) {
+	var tag model.Tags
+	err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).First(&tag, id).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &model.TagSnapshot{Name: tag.Name, ParentId: tag.ParentId, Version: tag.Version}, nil
+}
+
+// necheSnapshot locks the neche and reads it for a revision, nil when it
+// doesn't exist or is in the trash
+func necheSnapshot(tx *gorm.DB, id int) (*model.NecheSnapshot, error) {
+	var neche model.Neche
+	err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).First(&neche, id).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	tagIds := []int{}
+	err = tx.Model(&model.NecheTag{}).
+		Joins("JOIN tags ON tags.id = neche_tags.tag_id AND tags.deleted_at IS NULL").
+		Where("neche_tags.neche_id = ?", id).
+		Order("neche_tags.tag_id").
+		Pluck("neche_tags.tag_id", &tagIds).Error
+	if err != nil {
+		return nil, err
+	}
+	return &model.NecheSnapshot{Name: neche.NecheType, TagIds: tagIds, Version: neche.Version}, nil
+}
@@ -0,0 +1,64 @@
+package repository
+
+import (
+	"errors"
+	"time"
+
+	"example.com/go-project/model"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// RevocationRepository is the database backed auth.RevocationStore
+type RevocationRepository struct {
+	Db *gorm.DB
+}
+
+func NewRevocationRepository(db *gorm.DB) *RevocationRepository {
+	return &RevocationRepository{Db: db}
+}
+
+func (repo *RevocationRepository) RevokeToken(jti string, userId int, expiresAt time.Time) error {
+	revoked := model.RevokedToken{Jti: jti, UserId: userId, ExpiresAt: expiresAt}
+	// Revoking the same token twice is not an error
+	result := repo.Db.Clauses(clause.OnConflict{DoNothing: true}).Create(&revoked)
+	return result.Error
+}
+
+func (repo *RevocationRepository) RevokeUser(userId int, revokedAt time.Time, expiresAt time.Time) error {
+	revocation := model.UserRevocation{UserId: userId, RevokedAt: revokedAt, ExpiresAt: expiresAt}
+	result := repo.Db.Clauses(clause.OnConflict{
+		Columns:   []clause.Column{{Name: "user_id"}},
+		DoUpdates: clause.AssignmentColumns([]string{"revoked_at", "expires_at"}),
+	}).Create(&revocation)
+	return result.Error
+}
+
+func (repo *RevocationRepository) IsRevoked(jti string, userId int, issuedAt time.Time) (bool, error) {
+	var count int64
+	result := repo.Db.Model(&model.RevokedToken{}).Where("jti = ?", jti).Count(&count)
+	if result.Error != nil {
+		return false, result.Error
+	}
+	if count > 0 {
+		return true, nil
+	}
+
+	var revocation model.UserRevocation
+	result = repo.Db.Where("user_id = ?", userId).First(&revocation)
+	if result.Error != nil {
+		if errors.Is(result.Error, gorm.ErrRecordNotFound) {
+			return false, nil
+		}
+		return false, result.Error
+	}
+	return issuedAt.Before(revocation.RevokedAt), nil
+}
+
+// Prune removes entries for tokens that have expired on their own
+func (repo *RevocationRepository) Prune(now time.Time) error {
+	if err := repo.Db.Where("expires_at < ?", now).Delete(&model.RevokedToken{}).Error; err != nil {
+		return err
+	}
+	return repo.Db.Where("expires_at < ?", now).Delete(&model.UserRevocation{}).Error
+}
@@ -1,14 +1,50 @@
 package repository
 
-import "example.com/go-project/model"
+import (
+	"time"
+
+	"example.com/go-project/model"
+)
 
 type TagsRepository interface {
-	Save(tags model.Tags) error
-	Update(tags model.Tags) error
-	Delete(tagsId int) error
+	Save(actorId int, tags model.Tags) error
+	Update(actorId int, tags model.Tags) error
+	Delete(actorId int, tagsId int, version int) error
 	FindById(tagsId int) (tags model.Tags, err error)
+	MissingIds(ids []int) ([]int, error)
 	FindAll(int, int) ([]model.Tags, error)
-	// FindPaginated(limit int, offset int) []model.Tags
-	// FindAllSorted(sortBy string, order string) []model.Tags
-	// FindByCustomFilter(startsWith string) []model.Tags
+	Search(query TagsQuery) ([]model.Tags, int64, error)
+	Move(actorId int, tagId int, parentId *int, version int) error
+	Revert(actorId int, tagId int, to model.TagSnapshot, version int) error
+	Subtree(tagId int) ([]model.Tags, error)
+	Ancestors(tagId int) ([]model.Tags, error)
+	Trash(page PageQuery) ([]model.Tags, int64, error)
+	Restore(actorId int, tagId int) error
+	Purge(before time.Time) (int64, error)
+}
+
+// Ways TagsQuery.Name is matched against tag names
+const (
+	MatchContains = "contains"
+	MatchPrefix   = "prefix"
+)
+
+// TagsQuery filters and sorts tags, zero values don't filter
+type TagsQuery struct {
+	Name      string // Matched case-insensitively
+	Match     string // MatchContains or MatchPrefix, contains by default
+	HasNeches *bool
+	MinNeches *int
+	MaxNeches *int
+	Sort      []TagsSort // Ties are broken by id
+	PageQuery
 }
+
+// TagsSort sorts by one of TagsSortFields
+type TagsSort struct {
+	Field string
+	Desc  bool
+}
+
+// TagsSortFields are the fields tags can be sorted by
+var TagsSortFields = []string{"id", "name", "necheCount"}
@@ -2,11 +2,37 @@ package repository
 
 import (
 	"errors"
+	"fmt"
+	"slices"
+	"strings"
+	"time"
 
+	"example.com/go-project/helper"
 	"example.com/go-project/model"
 	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
 )
 
+// ErrTagHasChildren is returned when deleting a tag other tags are under
+var ErrTagHasChildren = helper.Conflict("the tag has child tags, move or delete them first")
+
+// ErrTagParentTrashed is returned when restoring a tag whose parent is in the trash
+var ErrTagParentTrashed = helper.Conflict("the parent of the tag is in the trash, restore it first")
+
+// ErrTagCycle is returned when a tag would be moved under itself or one of its descendants
+var ErrTagCycle = helper.Conflict("a tag can't be moved under itself or one of its descendants")
+
+// maxTagDepth bounds walks up the tree, a cycle in the data can't make them loop
+const maxTagDepth = 1000
+
+// subtreeSQL selects the ids of the tag with the id ? and of all its descendants.
+// UNION drops rows it already has, so the recursion ends even on a cycle.
+const subtreeSQL = `WITH RECURSIVE subtree(id) AS (
+	SELECT id FROM tags WHERE id = ?
+	UNION
+	SELECT tags.id FROM tags JOIN subtree ON tags.parent_id = subtree.id WHERE tags.deleted_at IS NULL
+) SELECT id FROM subtree`
+
 type TagsRepositoryImpl struct {
 	Db *gorm.DB
 }
@@ -15,19 +41,182 @@ func NewTagsRepositoryImpl(Db *gorm.DB) TagsRepository {
 	return &TagsRepositoryImpl{Db: Db}
 }
 
-func (t *TagsRepositoryImpl) Delete(tagId int) error {
-	var tags model.Tags
-	result := t.Db.Where("id = ?", tagId).Delete(&tags)
-	if result.Error != nil {
-		return result.Error
+// Delete moves the tag to the trash if it still has the version, 0 deletes
+// any version. Neches left without a tag go to the trash with it. Deleting a
+// tag that doesn't exist isn't an error, deleting one with children is.
+func (t *TagsRepositoryImpl) Delete(actorId int, tagId int, version int) error {
+	return t.Db.Transaction(func(tx *gorm.DB) error {
+		// The tag stays locked, a child can't be added or restored under it meanwhile
+		before, err := tagSnapshot(tx, tagId)
+		if err != nil || before == nil {
+			return err
+		}
+
+		var children int64
+		err = tx.Model(&model.Tags{}).Where("parent_id = ?", tagId).Count(&children).Error
+		if err != nil {
+			return err
+		}
+		if children > 0 {
+			return ErrTagHasChildren
+		}
+
+		// Neches without another tag outside the trash go with the tag
+		var necheIds []int
+		err = tx.Model(&model.Neche{}).
+			Where("id IN (SELECT neche_id FROM neche_tags WHERE tag_id = ?)", tagId).
+			Where("NOT EXISTS (SELECT 1 FROM neche_tags JOIN tags ON tags.id = neche_tags.tag_id"+
+				" WHERE neche_tags.neche_id = neches.id AND tags.deleted_at IS NULL AND tags.id <> ?)", tagId).
+			Order("id").Pluck("id", &necheIds).Error
+		if err != nil {
+			return err
+		}
+		necheBefore := make([]*model.NecheSnapshot, len(necheIds))
+		for i, necheId := range necheIds {
+			if necheBefore[i], err = necheSnapshot(tx, necheId); err != nil {
+				return err
+			}
+		}
+
+		err = deleteVersioned(tx, &model.Tags{}, tagId, version)
+		if err != nil {
+			return err
+		}
+		err = recordRevision(tx, actorId, model.RevisionTag, tagId, model.ActionDelete, before, nil)
+		if err != nil || len(necheIds) == 0 {
+			return err
+		}
+
+		// The neches share the deletion time of the tag and remember it, restoring the tag brings them back
+		err = tx.Model(&model.Neche{}).Where("id IN ?", necheIds).Updates(map[string]interface{}{
+			"deleted_at":          gorm.Expr("(SELECT deleted_at FROM tags WHERE id = ?)", tagId),
+			"deleted_with_tag_id": tagId,
+		}).Error
+		if err != nil {
+			return err
+		}
+		for i, necheId := range necheIds {
+			err := recordRevision(tx, actorId, model.RevisionNeche, necheId, model.ActionDelete, necheBefore[i], nil)
+			if err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+}
+
+// Trash returns a page of the tags in the trash ordered by id, with the number of them
+func (t *TagsRepositoryImpl) Trash(page PageQuery) ([]model.Tags, int64, error) {
+	query := t.Db.Unscoped().Model(&model.Tags{}).Where("deleted_at IS NOT NULL").Session(&gorm.Session{})
+	var total int64
+	if err := query.Count(&total).Error; err != nil {
+		return nil, 0, err
+	}
+
+	query, err := applyPage(query, []keysetColumn{{expr: "tags.id"}}, page)
+	if err != nil {
+		return nil, 0, err
+	}
+	var tags []model.Tags
+	if err := query.Find(&tags).Error; err != nil {
+		return nil, 0, err
+	}
+	if page.Keyset != nil && page.Keyset.Backward {
+		slices.Reverse(tags)
+	}
+	return tags, total, nil
+}
+
+// Restore takes the tag out of the trash with the neches that went there with
+// it, gorm.ErrRecordNotFound when the tag isn't in the trash. Its parent
+// mustn't be in the trash. The versions are incremented.
+func (t *TagsRepositoryImpl) Restore(actorId int, tagId int) error {
+	return t.Db.Transaction(func(tx *gorm.DB) error {
+		locking := tx.Clauses(clause.Locking{Strength: "UPDATE"}).Session(&gorm.Session{})
+		var tag model.Tags
+		err := locking.Unscoped().Where("deleted_at IS NOT NULL").First(&tag, tagId).Error
+		if err != nil {
+			return err
+		}
+
+		// The parent stays locked, it can't go to the trash until the child is back
+		if tag.ParentId != nil {
+			err := locking.Select("id").First(&model.Tags{}, *tag.ParentId).Error
+			if errors.Is(err, gorm.ErrRecordNotFound) {
+				return ErrTagParentTrashed
+			}
+			if err != nil {
+				return err
+			}
+		}
+
+		err = tx.Unscoped().Model(&model.Tags{}).Where("id = ?", tagId).Updates(map[string]interface{}{
+			"deleted_at": nil,
+			"version":    gorm.Expr("version + 1"),
+		}).Error
+		if err != nil {
+			return err
+		}
+		after, err := tagSnapshot(tx, tagId)
+		if err != nil {
+			return err
+		}
+		err = recordRevision(tx, actorId, model.RevisionTag, tagId, model.ActionRestore, nil, after)
+		if err != nil {
+			return err
+		}
+
+		var necheIds []int
+		err = tx.Unscoped().Model(&model.Neche{}).Where("deleted_with_tag_id = ?", tagId).Order("id").Pluck("id", &necheIds).Error
+		if err != nil || len(necheIds) == 0 {
+			return err
+		}
+		err = tx.Unscoped().Model(&model.Neche{}).Where("id IN ?", necheIds).Updates(map[string]interface{}{
+			"deleted_at":          nil,
+			"deleted_with_tag_id": nil,
+			"version":             gorm.Expr("version + 1"),
+		}).Error
+		if err != nil {
+			return err
+		}
+		for _, necheId := range necheIds {
+			after, err := necheSnapshot(tx, necheId)
+			if err != nil {
+				return err
+			}
+			err = recordRevision(tx, actorId, model.RevisionNeche, necheId, model.ActionRestore, nil, after)
+			if err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+}
+
+// Purge deletes the tags that went to the trash before the time for good and
+// returns how many. A tag stays while a child of it is in the trash.
+func (t *TagsRepositoryImpl) Purge(before time.Time) (int64, error) {
+	var purged int64
+	for {
+		// Every round purges the leaves, their parents become leaves for the next one
+		result := t.Db.Unscoped().
+			Where("deleted_at < ?", before).
+			Where("NOT EXISTS (SELECT 1 FROM tags AS children WHERE children.parent_id = tags.id)").
+			Delete(&model.Tags{})
+		if result.Error != nil {
+			return purged, result.Error
+		}
+		if result.RowsAffected == 0 {
+			return purged, nil
+		}
+		purged += result.RowsAffected
 	}
-	return nil
 }
 
 func (t *TagsRepositoryImpl) FindAll(limit int, offset int) ([]model.Tags, error) {
 	var tags []model.Tags
 	// Preload Neches and apply pagination using Limit and Offset
-	result := t.Db.Preload("Neches").Limit(limit).Offset(offset).Find(&tags)
+	result := t.Db.Preload("Neches.Tags", orderById("tags")).Limit(limit).Offset(offset).Find(&tags)
 
 	if result.Error != nil {
 		return nil, result.Error // Return nil and the error if fetching fails
@@ -44,66 +233,253 @@ func (r *TagsRepositoryImpl) FindById(tagsId int) (model.Tags, error) {
 
 	// Check if no rows were found
 	if errors.Is(result.Error, gorm.ErrRecordNotFound) {
-		return tag, errors.New("tag not found")
+		return tag, helper.NotFound("tag not found")
 	}
 
 	return tag, result.Error
 }
 
-func (t *TagsRepositoryImpl) Save(tags model.Tags) error {
-	// Try to create the tag in the database
-	result := t.Db.Create(&tags)
+// MissingIds returns the ids that don't belong to a tag
+func (t *TagsRepositoryImpl) MissingIds(ids []int) ([]int, error) {
+	return missingIds(t.Db, &model.Tags{}, ids)
+}
 
-	// Check for any errors and return them
-	if result.Error != nil {
-		return result.Error
-	}
+func (t *TagsRepositoryImpl) Save(actorId int, tags model.Tags) error {
+	return t.Db.Transaction(func(tx *gorm.DB) error {
+		// The parent stays locked, it can't go to the trash while the child is added
+		if tags.ParentId != nil {
+			err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).Select("id").First(&model.Tags{}, *tags.ParentId).Error
+			if errors.Is(err, gorm.ErrRecordNotFound) {
+				return parentNotFound(*tags.ParentId)
+			}
+			if err != nil {
+				return err
+			}
+		}
 
-	// If successful, return nil
-	return nil
+		// Try to create the tag in the database
+		if err := tx.Create(&tags).Error; err != nil {
+			return err
+		}
+		after, err := tagSnapshot(tx, tags.Id)
+		if err != nil {
+			return err
+		}
+		return recordRevision(tx, actorId, model.RevisionTag, tags.Id, model.ActionCreate, nil, after)
+	})
 }
 
-func (t *TagsRepositoryImpl) Update(tags model.Tags) error {
-	// Check if the tag exists
-	var existingTag model.Tags
-	if err := t.Db.First(&existingTag, tags.Id).Error; err != nil {
+// Update saves the tag if it still has tags.Version, 0 updates any version.
+// The stored version is incremented.
+func (t *TagsRepositoryImpl) Update(actorId int, tags model.Tags) error {
+	return t.change(actorId, tags.Id, model.ActionUpdate, func(tx *gorm.DB) error {
+		return updateVersioned(tx, &model.Tags{}, tags.Id, tags.Version, map[string]interface{}{
+			"name": tags.Name,
+		})
+	})
+}
+
+// Move puts the tag under the parent, or makes it a root when parentId is nil,
+// if it still has the version. 0 moves any version. The stored version is incremented.
+func (t *TagsRepositoryImpl) Move(actorId int, tagId int, parentId *int, version int) error {
+	return t.change(actorId, tagId, model.ActionUpdate, func(tx *gorm.DB) error {
+		if err := checkParent(tx, tagId, parentId); err != nil {
+			return err
+		}
+		return updateVersioned(tx, &model.Tags{}, tagId, version, map[string]interface{}{
+			"parent_id": parentId,
+		})
+	})
+}
+
+// Revert gives the tag the name and the parent of the snapshot if it still
+// has the version, 0 reverts any version. The stored version is incremented.
+func (t *TagsRepositoryImpl) Revert(actorId int, tagId int, to model.TagSnapshot, version int) error {
+	return t.change(actorId, tagId, model.ActionRevert, func(tx *gorm.DB) error {
+		if err := checkParent(tx, tagId, to.ParentId); err != nil {
+			return err
+		}
+		return updateVersioned(tx, &model.Tags{}, tagId, version, map[string]interface{}{
+			"name":      to.Name,
+			"parent_id": to.ParentId,
+		})
+	})
+}
+
+// change runs the update of the tag in a transaction and records it as a revision
+func (t *TagsRepositoryImpl) change(actorId int, tagId int, action string, update func(tx *gorm.DB) error) error {
+	return t.Db.Transaction(func(tx *gorm.DB) error {
+		before, err := tagSnapshot(tx, tagId)
+		if err != nil {
+			return err
+		}
+		if before == nil {
+			return helper.NotFound("tag not found")
+		}
+
+		if err := update(tx); err != nil {
+			return err
+		}
+		after, err := tagSnapshot(tx, tagId)
+		if err != nil {
+			return err
+		}
+		return recordRevision(tx, actorId, model.RevisionTag, tagId, action, before, after)
+	})
+}
+
+// checkParent makes sure the tag can go under the parent, a nil parent is the
+// root. Walking up from the parent meets the tag when it's one of its
+// descendants. Every tag on the way stays locked, a concurrent move can't
+// change the path until the transaction ends.
+func checkParent(tx *gorm.DB, tagId int, parentId *int) error {
+	locking := tx.Clauses(clause.Locking{Strength: "UPDATE"}).Session(&gorm.Session{})
+	for id, depth := parentId, 0; id != nil; depth++ {
+		if *id == tagId || depth > maxTagDepth {
+			return ErrTagCycle
+		}
+		var ancestor model.Tags
+		err := locking.Select("id", "parent_id").First(&ancestor, *id).Error
 		if errors.Is(err, gorm.ErrRecordNotFound) {
-			return errors.New("tag not found")
+			return parentNotFound(*parentId)
+		}
+		if err != nil {
+			return err
+		}
+		id = ancestor.ParentId
+	}
+	return nil
+}
+
+func parentNotFound(parentId int) error {
+	return fmt.Errorf("parent tag with ID %d: %w", parentId, helper.NotFound("tag not found"))
+}
+
+// Subtree returns the tag and all its descendants ordered by id, or
+// gorm.ErrRecordNotFound when there is no such tag
+func (t *TagsRepositoryImpl) Subtree(tagId int) ([]model.Tags, error) {
+	var tags []model.Tags
+	err := t.Db.Where("id IN ("+subtreeSQL+")", tagId).Order("id").Find(&tags).Error
+	if err != nil {
+		return nil, err
+	}
+	if len(tags) == 0 {
+		return nil, gorm.ErrRecordNotFound
+	}
+	return tags, nil
+}
+
+// Ancestors returns the path from the root down to the tag, the tag included,
+// or gorm.ErrRecordNotFound when there is no such tag
+func (t *TagsRepositoryImpl) Ancestors(tagId int) ([]model.Tags, error) {
+	var tags []model.Tags
+	err := t.Db.Raw(`WITH RECURSIVE ancestors(id, parent_id, depth) AS (
+		SELECT id, parent_id, 0 FROM tags WHERE id = ? AND deleted_at IS NULL
+		UNION ALL
+		SELECT tags.id, tags.parent_id, ancestors.depth + 1
+		FROM tags JOIN ancestors ON tags.id = ancestors.parent_id
+		WHERE ancestors.depth < ?
+	) SELECT tags.* FROM tags JOIN ancestors ON tags.id = ancestors.id ORDER BY ancestors.depth DESC`,
+		tagId, maxTagDepth).Scan(&tags).Error
+	if err != nil {
+		return nil, err
+	}
+	if len(tags) == 0 {
+		return nil, gorm.ErrRecordNotFound
+	}
+	return tags, nil
+}
+
+// necheCountSQL counts the neches of the tag of the current row, those in the trash aren't counted
+const necheCountSQL = "(SELECT COUNT(*) FROM neche_tags JOIN neches ON neches.id = neche_tags.neche_id" +
+	" WHERE neche_tags.tag_id = tags.id AND neches.deleted_at IS NULL)"
+
+// Sort fields mapped to trusted SQL, user input never reaches ORDER BY
+var tagsSortColumns = map[string]keysetColumn{
+	"id":         {expr: "tags.id"},
+	"name":       {expr: "LOWER(tags.name)", param: "LOWER(?)"},
+	"necheCount": {expr: necheCountSQL},
+}
+
+// TagsKeyset is the position of a tag in a listing with the sort, for paging
+// from it. The tag needs its Neches loaded when sorting by necheCount.
+func TagsKeyset(tag model.Tags, sort []TagsSort) []interface{} {
+	var values []interface{}
+	for _, s := range sort {
+		switch s.Field {
+		case "id":
+			values = append(values, tag.Id)
+		case "name":
+			values = append(values, tag.Name)
+		case "necheCount":
+			values = append(values, len(tag.Neches))
 		}
-		return err
 	}
+	return append(values, tag.Id)
+}
+
+// Search finds the tags matching every filter of the query, with the number
+// of matching tags before the page applies
+func (t *TagsRepositoryImpl) Search(query TagsQuery) ([]model.Tags, int64, error) {
+	db := t.Db.Model(&model.Tags{})
 
-	result := t.Db.Model(&existingTag).Updates(tags)
+	if query.Name != "" {
+		pattern := escapeLike(strings.ToLower(query.Name)) + "%"
+		if query.Match != MatchPrefix {
+			pattern = "%" + pattern
+		}
+		db = db.Where(`LOWER(tags.name) LIKE ? ESCAPE '\'`, pattern)
+	}
+	if query.HasNeches != nil {
+		if *query.HasNeches {
+			db = db.Where(necheCountSQL + " > 0")
+		} else {
+			db = db.Where(necheCountSQL + " = 0")
+		}
+	}
+	if query.MinNeches != nil {
+		db = db.Where(necheCountSQL+" >= ?", *query.MinNeches)
+	}
+	if query.MaxNeches != nil {
+		db = db.Where(necheCountSQL+" <= ?", *query.MaxNeches)
+	}
+
+	// The count and the page share the filters
+	db = db.Session(&gorm.Session{})
+	var total int64
+	result := db.Count(&total)
 	if result.Error != nil {
-		return result.Error
+		return nil, 0, result.Error
 	}
 
-	return nil
+	var columns []keysetColumn
+	for _, sort := range query.Sort {
+		column, ok := tagsSortColumns[sort.Field]
+		if !ok {
+			return nil, 0, fmt.Errorf("unknown tags sort field %q", sort.Field)
+		}
+		column.desc = sort.Desc
+		columns = append(columns, column)
+	}
+	columns = append(columns, keysetColumn{expr: "tags.id"})
+
+	db, err := applyPage(db.Preload("Neches.Tags", orderById("tags")), columns, query.PageQuery)
+	if err != nil {
+		return nil, 0, err
+	}
+
+	var tags []model.Tags
+	result = db.Find(&tags)
+	if result.Error != nil {
+		return nil, 0, result.Error
+	}
+	if query.Keyset != nil && query.Keyset.Backward {
+		slices.Reverse(tags)
+	}
+	return tags, total, nil
 }
 
-// // Repository method for paginated retrieval
-// func (r *TagsRepositoryImpl) FindPaginated(limit int, offset int) []model.Tags {
-// 	var tags []model.Tags
-// 	// Query to fetch paginated tags
-// 	r.Db.Limit(limit).Offset(offset).Find(&tags)
-// 	return tags
-// }
-
-// // Repository method for sorted retrieval
-// func (r *TagsRepositoryImpl) FindAllSorted(sortBy string, order string) []model.Tags {
-// 	var tags []model.Tags
-// 	// Dynamic query to sort by a specific field
-// 	r.Db.Order(fmt.Sprintf("%s %s", sortBy, order)).Find(&tags)
-// 	return tags
-// }
-
-// func (r *TagsRepositoryImpl) FindByCustomFilter(startsWith string) []model.Tags {
-// 	var tags []model.Tags
-
-// 	err := r.Db.Raw("SELECT * FROM tags WHERE name LIKE ?", startsWith+"%").Scan(&tags).Error
-// 	if err != nil {
-// 		return []model.Tags{}
-// 	}
-
-// 	return tags
-// }
+// escapeLike makes LIKE wildcards in user input match literally
+func escapeLike(value string) string {
+	return strings.NewReplacer(`\`, `\\`, "%", `\%`, "_", `\_`).Replace(value)
+}
@@ -13,8 +13,8 @@ func NewUsersRepository(db *gorm.DB) *UsersRepository {
 	return &UsersRepository{Db: db}
 }
 
-func (repo *UsersRepository) Save(user model.Users) error {
-	result := repo.Db.Create(&user)
+func (repo *UsersRepository) Save(user *model.Users) error {
+	result := repo.Db.Create(user)
 	if result.Error != nil {
 		return result.Error
 	}
@@ -34,3 +34,24 @@ func (repo *UsersRepository) FindByEmail(email string) (*model.Users, error) {
 	return &user, nil // User found, return the user
 }
 
+// FindById finds a user by their id
+func (repo *UsersRepository) FindById(id int) (*model.Users, error) {
+	var user model.Users
+	result := repo.Db.First(&user, id)
+	if result.Error != nil {
+		if result.Error == gorm.ErrRecordNotFound {
+			return nil, nil // No user found, return nil without error
+		}
+		return nil, result.Error
+	}
+	return &user, nil
+}
+
+// UpdateRole changes the role of a user, it returns false when the user doesn't exist
+func (repo *UsersRepository) UpdateRole(id int, role string) (bool, error) {
+	result := repo.Db.Model(&model.Users{}).Where("id = ?", id).Update("role", role)
+	if result.Error != nil {
+		return false, result.Error
+	}
+	return result.RowsAffected > 0, nil
+}
@@ -0,0 +1,59 @@
+package repository
+
+import (
+	"example.com/go-project/helper"
+	"gorm.io/gorm"
+)
+
+// ErrStaleVersion is returned when a row was changed since the caller read the version it names
+var ErrStaleVersion = helper.PreconditionFailed("the version doesn't match, it was changed in the meantime")
+
+// updateVersioned updates the row with the id if it still has the version and
+// increments the version, in one statement so concurrent updates can't both
+// win. Version 0 matches any version.
+func updateVersioned(db *gorm.DB, value interface{}, id int, version int, updates map[string]interface{}) error {
+	query := db.Model(value).Where("id = ?", id)
+	if version != 0 {
+		query = query.Where("version = ?", version)
+	}
+
+	updates["version"] = gorm.Expr("version + 1")
+	result := query.Updates(updates)
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		return missingOrStale(db, value, id)
+	}
+	return nil
+}
+
+// deleteVersioned deletes the row with the id if it still has the version, 0 matches any version
+func deleteVersioned(db *gorm.DB, value interface{}, id int, version int) error {
+	query := db.Where("id = ?", id)
+	if version != 0 {
+		query = query.Where("version = ?", version)
+	}
+
+	result := query.Delete(value)
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		return missingOrStale(db, value, id)
+	}
+	return nil
+}
+
+// missingOrStale tells why no row was changed
+func missingOrStale(db *gorm.DB, value interface{}, id int) error {
+	var count int64
+	err := db.Model(value).Where("id = ?", id).Count(&count).Error
+	if err != nil {
+		return err
+	}
+	if count == 0 {
+		return gorm.ErrRecordNotFound
+	}
+	return ErrStaleVersion
+}
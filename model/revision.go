@@ -0,0 +1,45 @@
+package model
+
+import "time"
+
+// Kinds of entities revisions are recorded for
+const (
+	RevisionTag   = "tag"
+	RevisionNeche = "neche"
+)
+
+// What a revision did to its entity
+const (
+	ActionCreate  = "create"
+	ActionUpdate  = "update"
+	ActionDelete  = "delete"
+	ActionRestore = "restore"
+	ActionRevert  = "revert"
+)
+
+// Revision records one change to a tag or a neche. Revisions are only ever
+// added, they outlive the entity they describe.
+type Revision struct {
+	Id         int       `gorm:"primary_key;autoIncrement"`
+	EntityType string    `gorm:"type:varchar(20);not null;index:idx_revisions_entity,priority:1"`
+	EntityId   int       `gorm:"not null;index:idx_revisions_entity,priority:2"`
+	Action     string    `gorm:"type:varchar(20);not null"`
+	ActorId    *int      // user_id of the caller, nil for changes nobody asked for
+	Before     *string   `gorm:"type:text"` // JSON snapshot, nil when the entity didn't exist or was in the trash
+	After      *string   `gorm:"type:text"` // JSON snapshot, nil when the entity was deleted
+	CreatedAt  time.Time `gorm:"not null"`
+}
+
+// TagSnapshot is the state of a tag a revision records
+type TagSnapshot struct {
+	Name     string `json:"name"`
+	ParentId *int   `json:"parentId"`
+	Version  int    `json:"version"`
+}
+
+// NecheSnapshot is the state of a neche a revision records, its tags outside the trash
+type NecheSnapshot struct {
+	Name    string `json:"name"`
+	TagIds  []int  `json:"tagIds"`
+	Version int    `json:"version"`
+}
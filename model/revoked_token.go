@@ -0,0 +1,17 @@
+package model
+
+import "time"
+
+// RevokedToken is an access token that was revoked before it expired
+type RevokedToken struct {
+	Jti       string    `gorm:"type:varchar(64);primary_key"`
+	UserId    int       `gorm:"not null;index"`
+	ExpiresAt time.Time `gorm:"not null;index"`
+}
+
+// UserRevocation invalidates every access token issued to the user before RevokedAt
+type UserRevocation struct {
+	UserId    int       `gorm:"primary_key;autoIncrement:false"`
+	RevokedAt time.Time `gorm:"not null"`
+	ExpiresAt time.Time `gorm:"not null;index"`
+}
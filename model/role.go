@@ -0,0 +1,22 @@
+package model
+
+// Role is a named set of permissions, users reference it through Users.Role
+type Role struct {
+	Id          int          `gorm:"primary_key;autoIncrement"`
+	Name        string       `gorm:"type:varchar(100);not null;uniqueIndex"`
+	Description string       `gorm:"type:varchar(255);not null;default:''"`
+	Permissions []Permission `gorm:"many2many:role_permissions;constraint:OnDelete:CASCADE;"`
+}
+
+// Permission is a single action on a resource, written as "resource:action"
+type Permission struct {
+	Id          int    `gorm:"primary_key;autoIncrement"`
+	Name        string `gorm:"type:varchar(100);not null;uniqueIndex"`
+	Description string `gorm:"type:varchar(255);not null;default:''"`
+}
+
+// RolePermission grants a permission to a role
+type RolePermission struct {
+	RoleId       int `gorm:"primary_key;autoIncrement:false"`
+	PermissionId int `gorm:"primary_key;autoIncrement:false"`
+}
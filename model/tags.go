@@ -1,7 +1,13 @@
 package model
 
+import "gorm.io/gorm"
+
 type Tags struct {
-	Id     int     `gorm:"primary_key;autoIncrement"`
-	Name   string  `gorm:"type:varchar(255);not null"`
-	Neches []Neche `gorm:"foreignKey:TagID;constraint:OnDelete:CASCADE;"`
+	Id        int            `gorm:"primary_key;autoIncrement"`
+	Name      string         `gorm:"type:varchar(255);not null"`
+	ParentId  *int           `gorm:"index"` // nil for a root tag
+	Children  []Tags         `gorm:"foreignKey:ParentId"`
+	Neches    []Neche        `gorm:"many2many:neche_tags;joinForeignKey:TagId;joinReferences:NecheId;constraint:OnDelete:CASCADE;"`
+	Version   int            `gorm:"not null;default:1"` // Incremented by every update
+	DeletedAt gorm.DeletedAt `gorm:"index"`              // Set while the tag is in the trash
 }
@@ -1,9 +1,15 @@
 package model
 
+// Built-in roles, new accounts always start as RoleUser
+const (
+	RoleUser  = "User"
+	RoleAdmin = "Admin"
+)
+
 type Users struct {
 	Id       int    `gorm:"primary_key;autoIncrement"`
 	Name     string `gorm:"type:varchar(255);not null"`
-	Email    string `gorm:"type:varchar(255);not null"`
-	Password string `gorm:"type:varchar(255);not null"`
+	Email    string `gorm:"type:varchar(255);not null;uniqueIndex:idx_users_email"`
+	Password string `gorm:"type:varchar(255);not null" json:"-"`
 	Role     string `gorm:"type:varchar(255);not null"`
 }
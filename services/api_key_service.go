@@ -0,0 +1,105 @@
+package services
+
+import (
+	"strings"
+	"time"
+
+	"example.com/go-project/data/request"
+	"example.com/go-project/helper"
+	"example.com/go-project/model"
+	"example.com/go-project/model/repository"
+	"github.com/go-playground/validator"
+)
+
+// ApiKeyPrefix starts every key so leaked keys are easy to spot
+const ApiKeyPrefix = "gp_"
+
+var (
+	ErrInvalidApiKey  = helper.Unauthorized("invalid api key")
+	ErrApiKeyNotFound = helper.NotFound("api key not found")
+)
+
+type ApiKeyService struct {
+	keysRepo  *repository.ApiKeyRepository
+	usersRepo *repository.UsersRepository
+	validate  *validator.Validate
+}
+
+func NewApiKeyService(keysRepo *repository.ApiKeyRepository, usersRepo *repository.UsersRepository, validate *validator.Validate) *ApiKeyService {
+	return &ApiKeyService{keysRepo: keysRepo, usersRepo: usersRepo, validate: validate}
+}
+
+// Create generates a key for the user and returns the raw key, only its hash is stored
+func (service *ApiKeyService) Create(userId int, keyReq request.CreateApiKeyRequest) (string, *model.ApiKey, error) {
+	err := service.validate.Struct(keyReq)
+	if err != nil {
+		return "", nil, err
+	}
+	if keyReq.ExpiresAt != nil && keyReq.ExpiresAt.Before(time.Now()) {
+		return "", nil, helper.Validation("expiresAt must be in the future")
+	}
+
+	secret, err := randomString(32)
+	if err != nil {
+		return "", nil, err
+	}
+	raw := ApiKeyPrefix + secret
+
+	key := &model.ApiKey{
+		UserId:    userId,
+		Name:      strings.TrimSpace(keyReq.Name),
+		Prefix:    raw[:len(ApiKeyPrefix)+6],
+		KeyHash:   hashToken(raw),
+		Scopes:    strings.Join(keyReq.Scopes, ","),
+		ExpiresAt: keyReq.ExpiresAt,
+		CreatedAt: time.Now(),
+	}
+	if err := service.keysRepo.Save(key); err != nil {
+		return "", nil, err
+	}
+	return raw, key, nil
+}
+
+// Authenticate finds the user a raw key belongs to
+func (service *ApiKeyService) Authenticate(raw string) (*model.ApiKey, *model.Users, error) {
+	if !strings.HasPrefix(raw, ApiKeyPrefix) {
+		return nil, nil, ErrInvalidApiKey
+	}
+
+	key, err := service.keysRepo.FindByHash(hashToken(raw))
+	if err != nil {
+		return nil, nil, err
+	}
+	now := time.Now()
+	if key == nil || key.RevokedAt != nil || (key.ExpiresAt != nil && now.After(*key.ExpiresAt)) {
+		return nil, nil, ErrInvalidApiKey
+	}
+
+	user, err := service.usersRepo.FindById(key.UserId)
+	if err != nil {
+		return nil, nil, err
+	}
+	if user == nil {
+		return nil, nil, ErrInvalidApiKey
+	}
+
+	if err := service.keysRepo.Touch(key.Id, now); err != nil {
+		return nil, nil, err
+	}
+	return key, user, nil
+}
+
+func (service *ApiKeyService) FindByUser(userId int) ([]model.ApiKey, error) {
+	return service.keysRepo.FindByUser(userId)
+}
+
+func (service *ApiKeyService) Revoke(id int, userId int) error {
+	found, err := service.keysRepo.Revoke(id, userId)
+	if err != nil {
+		return err
+	}
+	if !found {
+		return ErrApiKeyNotFound
+	}
+	return nil
+}
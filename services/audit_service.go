@@ -0,0 +1,139 @@
+package services
+
+import (
+	"encoding/json"
+	"io"
+	"net/url"
+	"strconv"
+	"time"
+	"unicode/utf8"
+
+	"example.com/go-project/data/request"
+	"example.com/go-project/data/response"
+	"example.com/go-project/helper"
+	"example.com/go-project/model"
+	"example.com/go-project/model/repository"
+	"github.com/go-playground/validator"
+)
+
+// auditExportBatch is how many events the export reads at a time
+const auditExportBatch = 500
+
+type AuditService struct {
+	auditRepo *repository.AuditRepository
+	validate  *validator.Validate
+}
+
+func NewAuditService(repo *repository.AuditRepository, validate *validator.Validate) *AuditService {
+	return &AuditService{auditRepo: repo, validate: validate}
+}
+
+// Record adds the event to the audit log, values too long for their column are cut
+func (service *AuditService) Record(event model.AuditEvent) error {
+	event.Email = truncate(event.Email, 255)
+	event.Ip = truncate(event.Ip, 45)
+	event.UserAgent = truncate(event.UserAgent, 512)
+	event.TargetId = truncate(event.TargetId, 255)
+	event.Detail = truncate(event.Detail, 512)
+	if event.CreatedAt.IsZero() {
+		event.CreatedAt = time.Now()
+	}
+	return service.auditRepo.Save(&event)
+}
+
+// Find returns a page of the events matching the filters, newest first
+func (service *AuditService) Find(queryReq request.AuditQueryRequest) (response.AuditEventsPage, error) {
+	query, err := service.query(queryReq)
+	if err != nil {
+		return response.AuditEventsPage{}, err
+	}
+	// A cursor only pages through the events of the filters it was made with
+	p, err := newPager(queryReq.PageRequest, auditScope(query))
+	if err != nil {
+		return response.AuditEventsPage{}, err
+	}
+
+	events, total, err := service.auditRepo.Find(query, p.query)
+	if err != nil {
+		return response.AuditEventsPage{}, err
+	}
+	start, end, cursors, err := p.page(len(events), func(i int) []interface{} {
+		return []interface{}{events[i].Id}
+	})
+	if err != nil {
+		return response.AuditEventsPage{}, err
+	}
+
+	eventResponses := []response.AuditEventResponse{}
+	for _, event := range events[start:end] {
+		eventResponses = append(eventResponses, response.NewAuditEventResponse(event))
+	}
+	return response.AuditEventsPage{Events: eventResponses, Total: total, Cursors: cursors}, nil
+}
+
+// Export writes every event matching the filters as JSON Lines, oldest
+// first. Nothing is written when the filters are invalid.
+func (service *AuditService) Export(queryReq request.AuditQueryRequest, w io.Writer) error {
+	query, err := service.query(queryReq)
+	if err != nil {
+		return err
+	}
+
+	encoder := json.NewEncoder(w)
+	return service.auditRepo.Each(query, auditExportBatch, func(events []model.AuditEvent) error {
+		for _, event := range events {
+			if err := encoder.Encode(response.NewAuditEventResponse(event)); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+}
+
+func (service *AuditService) query(queryReq request.AuditQueryRequest) (repository.AuditQuery, error) {
+	err := service.validate.Struct(queryReq)
+	if err != nil {
+		return repository.AuditQuery{}, err
+	}
+	if queryReq.From != nil && queryReq.To != nil && !queryReq.From.Before(*queryReq.To) {
+		return repository.AuditQuery{}, helper.Validation("from must be before to")
+	}
+	return repository.AuditQuery{
+		UserId: queryReq.UserId,
+		Action: queryReq.Action,
+		From:   queryReq.From,
+		To:     queryReq.To,
+	}, nil
+}
+
+// auditScope names a filtered listing for its cursors, e.g. "audit?action=auth.login"
+func auditScope(query repository.AuditQuery) string {
+	values := url.Values{}
+	if query.UserId != nil {
+		values.Set("userId", strconv.Itoa(*query.UserId))
+	}
+	if query.Action != "" {
+		values.Set("action", query.Action)
+	}
+	if query.From != nil {
+		values.Set("from", query.From.UTC().Format(time.RFC3339Nano))
+	}
+	if query.To != nil {
+		values.Set("to", query.To.UTC().Format(time.RFC3339Nano))
+	}
+	if len(values) == 0 {
+		return "audit"
+	}
+	return "audit?" + values.Encode()
+}
+
+// truncate cuts the value to at most n bytes without splitting a character
+func truncate(value string, n int) string {
+	if len(value) <= n {
+		return value
+	}
+	for n > 0 && !utf8.RuneStart(value[n]) {
+		n--
+	}
+	return value[:n]
+}
@@ -0,0 +1,168 @@
+package services
+
+import (
+	"log"
+	"time"
+
+	"example.com/go-project/config"
+	"example.com/go-project/helper"
+	"example.com/go-project/model"
+	"example.com/go-project/model/repository"
+)
+
+// ErrLoginThrottled is returned for the email of an account and for any other email alike
+var ErrLoginThrottled = helper.TooManyRequests("too many failed logins, try again later")
+
+// LoginThrottle counts failed logins per account and per client address.
+// Every failure doubles the wait before the next attempt and too many of
+// them lock the account or the address for a while. Emails are counted
+// whether or not they belong to an account, so the answers are the same.
+type LoginThrottle struct {
+	repo *repository.LoginAttemptRepository
+	cfg  config.LoginConfig
+}
+
+func NewLoginThrottle(repo *repository.LoginAttemptRepository, cfg config.LoginConfig) *LoginThrottle {
+	return &LoginThrottle{repo: repo, cfg: cfg}
+}
+
+// Check returns how long the client has to wait before it may try to log in
+// as the email, zero when it may try now
+func (t *LoginThrottle) Check(email string, ip string, now time.Time) (time.Duration, error) {
+	attempts, err := t.repo.Find(accountKey(email), ipKey(ip))
+	if err != nil {
+		return 0, err
+	}
+
+	var wait time.Duration
+	for _, attempt := range attempts {
+		wait = max(wait, t.wait(attempt, now))
+	}
+	return wait, nil
+}
+
+// Reserve counts a login as the email from the address before its password
+// is checked and returns how long the client has to wait first, zero when the
+// password may be checked now. Counting first keeps concurrent guesses from
+// passing the limit together. A reserved login ends with Failed, Succeeded or
+// Release.
+func (t *LoginThrottle) Reserve(email string, ip string, now time.Time) (time.Duration, error) {
+	wait, err := t.Check(email, ip, now)
+	if err != nil || wait > 0 {
+		return wait, err
+	}
+
+	wait, err = t.reserve(accountKey(email), t.cfg.MaxFailures, now)
+	if err != nil || wait > 0 {
+		return wait, err
+	}
+	wait, err = t.reserve(ipKey(ip), t.cfg.MaxIPFailures, now)
+	if err != nil || wait > 0 {
+		// The account isn't tried either
+		if releaseErr := t.repo.Release(accountKey(email)); err == nil {
+			err = releaseErr
+		}
+		return wait, err
+	}
+	return 0, nil
+}
+
+// Failed records that the password of a reserved login was wrong, the failure
+// that reaches the limit locks the account or the address
+func (t *LoginThrottle) Failed(email string, ip string, now time.Time) error {
+	lockedUntil := now.Add(t.cfg.LockoutDuration)
+	if err := t.repo.Lock(accountKey(email), t.cfg.MaxFailures, lockedUntil); err != nil {
+		return err
+	}
+	return t.repo.Lock(ipKey(ip), t.cfg.MaxIPFailures, lockedUntil)
+}
+
+// Succeeded forgets the failed logins of the account after a reserved login.
+// The failures of the address are kept, logging in to one account mustn't
+// allow guessing the passwords of others, only the login is taken back.
+func (t *LoginThrottle) Succeeded(email string, ip string) error {
+	if err := t.Reset(email); err != nil {
+		return err
+	}
+	return t.repo.Release(ipKey(ip))
+}
+
+// Release takes back a reserved login whose password couldn't be checked
+func (t *LoginThrottle) Release(email string, ip string) error {
+	if err := t.repo.Release(accountKey(email)); err != nil {
+		return err
+	}
+	return t.repo.Release(ipKey(ip))
+}
+
+// Reset forgets the failed logins of the account, when an admin unlocks it
+func (t *LoginThrottle) Reset(email string) error {
+	return t.repo.Delete(accountKey(email))
+}
+
+// ResetAddress forgets the failed logins from the address, when an admin unlocks it
+func (t *LoginThrottle) ResetAddress(ip string) error {
+	return t.repo.Delete(ipKey(ip))
+}
+
+// Prune removes the failures that are forgotten anyway
+func (t *LoginThrottle) Prune(now time.Time) (int64, error) {
+	return t.repo.Prune(now.Add(-t.cfg.Window))
+}
+
+// reserve counts an attempt for the key and takes it back when the key may
+// not be tried now. Guesses that passed Check together are let through up to
+// the limit, the others wait for the lockout the last of them may cause.
+func (t *LoginThrottle) reserve(key string, limit int, now time.Time) (time.Duration, error) {
+	attempt, err := t.repo.Reserve(key, now, t.cfg.Window)
+	if err != nil {
+		return 0, err
+	}
+
+	var wait time.Duration
+	if attempt.LockedUntil != nil {
+		wait = attempt.LockedUntil.Sub(now)
+	} else if attempt.Failures > limit {
+		wait = t.cfg.LockoutDuration
+	}
+	if wait > 0 {
+		return wait, t.repo.Release(key)
+	}
+	return 0, nil
+}
+
+func (t *LoginThrottle) wait(attempt model.LoginAttempt, now time.Time) time.Duration {
+	if attempt.LockedUntil != nil {
+		return max(attempt.LockedUntil.Sub(now), 0)
+	}
+	if attempt.Failures == 0 || now.Sub(attempt.LastFailureAt) >= t.cfg.Window {
+		return 0
+	}
+	return max(attempt.LastFailureAt.Add(t.backoff(attempt.Failures)).Sub(now), 0)
+}
+
+// backoff is the wait after the given number of failures
+func (t *LoginThrottle) backoff(failures int) time.Duration {
+	wait := t.cfg.BackoffBase
+	for i := 1; i < failures && wait < t.cfg.BackoffMax; i++ {
+		wait *= 2
+	}
+	return min(wait, t.cfg.BackoffMax)
+}
+
+func accountKey(email string) string {
+	return truncate("account:"+NormalizeEmail(email), 300)
+}
+
+func ipKey(ip string) string {
+	return "ip:" + ip
+}
+
+// StartLoginAttemptPruning periodically removes the failed logins that are forgotten
+func StartLoginAttemptPruning(throttle *LoginThrottle, interval time.Duration) (stop func()) {
+	return helper.StartPeriodic(interval, func(now time.Time) {
+		if _, err := throttle.Prune(now); err != nil {
+			log.Println("Error pruning failed logins:", err)
+		}
+	})
+}
@@ -2,12 +2,17 @@ package services
 
 import (
 	"example.com/go-project/data/request"
-	"example.com/go-project/model"
+	"example.com/go-project/data/response"
 )
 
 type NecheService interface {
-	Create(neche request.CreateNecheRequest) error
-	FindAll() ([]model.Neche, error)
-	FindById(id int) (*model.Neche, error)
-	Delete(id int) error
-}
\ No newline at end of file
+	Create(actorId int, neche request.CreateNecheRequest) (response.NecheResponse, error)
+	Update(actorId int, neche request.UpdateNecheRequest) (response.NecheResponse, error)
+	Patch(actorId int, patch request.PatchRequest) (response.NecheResponse, error)
+	FindAll(page request.PageRequest) (response.NechesPage, error)
+	FindById(id int) (response.NecheResponse, error)
+	FindByTagId(tagId int, descendants bool, page request.PageRequest) (response.NechesPage, error)
+	Attach(actorId int, tagId int, attach request.AttachNechesRequest) error
+	Detach(actorId int, tagId int, necheId int) error
+	Delete(actorId int, id int, version int) error
+}
@@ -1,11 +1,24 @@
 package services
 
 import (
+	"errors"
 	"fmt"
+	"slices"
+
 	"example.com/go-project/data/request"
+	"example.com/go-project/data/response"
+	"example.com/go-project/helper"
 	"example.com/go-project/model"
 	"example.com/go-project/model/repository"
 	"github.com/go-playground/validator"
+	"gorm.io/gorm"
+)
+
+var (
+	ErrNecheNotFound = helper.NotFound("neche not found")
+	ErrTagNotFound   = helper.NotFound("tag not found")
+
+	ErrNecheNotAttached = helper.NotFound("neche isn't attached to the tag")
 )
 
 type NecheServiceImpl struct {
@@ -22,37 +35,278 @@ func NewNecheServiceImpl(necheRepository repository.NecheRepository, validate *v
 	}
 }
 
-// Create Neche
-func (n *NecheServiceImpl) Create(necheReq request.CreateNecheRequest) error {
+// Create Neche, its Tags have to exist
+func (n *NecheServiceImpl) Create(actorId int, necheReq request.CreateNecheRequest) (response.NecheResponse, error) {
+	necheReq.TagIDs = legacyTagIds(necheReq.TagIDs, necheReq.TagID)
 	err := n.validate.Struct(necheReq)
 	if err != nil {
-		return fmt.Errorf("validation failed: %w", err)
+		return response.NecheResponse{}, err
 	}
 
-	tag, err := n.TagsRepository.FindById(necheReq.TagID)
+	tags, err := n.findTags(necheReq.TagIDs)
 	if err != nil {
-		return fmt.Errorf("tag with ID %d not found", necheReq.TagID)
+		return response.NecheResponse{}, err
 	}
 
 	neche := model.Neche{
-		TagID:     tag.Id,
 		NecheType: necheReq.Name,
+		Tags:      tags,
+	}
+
+	err = n.NecheRepository.Save(actorId, &neche)
+	if err != nil {
+		return response.NecheResponse{}, err
+	}
+	return n.FindById(neche.Id)
+}
+
+// Update Neche, the new Tags have to exist
+func (n *NecheServiceImpl) Update(actorId int, necheReq request.UpdateNecheRequest) (response.NecheResponse, error) {
+	necheReq.TagIDs = legacyTagIds(necheReq.TagIDs, necheReq.TagID)
+	err := n.validate.Struct(necheReq)
+	if err != nil {
+		return response.NecheResponse{}, err
+	}
+
+	neche, err := n.NecheRepository.FindById(necheReq.Id)
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return response.NecheResponse{}, ErrNecheNotFound
+	}
+	if err != nil {
+		return response.NecheResponse{}, err
+	}
+
+	// The client edited an older version than the stored one
+	if necheReq.Version != 0 && necheReq.Version != neche.Version {
+		return response.NecheResponse{}, repository.ErrStaleVersion
+	}
+
+	tags, err := n.findTags(necheReq.TagIDs)
+	if err != nil {
+		return response.NecheResponse{}, err
+	}
+
+	neche.NecheType = necheReq.Name
+	neche.Tags = tags
+	err = n.NecheRepository.Update(actorId, *neche)
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return response.NecheResponse{}, ErrNecheNotFound
+	}
+	if err != nil {
+		return response.NecheResponse{}, err
+	}
+	return n.FindById(neche.Id)
+}
+
+// Patch Neche, the patched Neche is validated like a new one
+func (n *NecheServiceImpl) Patch(actorId int, patch request.PatchRequest) (response.NecheResponse, error) {
+	neche, err := n.NecheRepository.FindById(patch.Id)
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return response.NecheResponse{}, ErrNecheNotFound
+	}
+	if err != nil {
+		return response.NecheResponse{}, err
+	}
+
+	// The patch was written against the version the client read
+	if patch.Version != 0 && patch.Version != neche.Version {
+		return response.NecheResponse{}, repository.ErrStaleVersion
+	}
+
+	// The document is the neche as it's read, with the first tag as tagId
+	document := request.CreateNecheRequest{Name: neche.NecheType, TagIDs: tagIds(neche.Tags)}
+	if len(document.TagIDs) > 0 {
+		document.TagID = document.TagIDs[0]
+	}
+	patched := request.CreateNecheRequest{}
+	err = patchFields(patch, document, &patched)
+	if err != nil {
+		return response.NecheResponse{}, err
+	}
+	// Older clients patch tagId alone, it replaces the tags like on create
+	if patched.TagID != 0 && patched.TagID != document.TagID && slices.Equal(patched.TagIDs, document.TagIDs) {
+		patched.TagIDs = []int{patched.TagID}
+	}
+	err = n.validate.Struct(patched)
+	if err != nil {
+		return response.NecheResponse{}, err
 	}
 
-	return n.NecheRepository.Save(neche)
+	// Only save over the version the patch was applied to
+	return n.Update(actorId, request.UpdateNecheRequest{Id: neche.Id, Name: patched.Name, TagIDs: patched.TagIDs, Version: neche.Version})
 }
 
-// Find all Neches
-func (n *NecheServiceImpl) FindAll() ([]model.Neche, error) {
-	return n.NecheRepository.FindAll()
+// Attach Neches to a Tag, the Tag and every Neche have to exist
+func (n *NecheServiceImpl) Attach(actorId int, tagId int, attachReq request.AttachNechesRequest) error {
+	err := n.validate.Struct(attachReq)
+	if err != nil {
+		return err
+	}
+
+	if _, err := n.findTags([]int{tagId}); err != nil {
+		return err
+	}
+	missing, err := n.NecheRepository.MissingIds(attachReq.NecheIDs)
+	if err != nil {
+		return err
+	}
+	if len(missing) > 0 {
+		return idsNotFound("neche", missing, ErrNecheNotFound)
+	}
+
+	return n.NecheRepository.Attach(actorId, tagId, attachReq.NecheIDs)
+}
+
+// Detach a Neche from a Tag
+func (n *NecheServiceImpl) Detach(actorId int, tagId int, necheId int) error {
+	err := n.NecheRepository.Detach(actorId, tagId, necheId)
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return ErrNecheNotAttached
+	}
+	return err
+}
+
+// findTags returns the Tags with the ids, every one of them has to exist
+func (n *NecheServiceImpl) findTags(ids []int) ([]model.Tags, error) {
+	missing, err := n.TagsRepository.MissingIds(ids)
+	if err != nil {
+		return nil, err
+	}
+	if len(missing) > 0 {
+		return nil, idsNotFound("tag", missing, ErrTagNotFound)
+	}
+
+	var tags []model.Tags
+	for _, id := range ids {
+		if !slices.ContainsFunc(tags, func(tag model.Tags) bool { return tag.Id == id }) {
+			tags = append(tags, model.Tags{Id: id})
+		}
+	}
+	return tags, nil
+}
+
+// idsNotFound names the ids that weren't found, e.g. "tags with IDs [3 4]: tag not found"
+func idsNotFound(noun string, ids []int, err error) error {
+	if len(ids) == 1 {
+		return fmt.Errorf("%s with ID %d: %w", noun, ids[0], err)
+	}
+	return fmt.Errorf("%ss with IDs %v: %w", noun, ids, err)
+}
+
+// Find all Neches one page at a time, with the total number of Neches
+func (n *NecheServiceImpl) FindAll(page request.PageRequest) (response.NechesPage, error) {
+	p, err := newPager(page, "neches")
+	if err != nil {
+		return response.NechesPage{}, err
+	}
+
+	neches, total, err := n.NecheRepository.FindAll(p.query)
+	if err != nil {
+		return response.NechesPage{}, err
+	}
+	return newNechesPage(p, neches, total)
 }
 
 // Find Neche by ID
-func (n *NecheServiceImpl) FindById(id int) (*model.Neche, error) {
-	return n.NecheRepository.FindById(id)
+func (n *NecheServiceImpl) FindById(id int) (response.NecheResponse, error) {
+	neche, err := n.NecheRepository.FindById(id)
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return response.NecheResponse{}, ErrNecheNotFound
+	}
+	if err != nil {
+		return response.NecheResponse{}, err
+	}
+	return newNecheResponse(*neche), nil
 }
 
-// Delete Neche by ID
-func (n *NecheServiceImpl) Delete(id int) error {
-	return n.NecheRepository.Delete(id)
+// Find the Neches of a Tag, with those of its descendants too when asked to.
+// The Tag has to exist.
+func (n *NecheServiceImpl) FindByTagId(tagId int, descendants bool, page request.PageRequest) (response.NechesPage, error) {
+	_, err := n.TagsRepository.FindById(tagId)
+	if errors.Is(err, helper.ErrNotFound) {
+		return response.NechesPage{}, fmt.Errorf("tag with ID %d: %w", tagId, ErrTagNotFound)
+	}
+	if err != nil {
+		return response.NechesPage{}, err
+	}
+
+	// A cursor of one listing doesn't page through the other
+	scope := fmt.Sprintf("tags/%d/neches", tagId)
+	if descendants {
+		scope += "?includeDescendants=true"
+	}
+	p, err := newPager(page, scope)
+	if err != nil {
+		return response.NechesPage{}, err
+	}
+
+	neches, total, err := n.NecheRepository.FindByTagId(tagId, descendants, p.query)
+	if err != nil {
+		return response.NechesPage{}, err
+	}
+	return newNechesPage(p, neches, total)
+}
+
+// Delete Neche by ID if it still has the version, 0 deletes any version
+func (n *NecheServiceImpl) Delete(actorId int, id int, version int) error {
+	err := n.NecheRepository.Delete(actorId, id, version)
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return ErrNecheNotFound
+	}
+	return err
+}
+
+func newNecheResponse(neche model.Neche) response.NecheResponse {
+	necheResponse := response.NecheResponse{
+		Id:      neche.Id,
+		Name:    neche.NecheType,
+		Tags:    newNecheTagResponses(neche.Tags),
+		Version: neche.Version,
+	}
+	if len(neche.Tags) > 0 {
+		necheResponse.TagId = neche.Tags[0].Id
+	}
+	return necheResponse
+}
+
+func newNecheTagResponses(tags []model.Tags) []response.NecheTagResponse {
+	tagResponses := []response.NecheTagResponse{}
+	for _, tag := range tags {
+		tagResponses = append(tagResponses, response.NecheTagResponse{Id: tag.Id, Name: tag.Name})
+	}
+	return tagResponses
+}
+
+// legacyTagIds reads the single tagId of older clients as a list of one tag
+func legacyTagIds(tagIds []int, tagId int) []int {
+	if len(tagIds) == 0 && tagId != 0 {
+		return []int{tagId}
+	}
+	return tagIds
+}
+
+func tagIds(tags []model.Tags) []int {
+	ids := []int{}
+	for _, tag := range tags {
+		ids = append(ids, tag.Id)
+	}
+	return ids
+}
+
+func newNechesPage(p *pager, neches []model.Neche, total int64) (response.NechesPage, error) {
+	start, end, cursors, err := p.page(len(neches), func(i int) []interface{} {
+		return []interface{}{neches[i].Id}
+	})
+	if err != nil {
+		return response.NechesPage{}, err
+	}
+	return response.NechesPage{Neches: newNecheResponses(neches[start:end]), Total: total, Cursors: cursors}, nil
+}
+
+func newNecheResponses(neches []model.Neche) []response.NecheResponse {
+	necheResponses := []response.NecheResponse{}
+	for _, neche := range neches {
+		necheResponses = append(necheResponses, newNecheResponse(neche))
+	}
+	return necheResponses
 }
@@ -0,0 +1,78 @@
+package services
+
+import (
+	"example.com/go-project/data/request"
+	"example.com/go-project/data/response"
+	"example.com/go-project/helper"
+	"example.com/go-project/model/repository"
+)
+
+// pager turns the page a client asked for into a repository query and the
+// rows it returns into a page with its cursors
+type pager struct {
+	query repository.PageQuery
+	scope string
+	limit int
+}
+
+// newPager decodes the cursor of the request, it must have been made for the scope
+func newPager(page request.PageRequest, scope string) (*pager, error) {
+	p := &pager{
+		query: repository.PageQuery{Limit: page.Limit, Offset: page.Offset},
+		scope: scope,
+		limit: page.Limit,
+	}
+
+	if page.Cursor != "" {
+		cursor, err := helper.DecodeCursor(page.Cursor, scope)
+		if err != nil {
+			return nil, err
+		}
+		p.query.Keyset = &repository.Keyset{Values: cursor.Values, Backward: cursor.Backward}
+	}
+
+	// One row more than asked for tells whether there is another page
+	if p.limit > 0 {
+		p.query.Limit = p.limit + 1
+	}
+	return p, nil
+}
+
+// page returns the range of the n fetched rows that are on the page, and the
+// cursors of the pages around it. keyset gives the position of the i-th row.
+func (p *pager) page(n int, keyset func(i int) []interface{}) (int, int, response.Cursors, error) {
+	start, end := 0, n
+	backward := p.query.Keyset != nil && p.query.Keyset.Backward
+	more := p.limit > 0 && n > p.limit
+	if more && backward {
+		start++
+	} else if more {
+		end--
+	}
+
+	var cursors response.Cursors
+	if start == end {
+		return start, end, cursors, nil
+	}
+
+	// A page reached with a cursor has rows on the side it came from
+	hasNext, hasPrev := more, p.query.Offset > 0
+	if p.query.Keyset != nil {
+		hasNext, hasPrev = more || backward, more || !backward
+	}
+
+	var err error
+	if hasNext {
+		cursors.Next, err = helper.EncodeCursor(helper.Cursor{Scope: p.scope, Values: keyset(end - 1)})
+		if err != nil {
+			return 0, 0, cursors, err
+		}
+	}
+	if hasPrev {
+		cursors.Prev, err = helper.EncodeCursor(helper.Cursor{Scope: p.scope, Values: keyset(start), Backward: true})
+		if err != nil {
+			return 0, 0, cursors, err
+		}
+	}
+	return start, end, cursors, nil
+}
@@ -0,0 +1,29 @@
+package services
+
+import (
+	"bytes"
+	"encoding/json"
+
+	"example.com/go-project/data/request"
+	"example.com/go-project/helper"
+)
+
+// patchFields applies the patch to the fields a client sets on create and
+// decodes the result into patched. Fields that aren't there can't be added.
+func patchFields(patch request.PatchRequest, fields interface{}, patched interface{}) error {
+	document, err := json.Marshal(fields)
+	if err != nil {
+		return err
+	}
+	result, err := helper.ApplyPatch(patch.MediaType, document, patch.Patch)
+	if err != nil {
+		return err
+	}
+
+	decoder := json.NewDecoder(bytes.NewReader(result))
+	decoder.DisallowUnknownFields()
+	if err := decoder.Decode(patched); err != nil {
+		return helper.Validation("the patched document is invalid: %s", err)
+	}
+	return nil
+}
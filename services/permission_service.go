@@ -0,0 +1,150 @@
+package services
+
+import (
+	"errors"
+	"strings"
+
+	"example.com/go-project/data/request"
+	"example.com/go-project/helper"
+	"example.com/go-project/model"
+	"example.com/go-project/model/repository"
+	"github.com/go-playground/validator"
+	"gorm.io/gorm"
+)
+
+var (
+	ErrRoleNotFound       = helper.NotFound("role not found")
+	ErrRoleExists         = helper.Conflict("role already exists")
+	ErrRoleInUse          = helper.Conflict("role is still assigned to users")
+	ErrPermissionNotFound = helper.NotFound("permission not found")
+	ErrPermissionExists   = helper.Conflict("permission already exists")
+)
+
+// PermissionService manages roles and what they are allowed to do
+type PermissionService struct {
+	permissionRepo *repository.PermissionRepository
+	validate       *validator.Validate
+}
+
+func NewPermissionService(repo *repository.PermissionRepository, validate *validator.Validate) *PermissionService {
+	return &PermissionService{permissionRepo: repo, validate: validate}
+}
+
+func (service *PermissionService) FindAllRoles() ([]model.Role, error) {
+	return service.permissionRepo.FindAllRoles()
+}
+
+func (service *PermissionService) FindRole(name string) (*model.Role, error) {
+	role, err := service.permissionRepo.FindRoleByName(name)
+	if err != nil {
+		return nil, err
+	}
+	if role == nil {
+		return nil, ErrRoleNotFound
+	}
+	return role, nil
+}
+
+func (service *PermissionService) CreateRole(roleReq request.CreateRoleRequest) (*model.Role, error) {
+	roleReq.Name = strings.TrimSpace(roleReq.Name)
+	err := service.validate.Struct(roleReq)
+	if err != nil {
+		return nil, err
+	}
+
+	role := &model.Role{Name: roleReq.Name, Description: roleReq.Description}
+	err = service.permissionRepo.SaveRole(role)
+	if errors.Is(err, gorm.ErrDuplicatedKey) {
+		return nil, ErrRoleExists
+	}
+	if err != nil {
+		return nil, err
+	}
+	return role, nil
+}
+
+// DeleteRole refuses to delete a role users still have, they would silently lose every permission
+func (service *PermissionService) DeleteRole(name string) error {
+	count, err := service.permissionRepo.CountUsersWithRole(name)
+	if err != nil {
+		return err
+	}
+	if count > 0 {
+		return ErrRoleInUse
+	}
+
+	found, err := service.permissionRepo.DeleteRole(name)
+	if err != nil {
+		return err
+	}
+	if !found {
+		return ErrRoleNotFound
+	}
+	return nil
+}
+
+func (service *PermissionService) FindAllPermissions() ([]model.Permission, error) {
+	return service.permissionRepo.FindAllPermissions()
+}
+
+func (service *PermissionService) CreatePermission(permissionReq request.CreatePermissionRequest) (*model.Permission, error) {
+	permissionReq.Name = strings.ToLower(strings.TrimSpace(permissionReq.Name))
+	err := service.validate.Struct(permissionReq)
+	if err != nil {
+		return nil, err
+	}
+
+	permission := &model.Permission{Name: permissionReq.Name, Description: permissionReq.Description}
+	err = service.permissionRepo.SavePermission(permission)
+	if errors.Is(err, gorm.ErrDuplicatedKey) {
+		return nil, ErrPermissionExists
+	}
+	if err != nil {
+		return nil, err
+	}
+	return permission, nil
+}
+
+func (service *PermissionService) DeletePermission(name string) error {
+	found, err := service.permissionRepo.DeletePermission(name)
+	if err != nil {
+		return err
+	}
+	if !found {
+		return ErrPermissionNotFound
+	}
+	return nil
+}
+
+// Grant gives the permission to the role
+func (service *PermissionService) Grant(roleName string, permissionName string) error {
+	role, permission, err := service.findGrant(roleName, permissionName)
+	if err != nil {
+		return err
+	}
+	return service.permissionRepo.Grant(role.Id, permission.Id)
+}
+
+// Revoke takes the permission away from the role
+func (service *PermissionService) Revoke(roleName string, permissionName string) error {
+	role, permission, err := service.findGrant(roleName, permissionName)
+	if err != nil {
+		return err
+	}
+	return service.permissionRepo.Revoke(role.Id, permission.Id)
+}
+
+func (service *PermissionService) findGrant(roleName string, permissionName string) (*model.Role, *model.Permission, error) {
+	role, err := service.FindRole(roleName)
+	if err != nil {
+		return nil, nil, err
+	}
+	permission, err := service.permissionRepo.FindPermissionByName(permissionName)
+	if err != nil {
+		return nil, nil, err
+	}
+	if permission == nil {
+		return nil, nil, ErrPermissionNotFound
+	}
+	return role, permission, nil
+}
@@ -0,0 +1,16 @@
+package services
+
+import (
+	"example.com/go-project/data/request"
+	"example.com/go-project/data/response"
+)
+
+// RevisionService serves the history of tags and neches and reverts them to a previous revision
+type RevisionService interface {
+	FindTagHistory(tagId int, page request.PageRequest) (response.RevisionsPage, error)
+	FindNecheHistory(necheId int, page request.PageRequest) (response.RevisionsPage, error)
+	RevertTag(actorId int, revert request.RevertRequest) (response.TagsResponse, error)
+	RevertNeche(actorId int, revert request.RevertRequest) (response.NecheResponse, error)
+	TagVersion(tagId int) (int, error)
+	NecheVersion(necheId int) (int, error)
+}
@@ -0,0 +1,183 @@
+package services
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+
+	"example.com/go-project/data/request"
+	"example.com/go-project/data/response"
+	"example.com/go-project/helper"
+	"example.com/go-project/model"
+	"example.com/go-project/model/repository"
+	"gorm.io/gorm"
+)
+
+type RevisionServiceImpl struct {
+	RevisionRepository repository.RevisionRepository
+	TagsRepository     repository.TagsRepository
+	NecheRepository    repository.NecheRepository
+}
+
+func NewRevisionServiceImpl(revisionRepository repository.RevisionRepository, tagsRepository repository.TagsRepository, necheRepository repository.NecheRepository) RevisionService {
+	return &RevisionServiceImpl{
+		RevisionRepository: revisionRepository,
+		TagsRepository:     tagsRepository,
+		NecheRepository:    necheRepository,
+	}
+}
+
+// FindTagHistory implements RevisionService. A tag in the trash keeps its history.
+func (s *RevisionServiceImpl) FindTagHistory(tagId int, page request.PageRequest) (response.RevisionsPage, error) {
+	return s.findHistory(model.RevisionTag, tagId, page, func() error {
+		_, err := s.TagsRepository.FindById(tagId)
+		return err
+	})
+}
+
+// FindNecheHistory implements RevisionService. A neche in the trash keeps its history.
+func (s *RevisionServiceImpl) FindNecheHistory(necheId int, page request.PageRequest) (response.RevisionsPage, error) {
+	return s.findHistory(model.RevisionNeche, necheId, page, func() error {
+		_, err := s.NecheRepository.FindById(necheId)
+		return err
+	})
+}
+
+// findHistory pages through the revisions of the entity. An entity without
+// revisions has to exist, it may predate them.
+func (s *RevisionServiceImpl) findHistory(entityType string, entityId int, page request.PageRequest, exists func() error) (response.RevisionsPage, error) {
+	p, err := newPager(page, fmt.Sprintf("%ss/%d/history", entityType, entityId))
+	if err != nil {
+		return response.RevisionsPage{}, err
+	}
+
+	revisions, total, err := s.RevisionRepository.FindByEntity(entityType, entityId, p.query)
+	if err != nil {
+		return response.RevisionsPage{}, err
+	}
+	if total == 0 {
+		err := exists()
+		if errors.Is(err, gorm.ErrRecordNotFound) || errors.Is(err, helper.ErrNotFound) {
+			return response.RevisionsPage{}, helper.NotFound("%s with id %d not found", entityType, entityId)
+		}
+		if err != nil {
+			return response.RevisionsPage{}, err
+		}
+	}
+
+	start, end, cursors, err := p.page(len(revisions), func(i int) []interface{} {
+		return []interface{}{revisions[i].Id}
+	})
+	if err != nil {
+		return response.RevisionsPage{}, err
+	}
+	revisionResponses := []response.RevisionResponse{}
+	for _, revision := range revisions[start:end] {
+		revisionResponses = append(revisionResponses, newRevisionResponse(revision))
+	}
+	return response.RevisionsPage{Revisions: revisionResponses, Total: total, Cursors: cursors}, nil
+}
+
+// RevertTag implements RevisionService. The tag gets the name and the parent the
+// revision left it with, the revert is recorded as a revision of its own.
+func (s *RevisionServiceImpl) RevertTag(actorId int, revert request.RevertRequest) (response.TagsResponse, error) {
+	var to model.TagSnapshot
+	if err := s.findSnapshot(model.RevisionTag, revert, &to); err != nil {
+		return response.TagsResponse{}, err
+	}
+
+	err := s.TagsRepository.Revert(actorId, revert.Id, to, revert.Version)
+	if err != nil {
+		return response.TagsResponse{}, err
+	}
+	tag, err := s.TagsRepository.FindById(revert.Id)
+	if err != nil {
+		return response.TagsResponse{}, err
+	}
+	return response.TagsResponse{Id: tag.Id, Name: tag.Name, ParentId: tag.ParentId, Version: tag.Version}, nil
+}
+
+// RevertNeche implements RevisionService. The neche gets the name and the tags
+// the revision left it with, they have to exist.
+func (s *RevisionServiceImpl) RevertNeche(actorId int, revert request.RevertRequest) (response.NecheResponse, error) {
+	var to model.NecheSnapshot
+	if err := s.findSnapshot(model.RevisionNeche, revert, &to); err != nil {
+		return response.NecheResponse{}, err
+	}
+
+	missing, err := s.TagsRepository.MissingIds(to.TagIds)
+	if err != nil {
+		return response.NecheResponse{}, err
+	}
+	if len(missing) > 0 {
+		return response.NecheResponse{}, idsNotFound("tag", missing, ErrTagNotFound)
+	}
+
+	err = s.NecheRepository.Revert(actorId, revert.Id, to, revert.Version)
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return response.NecheResponse{}, ErrNecheNotFound
+	}
+	if err != nil {
+		return response.NecheResponse{}, err
+	}
+	neche, err := s.NecheRepository.FindById(revert.Id)
+	if err != nil {
+		return response.NecheResponse{}, err
+	}
+	return newNecheResponse(*neche), nil
+}
+
+// TagVersion implements RevisionService. It is the version a revert of the tag changes.
+func (s *RevisionServiceImpl) TagVersion(tagId int) (int, error) {
+	tag, err := s.TagsRepository.FindById(tagId)
+	return tag.Version, err
+}
+
+// NecheVersion implements RevisionService. It is the version a revert of the neche changes.
+func (s *RevisionServiceImpl) NecheVersion(necheId int) (int, error) {
+	neche, err := s.NecheRepository.FindById(necheId)
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return 0, ErrNecheNotFound
+	}
+	if err != nil {
+		return 0, err
+	}
+	return neche.Version, nil
+}
+
+// findSnapshot decodes the state the revision of the entity left it in. A
+// deletion leaves nothing to revert to, the trash restores it.
+func (s *RevisionServiceImpl) findSnapshot(entityType string, revert request.RevertRequest, snapshot interface{}) error {
+	revision, err := s.RevisionRepository.FindById(revert.RevisionId)
+	if errors.Is(err, gorm.ErrRecordNotFound) || (err == nil && (revision.EntityType != entityType || revision.EntityId != revert.Id)) {
+		return helper.NotFound("revision %d of %s %d not found", revert.RevisionId, entityType, revert.Id)
+	}
+	if err != nil {
+		return err
+	}
+	if revision.After == nil {
+		return helper.Conflict("revision %d deleted the %s, restore it from the trash instead", revision.Id, entityType)
+	}
+	return json.Unmarshal([]byte(*revision.After), snapshot)
+}
+
+func newRevisionResponse(revision model.Revision) response.RevisionResponse {
+	return response.RevisionResponse{
+		Id:         revision.Id,
+		EntityType: revision.EntityType,
+		EntityId:   revision.EntityId,
+		Action:     revision.Action,
+		ActorId:    revision.ActorId,
+		Before:     rawSnapshot(revision.Before),
+		After:      rawSnapshot(revision.After),
+		CreatedAt:  revision.CreatedAt,
+	}
+}
+
+// rawSnapshot passes the stored JSON through, a missing snapshot is null
+func rawSnapshot(snapshot *string) json.RawMessage {
+	if snapshot == nil {
+		return json.RawMessage("null")
+	}
+	return json.RawMessage(*snapshot)
+}
@@ -6,12 +6,14 @@ import (
 )
 
 type TagsService interface {
-	Create(tags request.CreteTagsRequest) error
-	Update(tags request.UpdateTagsRequest) error
-	Delete(tagId int) error
+	Create(actorId int, tags request.CreteTagsRequest) error
+	Update(actorId int, tags request.UpdateTagsRequest) (response.TagsResponse, error)
+	Patch(actorId int, patch request.PatchRequest) (response.TagsResponse, error)
+	Delete(actorId int, tagId int, version int) error
 	FindById(tagsId int) (response.TagsResponse, error)
 	FindAll(limit int, offset int) ([]response.TagsResponse, error)
-	// FindPaginated(page int, pageSize int) []response.TagsResponse
-	// FindAllSorted(sortBy string, order string) []response.TagsResponse
-	// FindByCustomFilter(startsWith string) []response.TagsResponse
+	Search(query request.SearchTagsRequest) (response.TagsPage, error)
+	Move(actorId int, move request.MoveTagRequest) (response.TagsResponse, error)
+	Subtree(tagId int) (response.TagTreeResponse, error)
+	Ancestors(tagId int) ([]response.BreadcrumbResponse, error)
 }
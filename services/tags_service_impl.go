@@ -2,12 +2,19 @@ package services
 
 import (
 	"errors"
+	"fmt"
+	"net/url"
+	"slices"
+	"strconv"
+	"strings"
 
 	"example.com/go-project/data/request"
 	"example.com/go-project/data/response"
+	"example.com/go-project/helper"
 	"example.com/go-project/model"
 	"example.com/go-project/model/repository"
 	"github.com/go-playground/validator"
+	"gorm.io/gorm"
 )
 
 type TagsServiceImpl struct {
@@ -23,20 +30,28 @@ func NewTagsServiceImpl(tagsRepository repository.TagsRepository, validate *vali
 }
 
 // Create implements TagsService.
-func (t *TagsServiceImpl) Create(tags request.CreteTagsRequest) error {
+func (t *TagsServiceImpl) Create(actorId int, tags request.CreteTagsRequest) error {
 	// Validate the incoming request
 	err := t.validate.Struct(tags)
 	if err != nil {
 		return err // Return validation error if any
 	}
 
+	// A child tag needs an existing parent
+	if tags.ParentId != nil {
+		if _, err := t.TagsRepository.FindById(*tags.ParentId); err != nil {
+			return t.parentNotFound(*tags.ParentId, err)
+		}
+	}
+
 	// Create the tag model
 	tagModel := model.Tags{
-		Name: tags.Name,
+		Name:     tags.Name,
+		ParentId: tags.ParentId,
 	}
 
 	// Save the tag model to the database
-	err = t.TagsRepository.Save(tagModel)
+	err = t.TagsRepository.Save(actorId, tagModel)
 	if err != nil {
 		return err // Return database save error if any
 	}
@@ -45,9 +60,9 @@ func (t *TagsServiceImpl) Create(tags request.CreteTagsRequest) error {
 }
 
 // Delete implements TagsService.
-func (t *TagsServiceImpl) Delete(tagId int) error {
+func (t *TagsServiceImpl) Delete(actorId int, tagId int, version int) error {
 	// Call the repository's Delete method and return its result
-	return t.TagsRepository.Delete(tagId) // Return the error if any
+	return t.TagsRepository.Delete(actorId, tagId, version) // Return the error if any
 }
 
 // FindAll implements TagsService.
@@ -58,118 +73,281 @@ func (t *TagsServiceImpl) FindAll(limit int, offset int) ([]response.TagsRespons
 		return nil, err // Return nil and the error if fetching fails
 	}
 
-	var tags []response.TagsResponse
-
-	for _, value := range result {
-		var necheResponses []response.NecheResponse
-		for _, neche := range value.Neches {
-			necheResponse := response.NecheResponse{
-				Id:     neche.Id,
-				Name:   neche.NecheType,
-				TagsID: neche.TagID,
-			}
-			necheResponses = append(necheResponses, necheResponse)
-		}
-
-		tag := response.TagsResponse{
-			Id:     value.Id,
-			Name:   value.Name,
-			Neches: necheResponses,
-		}
-		tags = append(tags, tag) // Append each tag to the tags slice
-	}
-
-	return tags, nil // Return the list of tags and nil for error
+	return newTagsResponses(result), nil // Return the list of tags and nil for error
 }
 
 // FindById implements TagsService.
 func (t *TagsServiceImpl) FindById(tagsId int) (response.TagsResponse, error) {
 	tagData, err := t.TagsRepository.FindById(tagsId)
+	if errors.Is(err, helper.ErrNotFound) {
+		return response.TagsResponse{}, helper.NotFound("Tag with id %d not found", tagsId)
+	}
 	if err != nil {
 		return response.TagsResponse{}, err // Return empty response and error if not found
 	}
 	tagResponse := response.TagsResponse{
-		Id:   tagData.Id,
-		Name: tagData.Name,
+		Id:       tagData.Id,
+		Name:     tagData.Name,
+		ParentId: tagData.ParentId,
+		Version:  tagData.Version,
 	}
 	return tagResponse, nil // Return the tag response and nil for error
 }
 
 // Update implements TagsService.
-func (t *TagsServiceImpl) Update(tags request.UpdateTagsRequest) error {
-	// Validation for empty name
-	if tags.Name == "" {
-		return errors.New("tag name cannot be empty")
-	}
-
-	// Validation for non-positive ID
-	if tags.Id <= 0 {
-		return errors.New("tag ID must be positive")
+func (t *TagsServiceImpl) Update(actorId int, tags request.UpdateTagsRequest) (response.TagsResponse, error) {
+	err := t.validate.Struct(tags)
+	if err != nil {
+		return response.TagsResponse{}, err
 	}
 
 	// Attempt to find the existing tag by ID
 	tagsData, err := t.TagsRepository.FindById(tags.Id)
 	if err != nil {
 		// Explicitly return the "tag not found" error from the repository
-		return err
+		return response.TagsResponse{}, err
 	}
 
 	// If the tag was found but is empty, return an error
 	if tagsData.Id == 0 { // This should check if the tag does not exist
-		return errors.New("tag not found")
+		return response.TagsResponse{}, helper.NotFound("tag not found")
+	}
+
+	// The client edited an older version than the stored one
+	if tags.Version != 0 && tags.Version != tagsData.Version {
+		return response.TagsResponse{}, repository.ErrStaleVersion
 	}
 
-	// Update the tag name
+	// Update the tag name, the repository checks the version we read is
+	// still current so a concurrent update isn't overwritten
 	tagsData.Name = tags.Name
 
 	// Call the repository's Update method and handle the error
-	err = t.TagsRepository.Update(tagsData)
-	if err != nil {
-		return err // Return the error if the update fails
-	}
-
-	return nil // Return nil if everything is successful
-}
-
-// // FindPaginated implements TagsService.
-// func (t *TagsServiceImpl) FindPaginated(page int, pageSize int) []response.TagsResponse {
-// 	offset := (page - 1) * pageSize
-// 	result := t.TagsRepository.FindPaginated(pageSize, offset)
-// 	var tags []response.TagsResponse
-// 	for _, value := range result {
-// 		tag := response.TagsResponse{
-// 			Id:   value.Id,
-// 			Name: value.Name,
-// 		}
-// 		tags = append(tags, tag)
-// 	}
-// 	return tags
-// }
-
-// // FindAllSorted implements TagsService.
-// func (t *TagsServiceImpl) FindAllSorted(sortBy string, order string) []response.TagsResponse {
-// 	result := t.TagsRepository.FindAllSorted(sortBy, order)
-// 	var tags []response.TagsResponse
-// 	for _, value := range result {
-// 		tag := response.TagsResponse{
-// 			Id:   value.Id,
-// 			Name: value.Name,
-// 		}
-// 		tags = append(tags, tag)
-// 	}
-// 	return tags
-// }
-
-// // FindByCustomFilter implements TagsService.
-// func (t *TagsServiceImpl) FindByCustomFilter(startsWith string) []response.TagsResponse {
-// 	result := t.TagsRepository.FindByCustomFilter(startsWith)
-// 	var tags []response.TagsResponse
-// 	for _, value := range result {
-// 		tag := response.TagsResponse{
-// 			Id:   value.Id,
-// 			Name: value.Name,
-// 		}
-// 		tags = append(tags, tag)
-// 	}
-// 	return tags
-// }
+	err = t.TagsRepository.Update(actorId, tagsData)
+	if err != nil {
+		return response.TagsResponse{}, err // Return the error if the update fails
+	}
+
+	return response.TagsResponse{Id: tagsData.Id, Name: tagsData.Name, ParentId: tagsData.ParentId, Version: tagsData.Version + 1}, nil
+}
+
+// Patch implements TagsService. The patched tag is validated like a new one.
+func (t *TagsServiceImpl) Patch(actorId int, patch request.PatchRequest) (response.TagsResponse, error) {
+	tagsData, err := t.TagsRepository.FindById(patch.Id)
+	if err != nil {
+		return response.TagsResponse{}, err
+	}
+
+	// The patch was written against the version the client read
+	if patch.Version != 0 && patch.Version != tagsData.Version {
+		return response.TagsResponse{}, repository.ErrStaleVersion
+	}
+
+	patched := request.CreteTagsRequest{}
+	err = patchFields(patch, request.CreteTagsRequest{Name: tagsData.Name, ParentId: tagsData.ParentId}, &patched)
+	if err != nil {
+		return response.TagsResponse{}, err
+	}
+	if !sameId(patched.ParentId, tagsData.ParentId) {
+		return response.TagsResponse{}, helper.Validation("parentId can't be patched, move the tag with POST /tags/%d/move", tagsData.Id)
+	}
+	err = t.validate.Struct(patched)
+	if err != nil {
+		return response.TagsResponse{}, err
+	}
+
+	// Only save over the version the patch was applied to
+	return t.Update(actorId, request.UpdateTagsRequest{Id: tagsData.Id, Name: patched.Name, Version: tagsData.Version})
+}
+
+// Move implements TagsService. The tag keeps its subtree.
+func (t *TagsServiceImpl) Move(actorId int, move request.MoveTagRequest) (response.TagsResponse, error) {
+	err := t.validate.Struct(move)
+	if err != nil {
+		return response.TagsResponse{}, err
+	}
+
+	err = t.TagsRepository.Move(actorId, move.Id, move.ParentId, move.Version)
+	if err != nil {
+		return response.TagsResponse{}, err
+	}
+	return t.FindById(move.Id)
+}
+
+// Subtree implements TagsService.
+func (t *TagsServiceImpl) Subtree(tagId int) (response.TagTreeResponse, error) {
+	tags, err := t.TagsRepository.Subtree(tagId)
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return response.TagTreeResponse{}, helper.NotFound("Tag with id %d not found", tagId)
+	}
+	if err != nil {
+		return response.TagTreeResponse{}, err
+	}
+
+	children := map[int][]model.Tags{}
+	for _, tag := range tags {
+		if tag.ParentId != nil && tag.Id != tagId {
+			children[*tag.ParentId] = append(children[*tag.ParentId], tag)
+		}
+	}
+	var tree func(tag model.Tags) response.TagTreeResponse
+	tree = func(tag model.Tags) response.TagTreeResponse {
+		node := response.TagTreeResponse{Id: tag.Id, Name: tag.Name, Children: []response.TagTreeResponse{}}
+		for _, child := range children[tag.Id] {
+			node.Children = append(node.Children, tree(child))
+		}
+		return node
+	}
+
+	i := slices.IndexFunc(tags, func(tag model.Tags) bool { return tag.Id == tagId })
+	return tree(tags[i]), nil
+}
+
+// Ancestors implements TagsService. The breadcrumb starts at the root and ends with the tag.
+func (t *TagsServiceImpl) Ancestors(tagId int) ([]response.BreadcrumbResponse, error) {
+	tags, err := t.TagsRepository.Ancestors(tagId)
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, helper.NotFound("Tag with id %d not found", tagId)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	breadcrumb := make([]response.BreadcrumbResponse, 0, len(tags))
+	for _, tag := range tags {
+		breadcrumb = append(breadcrumb, response.BreadcrumbResponse{Id: tag.Id, Name: tag.Name})
+	}
+	return breadcrumb, nil
+}
+
+// parentNotFound names the parent when looking it up failed because it doesn't exist
+func (t *TagsServiceImpl) parentNotFound(parentId int, err error) error {
+	if errors.Is(err, helper.ErrNotFound) {
+		return fmt.Errorf("parent tag with ID %d: %w", parentId, ErrTagNotFound)
+	}
+	return err
+}
+
+// sameId tells whether two optional ids are both missing or equal
+func sameId(a, b *int) bool {
+	if a == nil || b == nil {
+		return a == b
+	}
+	return *a == *b
+}
+
+// Search implements TagsService.
+func (t *TagsServiceImpl) Search(query request.SearchTagsRequest) (response.TagsPage, error) {
+	err := t.validate.Struct(query)
+	if err != nil {
+		return response.TagsPage{}, err
+	}
+	if query.MinNeches != nil && query.MaxNeches != nil && *query.MinNeches > *query.MaxNeches {
+		return response.TagsPage{}, helper.Validation("minNeches can't be greater than maxNeches")
+	}
+
+	sort, err := parseTagsSort(query.Sort)
+	if err != nil {
+		return response.TagsPage{}, err
+	}
+
+	tagsQuery := repository.TagsQuery{
+		Name:      strings.TrimSpace(query.Q),
+		Match:     query.Match,
+		HasNeches: query.HasNeches,
+		MinNeches: query.MinNeches,
+		MaxNeches: query.MaxNeches,
+		Sort:      sort,
+	}
+
+	// A cursor only makes sense for the filters and the sort it was made with
+	p, err := newPager(query.PageRequest, tagsScope(tagsQuery))
+	if err != nil {
+		return response.TagsPage{}, err
+	}
+
+	tagsQuery.PageQuery = p.query
+	result, total, err := t.TagsRepository.Search(tagsQuery)
+	if err != nil {
+		return response.TagsPage{}, err
+	}
+
+	start, end, cursors, err := p.page(len(result), func(i int) []interface{} {
+		return repository.TagsKeyset(result[i], sort)
+	})
+	if err != nil {
+		return response.TagsPage{}, err
+	}
+	return response.TagsPage{Tags: newTagsResponses(result[start:end]), Total: total, Cursors: cursors}, nil
+}
+
+// parseTagsSort reads a comma separated list of fields, a leading "-" sorts descending
+func parseTagsSort(sort string) ([]repository.TagsSort, error) {
+	var fields []repository.TagsSort
+	for _, field := range strings.Split(sort, ",") {
+		field = strings.TrimSpace(field)
+		if field == "" {
+			continue
+		}
+
+		desc := strings.HasPrefix(field, "-")
+		field = strings.TrimPrefix(field, "-")
+		if !slices.Contains(repository.TagsSortFields, field) {
+			return nil, helper.Validation("can't sort by %q, use one of %s", field, strings.Join(repository.TagsSortFields, ", "))
+		}
+		fields = append(fields, repository.TagsSort{Field: field, Desc: desc})
+	}
+	return fields, nil
+}
+
+// tagsScope names a search for its cursors, e.g. "tags?minNeches=1&sort=-necheCount"
+func tagsScope(query repository.TagsQuery) string {
+	values := url.Values{"sort": {formatTagsSort(query.Sort)}}
+	if query.Name != "" {
+		values.Set("q", query.Name)
+		if query.Match == repository.MatchPrefix {
+			values.Set("match", query.Match)
+		}
+	}
+	if query.HasNeches != nil {
+		values.Set("hasNeches", strconv.FormatBool(*query.HasNeches))
+	}
+	if query.MinNeches != nil {
+		values.Set("minNeches", strconv.Itoa(*query.MinNeches))
+	}
+	if query.MaxNeches != nil {
+		values.Set("maxNeches", strconv.Itoa(*query.MaxNeches))
+	}
+	return "tags?" + values.Encode()
+}
+
+func formatTagsSort(sort []repository.TagsSort) string {
+	fields := make([]string, 0, len(sort))
+	for _, s := range sort {
+		if s.Desc {
+			fields = append(fields, "-"+s.Field)
+		} else {
+			fields = append(fields, s.Field)
+		}
+	}
+	return strings.Join(fields, ",")
+}
+
+func newTagsResponses(tags []model.Tags) []response.TagsResponse {
+	var tagResponses []response.TagsResponse
+	for _, value := range tags {
+		var necheResponses []response.NecheResponse
+		for _, neche := range value.Neches {
+			necheResponses = append(necheResponses, newNecheResponse(neche))
+		}
+
+		tagResponses = append(tagResponses, response.TagsResponse{
+			Id:       value.Id,
+			Name:     value.Name,
+			ParentId: value.ParentId,
+			Neches:   necheResponses,
+			Version:  value.Version,
+		})
+	}
+	return tagResponses
+}
@@ -0,0 +1,143 @@
+package services
+
+import (
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"time"
+
+	"example.com/go-project/helper"
+	"example.com/go-project/model"
+	"example.com/go-project/model/repository"
+)
+
+var (
+	ErrInvalidRefreshToken = helper.Unauthorized("invalid refresh token")
+	ErrRefreshTokenReused  = helper.Unauthorized("refresh token reuse detected")
+)
+
+type TokenService struct {
+	tokensRepo *repository.RefreshTokenRepository
+	usersRepo  *repository.UsersRepository
+	ttl        time.Duration // How long a refresh token can be exchanged for a new pair
+}
+
+func NewTokenService(tokensRepo *repository.RefreshTokenRepository, usersRepo *repository.UsersRepository, ttl time.Duration) *TokenService {
+	return &TokenService{tokensRepo: tokensRepo, usersRepo: usersRepo, ttl: ttl}
+}
+
+// Issue starts a new refresh token family for the user and returns the raw token
+func (service *TokenService) Issue(userId int) (string, error) {
+	familyId, err := randomString(16)
+	if err != nil {
+		return "", err
+	}
+
+	raw, token, err := service.newRefreshToken(userId, familyId)
+	if err != nil {
+		return "", err
+	}
+
+	if err := service.tokensRepo.Save(token); err != nil {
+		return "", err
+	}
+	return raw, nil
+}
+
+// Rotate exchanges a refresh token for a new one from the same family.
+// Presenting a token that was already rotated revokes the whole family.
+func (service *TokenService) Rotate(raw string) (*model.Users, string, error) {
+	token, err := service.tokensRepo.FindByHash(hashToken(raw))
+	if err != nil {
+		return nil, "", err
+	}
+	if token == nil {
+		return nil, "", ErrInvalidRefreshToken
+	}
+
+	// A revoked token means it has been used before, so someone is replaying it
+	if token.RevokedAt != nil {
+		if err := service.tokensRepo.RevokeFamily(token.FamilyId); err != nil {
+			return nil, "", err
+		}
+		return nil, "", ErrRefreshTokenReused
+	}
+
+	if time.Now().After(token.ExpiresAt) {
+		return nil, "", ErrInvalidRefreshToken
+	}
+
+	user, err := service.usersRepo.FindById(token.UserId)
+	if err != nil {
+		return nil, "", err
+	}
+	if user == nil {
+		return nil, "", ErrInvalidRefreshToken
+	}
+
+	newRaw, replacement, err := service.newRefreshToken(token.UserId, token.FamilyId)
+	if err != nil {
+		return nil, "", err
+	}
+
+	rotated, err := service.tokensRepo.Rotate(token.Id, replacement)
+	if err != nil {
+		return nil, "", err
+	}
+	if !rotated {
+		// Lost the race against another request using the same token
+		if err := service.tokensRepo.RevokeFamily(token.FamilyId); err != nil {
+			return nil, "", err
+		}
+		return nil, "", ErrRefreshTokenReused
+	}
+
+	return user, newRaw, nil
+}
+
+// Revoke revokes the family of the given refresh token, used on logout
+func (service *TokenService) Revoke(raw string) error {
+	token, err := service.tokensRepo.FindByHash(hashToken(raw))
+	if err != nil {
+		return err
+	}
+	if token == nil {
+		return ErrInvalidRefreshToken
+	}
+	return service.tokensRepo.RevokeFamily(token.FamilyId)
+}
+
+// RevokeAllForUser revokes every refresh token of the user
+func (service *TokenService) RevokeAllForUser(userId int) error {
+	return service.tokensRepo.RevokeAllForUser(userId)
+}
+
+func (service *TokenService) newRefreshToken(userId int, familyId string) (string, *model.RefreshToken, error) {
+	raw, err := randomString(32)
+	if err != nil {
+		return "", nil, err
+	}
+
+	token := &model.RefreshToken{
+		UserId:    userId,
+		FamilyId:  familyId,
+		TokenHash: hashToken(raw),
+		ExpiresAt: time.Now().Add(service.ttl),
+	}
+	return raw, token, nil
+}
+
+// hashToken hashes the raw token so a database leak doesn't leak usable tokens
+func hashToken(raw string) string {
+	sum := sha256.Sum256([]byte(raw))
+	return hex.EncodeToString(sum[:])
+}
+
+func randomString(size int) (string, error) {
+	bytes := make([]byte, size)
+	if _, err := rand.Read(bytes); err != nil {
+		return "", err
+	}
+	return base64.RawURLEncoding.EncodeToString(bytes), nil
+}
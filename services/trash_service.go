@@ -0,0 +1,17 @@
+package services
+
+import (
+	"time"
+
+	"example.com/go-project/data/request"
+	"example.com/go-project/data/response"
+)
+
+// TrashService lists and restores deleted tags and neches, and purges them once they expire
+type TrashService interface {
+	FindTags(page request.PageRequest) (response.TrashedTagsPage, error)
+	FindNeches(page request.PageRequest) (response.TrashedNechesPage, error)
+	RestoreTag(actorId int, tagId int) (response.TagsResponse, error)
+	RestoreNeche(actorId int, necheId int) (response.NecheResponse, error)
+	Purge(now time.Time) (tags int64, neches int64, err error)
+}
@@ -0,0 +1,152 @@
+package services
+
+import (
+	"errors"
+	"log"
+	"time"
+
+	"example.com/go-project/data/request"
+	"example.com/go-project/data/response"
+	"example.com/go-project/helper"
+	"example.com/go-project/model/repository"
+	"gorm.io/gorm"
+)
+
+type TrashServiceImpl struct {
+	TagsRepository  repository.TagsRepository
+	NecheRepository repository.NecheRepository
+	retention       time.Duration
+}
+
+// NewTrashServiceImpl keeps deleted tags and neches for the retention
+func NewTrashServiceImpl(tagsRepository repository.TagsRepository, necheRepository repository.NecheRepository, retention time.Duration) TrashService {
+	return &TrashServiceImpl{
+		TagsRepository:  tagsRepository,
+		NecheRepository: necheRepository,
+		retention:       retention,
+	}
+}
+
+// FindTags implements TrashService.
+func (s *TrashServiceImpl) FindTags(page request.PageRequest) (response.TrashedTagsPage, error) {
+	p, err := newPager(page, "trash/tags")
+	if err != nil {
+		return response.TrashedTagsPage{}, err
+	}
+
+	tags, total, err := s.TagsRepository.Trash(p.query)
+	if err != nil {
+		return response.TrashedTagsPage{}, err
+	}
+	start, end, cursors, err := p.page(len(tags), func(i int) []interface{} {
+		return []interface{}{tags[i].Id}
+	})
+	if err != nil {
+		return response.TrashedTagsPage{}, err
+	}
+
+	trashed := []response.TrashedTagResponse{}
+	for _, tag := range tags[start:end] {
+		trashed = append(trashed, response.TrashedTagResponse{
+			Id:        tag.Id,
+			Name:      tag.Name,
+			ParentId:  tag.ParentId,
+			DeletedAt: tag.DeletedAt.Time,
+			PurgeAt:   tag.DeletedAt.Time.Add(s.retention),
+		})
+	}
+	return response.TrashedTagsPage{Tags: trashed, Total: total, Cursors: cursors}, nil
+}
+
+// FindNeches implements TrashService.
+func (s *TrashServiceImpl) FindNeches(page request.PageRequest) (response.TrashedNechesPage, error) {
+	p, err := newPager(page, "trash/neches")
+	if err != nil {
+		return response.TrashedNechesPage{}, err
+	}
+
+	neches, total, err := s.NecheRepository.Trash(p.query)
+	if err != nil {
+		return response.TrashedNechesPage{}, err
+	}
+	start, end, cursors, err := p.page(len(neches), func(i int) []interface{} {
+		return []interface{}{neches[i].Id}
+	})
+	if err != nil {
+		return response.TrashedNechesPage{}, err
+	}
+
+	trashed := []response.TrashedNecheResponse{}
+	for _, neche := range neches[start:end] {
+		trashed = append(trashed, response.TrashedNecheResponse{
+			Id:               neche.Id,
+			Name:             neche.NecheType,
+			Tags:             newNecheTagResponses(neche.Tags),
+			DeletedWithTagId: neche.DeletedWithTagId,
+			DeletedAt:        neche.DeletedAt.Time,
+			PurgeAt:          neche.DeletedAt.Time.Add(s.retention),
+		})
+	}
+	return response.TrashedNechesPage{Neches: trashed, Total: total, Cursors: cursors}, nil
+}
+
+// RestoreTag implements TrashService. The neches deleted with the tag come back with it.
+func (s *TrashServiceImpl) RestoreTag(actorId int, tagId int) (response.TagsResponse, error) {
+	err := s.TagsRepository.Restore(actorId, tagId)
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return response.TagsResponse{}, helper.NotFound("Tag with id %d isn't in the trash", tagId)
+	}
+	if err != nil {
+		return response.TagsResponse{}, err
+	}
+
+	tag, err := s.TagsRepository.FindById(tagId)
+	if err != nil {
+		return response.TagsResponse{}, err
+	}
+	return response.TagsResponse{Id: tag.Id, Name: tag.Name, ParentId: tag.ParentId, Version: tag.Version}, nil
+}
+
+// RestoreNeche implements TrashService.
+func (s *TrashServiceImpl) RestoreNeche(actorId int, necheId int) (response.NecheResponse, error) {
+	err := s.NecheRepository.Restore(actorId, necheId)
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return response.NecheResponse{}, helper.NotFound("Neche with id %d isn't in the trash", necheId)
+	}
+	if err != nil {
+		return response.NecheResponse{}, err
+	}
+
+	neche, err := s.NecheRepository.FindById(necheId)
+	if err != nil {
+		return response.NecheResponse{}, err
+	}
+	return newNecheResponse(*neche), nil
+}
+
+// Purge implements TrashService. Whatever went to the trash a retention before now is deleted for good.
+func (s *TrashServiceImpl) Purge(now time.Time) (int64, int64, error) {
+	before := now.Add(-s.retention)
+	neches, err := s.NecheRepository.Purge(before)
+	if err != nil {
+		return 0, neches, err
+	}
+	tags, err := s.TagsRepository.Purge(before)
+	return tags, neches, err
+}
+
+// StartTrashPurging purges the expired trash now and then periodically, so an
+// instance that restarts more often than the interval still purges
+func StartTrashPurging(service TrashService, interval time.Duration) (stop func()) {
+	purge := func(now time.Time) {
+		tags, neches, err := service.Purge(now)
+		if err != nil {
+			log.Println("Error purging the trash:", err)
+		} else if tags > 0 || neches > 0 {
+			log.Printf("Purged %d tags and %d neches from the trash", tags, neches)
+		}
+	}
+
+	purge(time.Now())
+	return helper.StartPeriodic(interval, purge)
+}
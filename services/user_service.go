@@ -2,28 +2,65 @@ package services
 
 import (
 	"errors"
+	"strings"
+	"sync"
 
 	"example.com/go-project/config"
+	"example.com/go-project/data/request"
+	"example.com/go-project/helper"
 	"example.com/go-project/model"
 	"example.com/go-project/model/repository"
+	"github.com/go-playground/validator"
+	"gorm.io/gorm"
+)
+
+var (
+	ErrEmailExists        = helper.Conflict("email already exists")
+	ErrUserNotFound       = helper.NotFound("user not found")
+	ErrInvalidCredentials = helper.Unauthorized("invalid credentials")
 )
 
 type UsersService struct {
 	usersRepo *repository.UsersRepository
+	validate  *validator.Validate
 }
 
-func NewUsersService(repo *repository.UsersRepository) *UsersService {
-	return &UsersService{usersRepo: repo}
+func NewUsersService(repo *repository.UsersRepository, validate *validator.Validate) *UsersService {
+	return &UsersService{usersRepo: repo, validate: validate}
 }
 
-func (service *UsersService) Create(user model.Users) error {
+// Register creates a new account from a registration request, the role is always assigned by the server
+func (service *UsersService) Register(userReq request.RegisterUserRequest) (*model.Users, error) {
+	userReq.Email = NormalizeEmail(userReq.Email)
+	err := service.validate.Struct(userReq)
+	if err != nil {
+		return nil, err
+	}
+
+	user := &model.Users{
+		Name:     strings.TrimSpace(userReq.Name),
+		Email:    userReq.Email,
+		Password: userReq.Password,
+		Role:     model.RoleUser,
+	}
+
+	err = service.Create(user)
+	if err != nil {
+		return nil, err
+	}
+	return user, nil
+}
+
+func (service *UsersService) Create(user *model.Users) error {
+	user.Email = NormalizeEmail(user.Email)
+
 	// Check if the email already exists
 	existingUser, err := service.usersRepo.FindByEmail(user.Email)
 	if err != nil {
 		return err
 	}
 	if existingUser != nil {
-		return errors.New("email already exists")
+		return ErrEmailExists
 	}
 
 	// Hash the user's password before saving
@@ -33,25 +70,65 @@ func (service *UsersService) Create(user model.Users) error {
 	}
 	user.Password = hashedPassword
 
-	// Save the user with the hashed password
-	return service.usersRepo.Save(user)
+	// Save the user with the hashed password, the unique index catches
+	// registrations that raced past the check above
+	err = service.usersRepo.Save(user)
+	if errors.Is(err, gorm.ErrDuplicatedKey) {
+		return ErrEmailExists
+	}
+	return err
 }
 
 func (service *UsersService) Authenticate(email string, password string) (*model.Users, error) {
-	// Retrieve the user by email
-	user, err := service.usersRepo.FindByEmail(email)
+	// Retrieve the user by email, an unknown email still costs a hash
+	// comparison so the response time doesn't tell it apart
+	user, err := service.usersRepo.FindByEmail(NormalizeEmail(email))
 	if err != nil || user == nil {
-		return nil, errors.New("invalid credentials")
+		config.CheckPasswordHash(password, dummyPasswordHash())
+		return nil, ErrInvalidCredentials
 	}
 
 	// Compare the provided password with the stored hashed password
 	if !config.CheckPasswordHash(password, user.Password) {
-		return nil, errors.New("invalid credentials")
+		return nil, ErrInvalidCredentials
 	}
 
 	return user, nil
 }
 
+var dummyPasswordHash = sync.OnceValue(func() string {
+	hash, err := config.HashPassword("not a password of any user")
+	helper.ErrorPanic(err)
+	return hash
+})
+
 func (s *UsersService) FindUserByEmail(email string) (*model.Users, error) {
-	return s.usersRepo.FindByEmail(email)
+	return s.usersRepo.FindByEmail(NormalizeEmail(email))
+}
+
+// NormalizeEmail makes emails comparable, addresses are matched case-insensitively
+func NormalizeEmail(email string) string {
+	return strings.ToLower(strings.TrimSpace(email))
+}
+
+// UpdateRole grants a role to an existing user
+func (service *UsersService) UpdateRole(roleReq request.UpdateUserRoleRequest) error {
+	err := service.validate.Struct(roleReq)
+	if err != nil {
+		return err
+	}
+
+	found, err := service.usersRepo.UpdateRole(roleReq.Id, roleReq.Role)
+	if err != nil {
+		return err
+	}
+	if !found {
+		return ErrUserNotFound
+	}
+	return nil
+}
+
+// FindUserById returns nil without error when the user doesn't exist
+func (service *UsersService) FindUserById(id int) (*model.Users, error) {
+	return service.usersRepo.FindById(id)
 }
@@ -0,0 +1,207 @@
+package unittesting
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+
+	"example.com/go-project/auth"
+	"example.com/go-project/config"
+	"example.com/go-project/controller"
+	"example.com/go-project/helper"
+	"example.com/go-project/middleware"
+	"example.com/go-project/model"
+	"example.com/go-project/model/repository"
+	"example.com/go-project/services"
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+)
+
+// setupAuditRouter serves login, registration, an audited admin delete, role
+// changes and the audit log, with an admin account to delete with
+func setupAuditRouter(t *testing.T) (*gin.Engine, *model.Users) {
+	db := setupMigratedDB(t)
+
+	key, err := auth.GenerateSigningKey(auth.AlgEdDSA)
+	assert.NoError(t, err)
+	keyring := auth.NewKeyring(time.Minute)
+	keyring.Add(key)
+	auth.UseKeyring(keyring)
+	auth.UseRevocationStore(auth.NewMemoryRevocationStore())
+	auth.UsePermissionStore(repository.NewPermissionRepository(db))
+	auth.UseAuthenticators(auth.BearerAuthenticator{})
+
+	validate := helper.NewValidator()
+	auditService := services.NewAuditService(repository.NewAuditRepository(db), validate)
+	auth.UseAuditLog(auditService)
+	t.Cleanup(func() { auth.UseAuditLog(nil) })
+
+	usersRepo := repository.NewUsersRepository(db)
+	usersService := services.NewUsersService(usersRepo, validate)
+	tokenService := services.NewTokenService(repository.NewRefreshTokenRepository(db), usersRepo, time.Hour)
+	admin := &model.Users{Name: "Admin", Email: "admin@example.com", Password: "password", Role: "Admin"}
+	assert.NoError(t, usersService.Create(admin))
+
+	gin.SetMode(gin.TestMode)
+	router := gin.New()
+	router.Use(middleware.ErrorHandler())
+	// Without a backoff to wait for between the failed and the good logins
+	loginConfig := config.Default().Login
+	loginConfig.BackoffBase = time.Nanosecond
+	loginThrottle := services.NewLoginThrottle(repository.NewLoginAttemptRepository(db), loginConfig)
+	userController := controller.NewUsersController(usersService, tokenService, loginThrottle)
+	auditController := controller.NewAuditController(auditService)
+	router.POST("/register", userController.RegisterUser)
+	router.POST("/login", userController.Login)
+	router.DELETE("/tags/:tagId", auth.Audited(model.AuditTagDelete, "tag", "tagId"), auth.RequirePermission("tags:delete"), func(c *gin.Context) {
+		c.Status(http.StatusOK)
+	})
+	rolesController := controller.NewRolesController(services.NewPermissionService(repository.NewPermissionRepository(db), validate))
+	router.POST("/roles", auth.Audited(model.AuditRoleCreate, "role", ""), rolesController.CreateRole)
+	router.PUT("/roles/:role/permissions/:permission", auth.Audited(model.AuditPermissionGrant, "role", "role"), rolesController.Grant)
+	router.GET("/audit", auditController.FindAll)
+	router.GET("/audit/export", auditController.Export)
+	return router, admin
+}
+
+func auditEvents(t *testing.T, router *gin.Engine, url string) []map[string]interface{} {
+	recorder, body := getPage(t, router, url)
+	assert.Equal(t, http.StatusOK, recorder.Code)
+	var events []map[string]interface{}
+	for _, event := range body.Data.([]interface{}) {
+		events = append(events, event.(map[string]interface{}))
+	}
+	return events
+}
+
+func TestAudit_LoginAndRegistration(t *testing.T) {
+	router, admin := setupAuditRouter(t)
+	agent := map[string]string{"User-Agent": "audit-test/1.0"}
+
+	recorder := sendWithHeaders(router, http.MethodPost, "/register", `{"name":"Ann","email":"ann@example.com","password":"password"}`, agent)
+	assert.Equal(t, http.StatusOK, recorder.Code)
+	recorder = sendWithHeaders(router, http.MethodPost, "/register", `{"name":"Ann","email":"ann@example.com","password":"password"}`, agent)
+	assert.Equal(t, http.StatusConflict, recorder.Code)
+
+	recorder = sendWithHeaders(router, http.MethodPost, "/login", `{"email":"admin@example.com","password":"wrong"}`, agent)
+	assert.Equal(t, http.StatusUnauthorized, recorder.Code)
+	recorder = sendWithHeaders(router, http.MethodPost, "/login", `{"email":"admin@example.com","password":"password"}`, agent)
+	assert.Equal(t, http.StatusOK, recorder.Code)
+
+	// Newest first, a failed login has no actor but names the email it tried
+	events := auditEvents(t, router, "/audit?action=auth.login")
+	assert.Len(t, events, 2)
+	assert.Equal(t, "success", events[0]["outcome"])
+	assert.Equal(t, float64(admin.Id), events[0]["actorId"])
+	assert.Equal(t, "failure", events[1]["outcome"])
+	assert.Nil(t, events[1]["actorId"])
+	assert.Equal(t, "admin@example.com", events[1]["email"])
+	assert.Equal(t, "invalid credentials", events[1]["detail"])
+	assert.Equal(t, "audit-test/1.0", events[1]["userAgent"])
+
+	events = auditEvents(t, router, "/audit?action=auth.register")
+	assert.Len(t, events, 2)
+	assert.Equal(t, "failure", events[0]["outcome"])
+	assert.Equal(t, "user", events[1]["targetType"])
+
+	// The user filter matches the actor and the target
+	events = auditEvents(t, router, fmt.Sprintf("/audit?userId=%d", admin.Id))
+	assert.Len(t, events, 1)
+	assert.Equal(t, "auth.login", events[0]["action"])
+}
+
+func TestAudit_AdminDeletes(t *testing.T) {
+	router, admin := setupAuditRouter(t)
+	user := &model.Users{Id: admin.Id + 1}
+	adminToken, err := auth.GenerateJWT(admin.Id, admin.Email, admin.Role)
+	assert.NoError(t, err)
+	userToken, err := auth.GenerateJWT(user.Id, "user@example.com", model.RoleUser)
+	assert.NoError(t, err)
+
+	recorder := sendWithHeaders(router, http.MethodDelete, "/tags/4", "", map[string]string{"Authorization": "Bearer " + userToken})
+	assert.Equal(t, http.StatusForbidden, recorder.Code)
+	recorder = sendWithHeaders(router, http.MethodDelete, "/tags/4", "", map[string]string{"Authorization": "Bearer " + adminToken})
+	assert.Equal(t, http.StatusOK, recorder.Code)
+
+	events := auditEvents(t, router, "/audit?action=tag.delete")
+	assert.Len(t, events, 2)
+	assert.Equal(t, "success", events[0]["outcome"])
+	assert.Equal(t, float64(admin.Id), events[0]["actorId"])
+	assert.Equal(t, "4", events[0]["targetId"])
+	assert.Equal(t, "failure", events[1]["outcome"])
+	assert.Equal(t, float64(user.Id), events[1]["actorId"])
+	assert.Equal(t, "Forbidden", events[1]["detail"])
+}
+
+func TestAudit_RoleChanges(t *testing.T) {
+	router, _ := setupAuditRouter(t)
+
+	recorder := sendWithHeaders(router, http.MethodPost, "/roles", `{"name":"Moderator"}`, nil)
+	assert.Equal(t, http.StatusOK, recorder.Code)
+	recorder = sendWithHeaders(router, http.MethodPut, "/roles/Moderator/permissions/tags:delete", "", nil)
+	assert.Equal(t, http.StatusOK, recorder.Code)
+	recorder = sendWithHeaders(router, http.MethodPut, "/roles/Moderator/permissions/tags:unknown", "", nil)
+	assert.Equal(t, http.StatusNotFound, recorder.Code)
+
+	// A created role is the target even without a path parameter
+	events := auditEvents(t, router, "/audit?action=role.create")
+	assert.Len(t, events, 1)
+	assert.Equal(t, "role", events[0]["targetType"])
+	assert.Equal(t, "Moderator", events[0]["targetId"])
+
+	// Grants name the permission, failed ones the reason
+	events = auditEvents(t, router, "/audit?action=role.permission_grant")
+	assert.Len(t, events, 2)
+	assert.Equal(t, "failure", events[0]["outcome"])
+	assert.Equal(t, "success", events[1]["outcome"])
+	assert.Equal(t, "Moderator", events[1]["targetId"])
+	assert.Equal(t, "permission tags:delete", events[1]["detail"])
+}
+
+func TestAudit_TimeRangeAndExport(t *testing.T) {
+	router, _ := setupAuditRouter(t)
+	for _, password := range []string{"wrong", "password"} {
+		sendWithHeaders(router, http.MethodPost, "/login", `{"email":"admin@example.com","password":"`+password+`"}`, nil)
+	}
+
+	future := time.Now().Add(time.Hour).UTC().Format(time.RFC3339)
+	past := time.Now().Add(-time.Hour).UTC().Format(time.RFC3339)
+	assert.Empty(t, auditEvents(t, router, "/audit?from="+future))
+	assert.Len(t, auditEvents(t, router, "/audit?from="+past+"&to="+future), 2)
+
+	recorder := sendWithHeaders(router, http.MethodGet, "/audit?from="+future+"&to="+past, "", nil)
+	assert.Equal(t, http.StatusBadRequest, recorder.Code)
+	assert.Contains(t, recorder.Body.String(), "from must be before to")
+	recorder = sendWithHeaders(router, http.MethodGet, "/audit?from=yesterday", "", nil)
+	assert.Equal(t, http.StatusBadRequest, recorder.Code)
+
+	// A cursor only pages through the filters it was made with
+	_, body := getPage(t, router, "/audit?action=auth.login&pageSize=1")
+	assert.NotEmpty(t, body.NextCursor)
+	assert.Len(t, auditEvents(t, router, "/audit?action=auth.login&pageSize=1&cursor="+body.NextCursor), 1)
+	recorder = sendWithHeaders(router, http.MethodGet, "/audit?action=auth.register&pageSize=1&cursor="+body.NextCursor, "", nil)
+	assert.Equal(t, http.StatusBadRequest, recorder.Code)
+	recorder = sendWithHeaders(router, http.MethodGet, "/audit?pageSize=1&cursor="+body.NextCursor, "", nil)
+	assert.Equal(t, http.StatusBadRequest, recorder.Code)
+
+	// The export has one event per line, oldest first
+	recorder = sendWithHeaders(router, http.MethodGet, "/audit/export?action=auth.login", "", nil)
+	assert.Equal(t, http.StatusOK, recorder.Code)
+	assert.Equal(t, "application/x-ndjson", recorder.Header().Get("Content-Type"))
+	var outcomes []string
+	scanner := bufio.NewScanner(strings.NewReader(recorder.Body.String()))
+	for scanner.Scan() {
+		var event map[string]interface{}
+		assert.NoError(t, json.Unmarshal(scanner.Bytes(), &event))
+		outcomes = append(outcomes, event["outcome"].(string))
+	}
+	assert.Equal(t, []string{"failure", "success"}, outcomes)
+
+	recorder = sendWithHeaders(router, http.MethodGet, "/audit/export?from="+future+"&to="+past, "", nil)
+	assert.Equal(t, http.StatusBadRequest, recorder.Code)
+	assert.Contains(t, recorder.Header().Get("Content-Type"), "application/json")
+}
@@ -0,0 +1,95 @@
+package unittesting
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"example.com/go-project/auth"
+	"example.com/go-project/data/request"
+	"example.com/go-project/model"
+	"example.com/go-project/model/repository"
+	"example.com/go-project/services"
+	"github.com/gin-gonic/gin"
+	"github.com/go-playground/validator"
+	"github.com/golang-jwt/jwt"
+	"github.com/stretchr/testify/assert"
+)
+
+func setupAuthenticatorChain(t *testing.T) (*gin.Engine, *services.ApiKeyService, *model.Users, *auth.SigningKey) {
+	gin.SetMode(gin.TestMode)
+	db := setupMigratedDB(t)
+
+	user := &model.Users{Name: "Test", Email: "test@example.com", Password: "x", Role: model.RoleUser}
+	assert.NoError(t, db.Create(user).Error)
+
+	key, err := auth.GenerateSigningKey(auth.AlgEdDSA)
+	assert.NoError(t, err)
+	keyring := auth.NewKeyring(time.Minute)
+	keyring.Add(key)
+	auth.UseKeyring(keyring)
+	auth.UseRevocationStore(auth.NewMemoryRevocationStore())
+	auth.UsePermissionStore(repository.NewPermissionRepository(db))
+
+	apiKeyService := services.NewApiKeyService(repository.NewApiKeyRepository(db), repository.NewUsersRepository(db), validator.New())
+	auth.UseAuthenticators(auth.BearerAuthenticator{}, auth.APIKeyAuthenticator{Keys: apiKeyService})
+
+	router := gin.New()
+	whoami := func(c *gin.Context) {
+		p, _ := auth.CurrentPrincipal(c)
+		c.JSON(http.StatusOK, gin.H{"userId": p.UserId, "method": p.Method})
+	}
+	router.GET("/tags", auth.RequirePermission("tags:read"), whoami)
+	router.POST("/tags", auth.RequirePermission("tags:create"), whoami)
+	return router, apiKeyService, user, key
+}
+
+func serve(router *gin.Engine, method string, headers map[string]string) *httptest.ResponseRecorder {
+	req, _ := http.NewRequest(method, "/tags", nil)
+	for name, value := range headers {
+		req.Header.Set(name, value)
+	}
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+	return w
+}
+
+func TestAuthenticatorChain_BearerAndAPIKey(t *testing.T) {
+	router, apiKeyService, user, _ := setupAuthenticatorChain(t)
+
+	token, err := auth.GenerateJWT(user.Id, user.Email, user.Role)
+	assert.NoError(t, err)
+	w := serve(router, http.MethodGet, map[string]string{"Authorization": "Bearer " + token})
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Contains(t, w.Body.String(), `"method":"bearer"`)
+
+	// The key is limited to its scopes even though the user may create tags
+	raw, _, err := apiKeyService.Create(user.Id, request.CreateApiKeyRequest{Name: "script", Scopes: []string{"tags:read"}})
+	assert.NoError(t, err)
+	w = serve(router, http.MethodGet, map[string]string{auth.APIKeyHeader: raw})
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Contains(t, w.Body.String(), `"method":"apiKey"`)
+	w = serve(router, http.MethodPost, map[string]string{auth.APIKeyHeader: raw})
+	assert.Equal(t, http.StatusForbidden, w.Code)
+
+	// Wrong credentials don't fall back to the next authenticator
+	w = serve(router, http.MethodGet, map[string]string{"Authorization": "Bearer nonsense", auth.APIKeyHeader: raw})
+	assert.Equal(t, http.StatusUnauthorized, w.Code)
+
+	w = serve(router, http.MethodGet, nil)
+	assert.Equal(t, http.StatusUnauthorized, w.Code)
+}
+
+func TestAuthenticatorChain_MissingRoleClaimDoesNotPanic(t *testing.T) {
+	router, _, user, signingKey := setupAuthenticatorChain(t)
+
+	// Signed with our key but without a role claim
+	token := jwt.NewWithClaims(signingKey.Method, jwt.MapClaims{"user_id": user.Id, "jti": "abc", "exp": time.Now().Add(time.Minute).Unix()})
+	token.Header["kid"] = signingKey.Kid
+	signed, err := token.SignedString(signingKey.PrivateKey)
+	assert.NoError(t, err)
+
+	w := serve(router, http.MethodGet, map[string]string{"Authorization": "Bearer " + signed})
+	assert.Equal(t, http.StatusForbidden, w.Code)
+}
@@ -0,0 +1,59 @@
+package unittesting
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"example.com/go-project/config"
+	"github.com/stretchr/testify/assert"
+)
+
+func writeConfigFile(t *testing.T, content string) string {
+	path := filepath.Join(t.TempDir(), "config.yaml")
+	assert.NoError(t, os.WriteFile(path, []byte(content), 0600))
+	return path
+}
+
+func TestLoadConfig_Precedence(t *testing.T) {
+	path := writeConfigFile(t, `
+server:
+  port: 9000
+database:
+  host: file-host
+  password: file-password
+  name: file-db
+auth:
+  cookieKey: 0123456789abcdef0123456789abcdef
+  accessTokenTtl: 5m
+`)
+
+	// Environment variables override the file, flags override both
+	t.Setenv("DB_HOST", "env-host")
+	t.Setenv("DB_NAME", "env-db")
+
+	cfg, args, err := config.Load([]string{"-config", path, "-db-name", "flag-db", "-prod", "status"})
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"status"}, args)
+
+	assert.Equal(t, 9000, cfg.Server.Port)
+	assert.Equal(t, "env-host", cfg.Database.Host)
+	assert.Equal(t, "flag-db", cfg.Database.Name)
+	assert.Equal(t, "file-password", cfg.Database.Password)
+	assert.Equal(t, 5*time.Minute, cfg.Auth.AccessTokenTTL)
+	assert.True(t, cfg.Auth.IsProd)
+
+	// Untouched values keep their defaults
+	assert.Equal(t, 5432, cfg.Database.Port)
+	assert.Equal(t, "EdDSA", cfg.Auth.SigningAlg)
+}
+
+func TestLoadConfig_MissingSecrets(t *testing.T) {
+	path := writeConfigFile(t, "server:\n  port: 9000\n")
+
+	_, _, err := config.Load([]string{"-config", path})
+	assert.Error(t, err)
+	assert.Contains(t, err.Error(), "database.password is required")
+	assert.Contains(t, err.Error(), "auth.cookieKey is required")
+}
@@ -0,0 +1,65 @@
+package unittesting
+
+import (
+	"path/filepath"
+	"testing"
+
+	"example.com/go-project/config"
+	"example.com/go-project/model"
+	"github.com/stretchr/testify/assert"
+	"gorm.io/gorm"
+)
+
+// assertCascadeDelete checks that purging a tag detaches its neches through the foreign key
+func assertCascadeDelete(t *testing.T, db *gorm.DB) {
+	err := db.AutoMigrate(&model.Tags{}, &model.Neche{})
+	assert.NoError(t, err)
+
+	db.Create(&model.Tags{Id: 1, Name: "Tag 1"})
+	db.Create(&model.Neche{Id: 1, NecheType: "Neche 1", Tags: []model.Tags{{Id: 1}}})
+
+	err = db.Unscoped().Delete(&model.Tags{}, 1).Error
+	assert.NoError(t, err)
+
+	// The neche stays, it can belong to other tags
+	var count int64
+	db.Model(&model.NecheTag{}).Count(&count)
+	assert.Equal(t, int64(0), count)
+	db.Model(&model.Neche{}).Count(&count)
+	assert.Equal(t, int64(1), count)
+
+	// Neches can't be attached to a tag that doesn't exist
+	err = db.Create(&model.NecheTag{NecheId: 1, TagId: 99}).Error
+	assert.Error(t, err)
+}
+
+func TestOpenDatabase_SQLiteMemory(t *testing.T) {
+	cfg := config.Default().Database
+	cfg.Driver = config.DriverSQLiteMemory
+
+	db, err := config.OpenDatabase(cfg, &gorm.Config{})
+	assert.NoError(t, err)
+	assertCascadeDelete(t, db)
+}
+
+func TestOpenDatabase_SQLiteFile(t *testing.T) {
+	cfg := config.Default().Database
+	cfg.Driver = config.DriverSQLite
+	cfg.Path = filepath.Join(t.TempDir(), "test.db")
+
+	db, err := config.OpenDatabase(cfg, &gorm.Config{})
+	assert.NoError(t, err)
+	assertCascadeDelete(t, db)
+
+	sqlDB, err := db.DB()
+	assert.NoError(t, err)
+	sqlDB.Close()
+}
+
+func TestOpenDatabase_UnknownDriver(t *testing.T) {
+	cfg := config.Default().Database
+	cfg.Driver = "oracle"
+
+	_, err := config.OpenDatabase(cfg, &gorm.Config{})
+	assert.Error(t, err)
+}
@@ -0,0 +1,80 @@
+package unittesting
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"example.com/go-project/data/response"
+	"example.com/go-project/helper"
+	"example.com/go-project/middleware"
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"gorm.io/gorm"
+)
+
+func serveError(t *testing.T, handler gin.HandlerFunc) (int, response.Response) {
+	gin.SetMode(gin.TestMode)
+	router := gin.New()
+	router.Use(middleware.Recovery(), middleware.ErrorHandler())
+	router.GET("/", handler)
+
+	recorder := httptest.NewRecorder()
+	req, _ := http.NewRequest(http.MethodGet, "/", nil)
+	router.ServeHTTP(recorder, req)
+
+	var body response.Response
+	err := json.Unmarshal(recorder.Body.Bytes(), &body)
+	assert.NoError(t, err)
+	return recorder.Code, body
+}
+
+func TestErrorHandlerMapsKinds(t *testing.T) {
+	cases := []struct {
+		err       error
+		status    int
+		errorCode string
+		message   string
+	}{
+		{helper.BadRequest("Invalid tag ID: %s", "abc"), http.StatusBadRequest, "bad_request", "Invalid tag ID: abc"},
+		{helper.Validation("Unknown role: %s", "Root"), http.StatusBadRequest, "validation_failed", "Unknown role: Root"},
+		{helper.Unauthorized("invalid credentials"), http.StatusUnauthorized, "unauthorized", "invalid credentials"},
+		{helper.Forbidden("nope"), http.StatusForbidden, "forbidden", "nope"},
+		{fmt.Errorf("tag 5: %w", helper.NotFound("tag not found")), http.StatusNotFound, "not_found", "tag 5: tag not found"},
+		{helper.Conflict("email already exists"), http.StatusConflict, "conflict", "email already exists"},
+		{gorm.ErrRecordNotFound, http.StatusNotFound, "not_found", "record not found"},
+		{gorm.ErrDuplicatedKey, http.StatusConflict, "conflict", "record already exists"},
+		{errors.New("pq: connection refused"), http.StatusInternalServerError, "internal_error", "internal server error"},
+	}
+
+	for _, tc := range cases {
+		status, body := serveError(t, func(c *gin.Context) {
+			c.Error(tc.err)
+		})
+
+		assert.Equal(t, tc.status, status, tc.err.Error())
+		assert.Equal(t, tc.status, body.Code)
+		assert.Equal(t, "error", body.Status)
+		assert.Equal(t, tc.errorCode, body.ErrorCode)
+		assert.Equal(t, tc.message, body.Data)
+	}
+}
+
+func TestErrorKindsMatchWithErrorsIs(t *testing.T) {
+	err := fmt.Errorf("finding tag: %w", helper.NotFound("tag not found"))
+	assert.True(t, errors.Is(err, helper.ErrNotFound))
+	assert.False(t, errors.Is(err, helper.ErrConflict))
+}
+
+func TestRecoveryRespondsWithEnvelope(t *testing.T) {
+	status, body := serveError(t, func(c *gin.Context) {
+		panic("boom")
+	})
+
+	assert.Equal(t, http.StatusInternalServerError, status)
+	assert.Equal(t, "internal_error", body.ErrorCode)
+	assert.Equal(t, "internal server error", body.Data)
+}
@@ -0,0 +1,144 @@
+package unittesting
+
+import (
+	"bytes"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"example.com/go-project/controller"
+	"example.com/go-project/data/request"
+	"example.com/go-project/helper"
+	"example.com/go-project/middleware"
+	"example.com/go-project/model"
+	"example.com/go-project/model/repository"
+	"example.com/go-project/services"
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+)
+
+func setupETagRouter(t *testing.T) *gin.Engine {
+	necheService, db := setupNecheService(t)
+	assert.NoError(t, db.Create(&model.Tags{Id: 1, Name: "Cuisine"}).Error)
+	_, err := necheService.Create(0, request.CreateNecheRequest{Name: "Pasta", TagIDs: []int{1}})
+	assert.NoError(t, err)
+
+	tagsService := services.NewTagsServiceImpl(repository.NewTagsRepositoryImpl(db), helper.NewValidator())
+	tagsController := controller.NewTagsController(tagsService)
+	necheController := controller.NewNecheController(necheService)
+
+	gin.SetMode(gin.TestMode)
+	router := gin.New()
+	router.Use(middleware.ErrorHandler())
+	router.GET("/tags/:tagId", tagsController.FindById)
+	router.PUT("/tags/:tagId", tagsController.Update)
+	router.PATCH("/tags/:tagId", tagsController.Patch)
+	router.DELETE("/tags/:tagId", tagsController.Delete)
+	router.GET("/neches/:necheId", necheController.FindById)
+	router.PUT("/neches/:necheId", necheController.Update)
+	router.PATCH("/neches/:necheId", necheController.Patch)
+	router.DELETE("/neches/:necheId", necheController.Delete)
+	return router
+}
+
+func sendWithHeaders(router *gin.Engine, method, url, body string, headers map[string]string) *httptest.ResponseRecorder {
+	req, _ := http.NewRequest(method, url, bytes.NewBufferString(body))
+	req.Header.Set("Content-Type", "application/json")
+	for name, value := range headers {
+		req.Header.Set(name, value)
+	}
+	recorder := httptest.NewRecorder()
+	router.ServeHTTP(recorder, req)
+	return recorder
+}
+
+func TestETag_ConditionalGet(t *testing.T) {
+	router := setupETagRouter(t)
+
+	recorder := sendWithHeaders(router, http.MethodGet, "/tags/1", "", nil)
+	assert.Equal(t, http.StatusOK, recorder.Code)
+	assert.Equal(t, `"1"`, recorder.Header().Get("ETag"))
+
+	recorder = sendWithHeaders(router, http.MethodGet, "/tags/1", "", map[string]string{"If-None-Match": `"7", W/"1"`})
+	assert.Equal(t, http.StatusNotModified, recorder.Code)
+	assert.Empty(t, recorder.Body.String())
+
+	recorder = sendWithHeaders(router, http.MethodGet, "/neches/1", "", map[string]string{"If-None-Match": `"1"`})
+	assert.Equal(t, http.StatusNotModified, recorder.Code)
+}
+
+func TestETag_LostUpdateIsRejected(t *testing.T) {
+	router := setupETagRouter(t)
+
+	// Two clients read version 1, the first update wins
+	recorder := sendWithHeaders(router, http.MethodPut, "/tags/1", `{"name":"Food"}`, map[string]string{"If-Match": `"1"`})
+	assert.Equal(t, http.StatusOK, recorder.Code)
+	assert.Equal(t, `"2"`, recorder.Header().Get("ETag"))
+
+	recorder = sendWithHeaders(router, http.MethodPut, "/tags/1", `{"name":"Meals"}`, map[string]string{"If-Match": `"1"`})
+	assert.Equal(t, http.StatusPreconditionFailed, recorder.Code)
+
+	recorder = sendWithHeaders(router, http.MethodGet, "/tags/1", "", nil)
+	assert.Contains(t, recorder.Body.String(), `"name":"Food"`)
+	assert.Equal(t, `"2"`, recorder.Header().Get("ETag"))
+
+	recorder = sendWithHeaders(router, http.MethodPut, "/neches/1", `{"name":"Pizza","tagIds":[1]}`, map[string]string{"If-Match": `"1"`})
+	assert.Equal(t, http.StatusOK, recorder.Code)
+	assert.Equal(t, `"2"`, recorder.Header().Get("ETag"))
+
+	recorder = sendWithHeaders(router, http.MethodDelete, "/neches/1", "", map[string]string{"If-Match": `"1"`})
+	assert.Equal(t, http.StatusPreconditionFailed, recorder.Code)
+
+	recorder = sendWithHeaders(router, http.MethodDelete, "/neches/1", "", map[string]string{"If-Match": `"2"`})
+	assert.Equal(t, http.StatusOK, recorder.Code)
+}
+
+func TestETag_IfMatchList(t *testing.T) {
+	router := setupETagRouter(t)
+
+	// Any of the listed ETags may be the current one, weak ones are skipped
+	recorder := sendWithHeaders(router, http.MethodPut, "/tags/1", `{"name":"Food"}`, map[string]string{"If-Match": `"3", W/"2", "1"`})
+	assert.Equal(t, http.StatusOK, recorder.Code)
+	assert.Equal(t, `"2"`, recorder.Header().Get("ETag"))
+	recorder = sendWithHeaders(router, http.MethodPut, "/tags/1", `{"name":"Meals"}`, map[string]string{"If-Match": `"1", "3"`})
+	assert.Equal(t, http.StatusPreconditionFailed, recorder.Code)
+
+	recorder = sendWithHeaders(router, http.MethodDelete, "/neches/1", "", map[string]string{"If-Match": `"1","2"`})
+	assert.Equal(t, http.StatusOK, recorder.Code)
+	recorder = sendWithHeaders(router, http.MethodDelete, "/neches/1", "", map[string]string{"If-Match": `"1", "2"`})
+	assert.Equal(t, http.StatusNotFound, recorder.Code)
+}
+
+func TestETag_IfMatchRequired(t *testing.T) {
+	router := setupETagRouter(t)
+
+	recorder := sendWithHeaders(router, http.MethodPut, "/tags/1", `{"name":"Food"}`, nil)
+	assert.Equal(t, 428, recorder.Code)
+
+	// Weak tags never match an If-Match
+	recorder = sendWithHeaders(router, http.MethodDelete, "/tags/1", "", map[string]string{"If-Match": `W/"1"`})
+	assert.Equal(t, http.StatusPreconditionFailed, recorder.Code)
+
+	// A wildcard matches any version
+	recorder = sendWithHeaders(router, http.MethodPut, "/tags/1", `{"name":"Food"}`, map[string]string{"If-Match": "*"})
+	assert.Equal(t, http.StatusOK, recorder.Code)
+
+	defer func(required bool) { controller.RequireIfMatch = required }(controller.RequireIfMatch)
+	controller.RequireIfMatch = false
+	recorder = sendWithHeaders(router, http.MethodDelete, "/tags/1", "", nil)
+	assert.Equal(t, http.StatusOK, recorder.Code)
+}
+
+func TestETag_RepositoryChecksVersion(t *testing.T) {
+	_, db := setupNecheService(t)
+	tagsRepository := repository.NewTagsRepositoryImpl(db)
+	assert.NoError(t, db.Create(&model.Tags{Id: 1, Name: "Cuisine"}).Error)
+
+	// The version read before a concurrent update is stale once it's saved
+	tag, err := tagsRepository.FindById(1)
+	assert.NoError(t, err)
+	assert.Equal(t, 1, tag.Version)
+	assert.NoError(t, tagsRepository.Update(0, model.Tags{Id: 1, Name: "Food", Version: tag.Version}))
+	assert.ErrorIs(t, tagsRepository.Update(0, model.Tags{Id: 1, Name: "Meals", Version: tag.Version}), repository.ErrStaleVersion)
+	assert.ErrorIs(t, tagsRepository.Delete(0, 1, tag.Version), repository.ErrStaleVersion)
+}
@@ -0,0 +1,95 @@
+package unittesting
+
+import (
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/x509"
+	"encoding/pem"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"example.com/go-project/auth"
+	"github.com/golang-jwt/jwt"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestKeyring_SignAndValidate(t *testing.T) {
+	for _, alg := range []string{auth.AlgRS256, auth.AlgEdDSA} {
+		keyring, err := auth.LoadKeyring(nil, alg, time.Minute)
+		assert.NoError(t, err)
+		auth.UseKeyring(keyring)
+
+		tokenString, err := auth.GenerateJWT(1, "test@example.com", "User")
+		assert.NoError(t, err)
+
+		token, err := auth.ValidateJWT(tokenString)
+		assert.NoError(t, err)
+		assert.Equal(t, alg, token.Method.Alg())
+		assert.Equal(t, keyring.Current().Kid, token.Header["kid"])
+	}
+}
+
+func TestKeyring_RotationGracePeriod(t *testing.T) {
+	keyring, err := auth.LoadKeyring(nil, auth.AlgEdDSA, time.Hour)
+	assert.NoError(t, err)
+	auth.UseKeyring(keyring)
+
+	oldToken, err := auth.GenerateJWT(1, "test@example.com", "User")
+	assert.NoError(t, err)
+
+	// Tokens signed with the retired key remain valid during the grace period
+	_, err = keyring.Rotate()
+	assert.NoError(t, err)
+	_, err = auth.ValidateJWT(oldToken)
+	assert.NoError(t, err)
+	assert.Len(t, keyring.JWKS().Keys, 2)
+
+	// Without a grace period the retired key is dropped straight away
+	keyring = auth.NewKeyring(0)
+	key, err := auth.GenerateSigningKey(auth.AlgEdDSA)
+	assert.NoError(t, err)
+	keyring.Add(key)
+	auth.UseKeyring(keyring)
+
+	oldToken, err = auth.GenerateJWT(1, "test@example.com", "User")
+	assert.NoError(t, err)
+	_, err = keyring.Rotate()
+	assert.NoError(t, err)
+	time.Sleep(time.Millisecond)
+
+	_, err = auth.ValidateJWT(oldToken)
+	assert.Error(t, err)
+	assert.Len(t, keyring.JWKS().Keys, 1)
+}
+
+func TestKeyring_RejectsHMACTokens(t *testing.T) {
+	keyring, err := auth.LoadKeyring(nil, auth.AlgEdDSA, time.Minute)
+	assert.NoError(t, err)
+	auth.UseKeyring(keyring)
+
+	// A token signed with a shared secret must never be accepted
+	token := jwt.NewWithClaims(jwt.SigningMethodHS256, jwt.MapClaims{"user_id": 1, "role": "Admin"})
+	token.Header["kid"] = keyring.Current().Kid
+	tokenString, err := token.SignedString([]byte("secret"))
+	assert.NoError(t, err)
+
+	_, err = auth.ValidateJWT(tokenString)
+	assert.Error(t, err)
+}
+
+func TestLoadSigningKey_FromPEM(t *testing.T) {
+	privateKey, err := rsa.GenerateKey(rand.Reader, 2048)
+	assert.NoError(t, err)
+
+	keyFile := filepath.Join(t.TempDir(), "signing.pem")
+	pemData := pem.EncodeToMemory(&pem.Block{Type: "RSA PRIVATE KEY", Bytes: x509.MarshalPKCS1PrivateKey(privateKey)})
+	assert.NoError(t, os.WriteFile(keyFile, pemData, 0600))
+
+	key, err := auth.LoadSigningKey(keyFile)
+	assert.NoError(t, err)
+	assert.Equal(t, auth.AlgRS256, key.Method.Alg())
+	assert.NotEmpty(t, key.Kid)
+	assert.Equal(t, "RSA", key.JWK().Kty)
+}
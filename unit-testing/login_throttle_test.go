@@ -0,0 +1,212 @@
+package unittesting
+
+import (
+	"fmt"
+	"net/http"
+	"sync"
+	"testing"
+	"time"
+
+	"example.com/go-project/auth"
+	"example.com/go-project/config"
+	"example.com/go-project/controller"
+	"example.com/go-project/helper"
+	"example.com/go-project/middleware"
+	"example.com/go-project/model"
+	"example.com/go-project/model/repository"
+	"example.com/go-project/services"
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"gorm.io/gorm"
+)
+
+var loginConfig = config.LoginConfig{
+	MaxFailures:     3,
+	MaxIPFailures:   5,
+	BackoffBase:     time.Second,
+	BackoffMax:      4 * time.Second,
+	LockoutDuration: 15 * time.Minute,
+	Window:          10 * time.Minute,
+}
+
+func setupLoginThrottle(t *testing.T, cfg config.LoginConfig) (*services.LoginThrottle, *gorm.DB) {
+	db := setupMigratedDB(t)
+	return services.NewLoginThrottle(repository.NewLoginAttemptRepository(db), cfg), db
+}
+
+func checkLogin(t *testing.T, throttle *services.LoginThrottle, email, ip string, now time.Time) time.Duration {
+	wait, err := throttle.Check(email, ip, now)
+	assert.NoError(t, err)
+	return wait
+}
+
+// failLogin reserves a login and records that its password was wrong
+func failLogin(t *testing.T, throttle *services.LoginThrottle, email, ip string, now time.Time) {
+	wait, err := throttle.Reserve(email, ip, now)
+	assert.NoError(t, err)
+	assert.Zero(t, wait)
+	assert.NoError(t, throttle.Failed(email, ip, now))
+}
+
+func TestLoginThrottle_BackoffAndLockout(t *testing.T) {
+	throttle, _ := setupLoginThrottle(t, loginConfig)
+	start := time.Now()
+	assert.Zero(t, checkLogin(t, throttle, "ann@example.com", "10.0.0.1", start))
+
+	// Every failure doubles the wait, emails are matched like at registration
+	failLogin(t, throttle, "Ann@Example.com", "10.0.0.1", start)
+	assert.Equal(t, time.Second, checkLogin(t, throttle, "ann@example.com", "10.0.0.2", start))
+	assert.Zero(t, checkLogin(t, throttle, "ann@example.com", "10.0.0.2", start.Add(time.Second)))
+	failLogin(t, throttle, "ann@example.com", "10.0.0.1", start.Add(time.Second))
+	assert.Equal(t, 2*time.Second, checkLogin(t, throttle, "ann@example.com", "10.0.0.2", start.Add(time.Second)))
+
+	// The last failure locks the account, from every address
+	failLogin(t, throttle, "ann@example.com", "10.0.0.1", start.Add(3*time.Second))
+	locked := start.Add(3 * time.Second)
+	assert.Equal(t, loginConfig.LockoutDuration, checkLogin(t, throttle, "ann@example.com", "10.0.0.2", locked))
+	assert.Zero(t, checkLogin(t, throttle, "bob@example.com", "10.0.0.2", locked))
+
+	// Once the lockout is over the count starts again
+	unlocked := locked.Add(loginConfig.LockoutDuration)
+	assert.Zero(t, checkLogin(t, throttle, "ann@example.com", "10.0.0.2", unlocked))
+	failLogin(t, throttle, "ann@example.com", "10.0.0.2", unlocked)
+	assert.Equal(t, time.Second, checkLogin(t, throttle, "ann@example.com", "10.0.0.3", unlocked))
+
+	// So it does after a quiet window, and when the account logs in
+	quiet := unlocked.Add(loginConfig.Window)
+	failLogin(t, throttle, "ann@example.com", "10.0.0.3", quiet)
+	assert.Equal(t, time.Second, checkLogin(t, throttle, "ann@example.com", "10.0.0.4", quiet))
+	assert.NoError(t, throttle.Reset("ann@example.com"))
+	assert.Zero(t, checkLogin(t, throttle, "ann@example.com", "10.0.0.4", quiet))
+}
+
+func TestLoginThrottle_PerAddress(t *testing.T) {
+	throttle, db := setupLoginThrottle(t, loginConfig)
+	start := time.Now()
+
+	// Guessing one password for many accounts locks the address
+	for i := 0; i < loginConfig.MaxIPFailures; i++ {
+		email := fmt.Sprintf("user%d@example.com", i)
+		failLogin(t, throttle, email, "10.0.0.1", start.Add(time.Duration(i)*loginConfig.BackoffMax))
+	}
+	locked := start.Add(time.Duration(loginConfig.MaxIPFailures-1) * loginConfig.BackoffMax)
+	assert.Equal(t, loginConfig.LockoutDuration, checkLogin(t, throttle, "new@example.com", "10.0.0.1", locked))
+	assert.Zero(t, checkLogin(t, throttle, "new@example.com", "10.0.0.2", locked))
+
+	// Logging in to an account doesn't unlock the address
+	assert.NoError(t, throttle.Reset("user0@example.com"))
+	assert.Equal(t, loginConfig.LockoutDuration, checkLogin(t, throttle, "user0@example.com", "10.0.0.1", locked))
+
+	// Pruning keeps what still counts
+	pruned, err := throttle.Prune(locked.Add(loginConfig.Window))
+	assert.NoError(t, err)
+	assert.Equal(t, int64(loginConfig.MaxIPFailures-2), pruned)
+	var left int64
+	db.Model(&model.LoginAttempt{}).Count(&left)
+	assert.Equal(t, int64(2), left)
+
+	// Until an admin unlocks the address
+	assert.NoError(t, throttle.ResetAddress("10.0.0.1"))
+	assert.Zero(t, checkLogin(t, throttle, "new@example.com", "10.0.0.1", locked))
+}
+
+// setupLoginRouter serves logins and the unlock endpoints with an account to log in as
+func setupLoginRouter(t *testing.T) (*gin.Engine, *model.Users) {
+	// No backoff, only account lockouts, every request comes from the same address
+	cfg := loginConfig
+	cfg.BackoffBase = time.Nanosecond
+	cfg.MaxIPFailures = 20
+	throttle, db := setupLoginThrottle(t, cfg)
+
+	usersRepo := repository.NewUsersRepository(db)
+	usersService := services.NewUsersService(usersRepo, helper.NewValidator())
+	tokenService := services.NewTokenService(repository.NewRefreshTokenRepository(db), usersRepo, time.Hour)
+	user := &model.Users{Name: "Ann", Email: "ann@example.com", Password: "password", Role: model.RoleUser}
+	assert.NoError(t, usersService.Create(user))
+
+	key, err := auth.GenerateSigningKey(auth.AlgEdDSA)
+	assert.NoError(t, err)
+	keyring := auth.NewKeyring(time.Minute)
+	keyring.Add(key)
+	auth.UseKeyring(keyring)
+
+	gin.SetMode(gin.TestMode)
+	router := gin.New()
+	router.Use(middleware.ErrorHandler())
+	userController := controller.NewUsersController(usersService, tokenService, throttle)
+	router.POST("/login", userController.Login)
+	router.POST("/users/:userId/unlock", userController.Unlock)
+	router.POST("/addresses/:ip/unlock", userController.UnlockAddress)
+	return router, user
+}
+
+func login(router *gin.Engine, email, password string) (int, string, string) {
+	recorder := sendWithHeaders(router, http.MethodPost, "/login", `{"email":"`+email+`","password":"`+password+`"}`, nil)
+	return recorder.Code, recorder.Header().Get("Retry-After"), recorder.Body.String()
+}
+
+func TestLoginThrottle_LockedLogin(t *testing.T) {
+	router, user := setupLoginRouter(t)
+
+	// An unknown email is answered exactly like an account
+	var bodies []string
+	for _, email := range []string{"ann@example.com", "nobody@example.com"} {
+		for i := 0; i < loginConfig.MaxFailures; i++ {
+			code, _, _ := login(router, email, "wrong")
+			assert.Equal(t, http.StatusUnauthorized, code)
+		}
+		code, retryAfter, body := login(router, email, "wrong")
+		assert.Equal(t, http.StatusTooManyRequests, code)
+		assert.Equal(t, "900", retryAfter)
+		bodies = append(bodies, body)
+	}
+	assert.Equal(t, bodies[0], bodies[1])
+	assert.Contains(t, bodies[0], `"too_many_requests"`)
+
+	// The right password has to wait as well, until an admin unlocks the account
+	code, _, _ := login(router, "ann@example.com", "password")
+	assert.Equal(t, http.StatusTooManyRequests, code)
+	recorder := sendWithHeaders(router, http.MethodPost, fmt.Sprintf("/users/%d/unlock", user.Id), "", nil)
+	assert.Equal(t, http.StatusOK, recorder.Code)
+	code, retryAfter, _ := login(router, "ann@example.com", "password")
+	assert.Equal(t, http.StatusOK, code)
+	assert.Empty(t, retryAfter)
+
+	recorder = sendWithHeaders(router, http.MethodPost, "/users/99/unlock", "", nil)
+	assert.Equal(t, http.StatusNotFound, recorder.Code)
+
+	// Addresses are unlocked the same way
+	recorder = sendWithHeaders(router, http.MethodPost, "/addresses/2001:db8::1/unlock", "", nil)
+	assert.Equal(t, http.StatusOK, recorder.Code)
+	recorder = sendWithHeaders(router, http.MethodPost, "/addresses/nowhere/unlock", "", nil)
+	assert.Equal(t, http.StatusBadRequest, recorder.Code)
+}
+
+func TestLoginThrottle_ConcurrentGuesses(t *testing.T) {
+	router, _ := setupLoginRouter(t)
+
+	// Guesses sent together are counted before the password is checked, only
+	// the ones up to the limit get to it and are answered 401
+	const guesses = 10
+	codes := make(chan int, guesses)
+	var wg sync.WaitGroup
+	for i := 0; i < guesses; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			code, _, _ := login(router, "ann@example.com", "wrong")
+			codes <- code
+		}()
+	}
+	wg.Wait()
+	close(codes)
+
+	answered := map[int]int{}
+	for code := range codes {
+		answered[code]++
+	}
+	assert.Equal(t, map[int]int{
+		http.StatusUnauthorized:    loginConfig.MaxFailures,
+		http.StatusTooManyRequests: guesses - loginConfig.MaxFailures,
+	}, answered)
+}
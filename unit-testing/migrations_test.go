@@ -0,0 +1,139 @@
+package unittesting
+
+import (
+	"testing"
+	"time"
+
+	"example.com/go-project/config"
+	"example.com/go-project/migrations"
+	"example.com/go-project/model"
+	"github.com/stretchr/testify/assert"
+	"gorm.io/gorm"
+)
+
+func setupMigrationsDB(t *testing.T) *gorm.DB {
+	cfg := config.Default().Database
+	cfg.Driver = config.DriverSQLiteMemory
+
+	db, err := config.OpenDatabase(cfg, &gorm.Config{})
+	if err != nil {
+		t.Fatalf("could not connect to db: %v", err)
+	}
+	return db
+}
+
+// setupMigratedDB returns a database with every migration applied
+func setupMigratedDB(t *testing.T) *gorm.DB {
+	db := setupMigrationsDB(t)
+	_, err := migrations.New(db).Up()
+	if err != nil {
+		t.Fatalf("could not migrate db: %v", err)
+	}
+	return db
+}
+
+func TestMigrations_UpDownStatus(t *testing.T) {
+	db := setupMigrationsDB(t)
+	migrator := migrations.New(db)
+
+	// Everything is pending on an empty database
+	statuses, err := migrator.Status()
+	assert.NoError(t, err)
+	assert.NotEmpty(t, statuses)
+	assert.Nil(t, statuses[0].AppliedAt)
+
+	applied, err := migrator.Up()
+	assert.NoError(t, err)
+	assert.Len(t, applied, len(migrations.All()))
+	assert.True(t, db.Migrator().HasTable(&model.Tags{}))
+	assert.True(t, db.Migrator().HasTable(&model.Users{}))
+
+	// Running again is a no-op
+	applied, err = migrator.Up()
+	assert.NoError(t, err)
+	assert.Empty(t, applied)
+
+	statuses, err = migrator.Status()
+	assert.NoError(t, err)
+	for _, status := range statuses {
+		assert.NotNil(t, status.AppliedAt)
+	}
+
+	// The migrated schema behaves like the models expect
+	assert.NoError(t, db.Create(&model.Tags{Id: 1, Name: "Tag 1"}).Error)
+	assert.NoError(t, db.Create(&model.Neche{Id: 1, NecheType: "Neche 1", Tags: []model.Tags{{Id: 1}}}).Error)
+
+	// Rolling everything back leaves only the bookkeeping tables
+	reverted, err := migrator.Down(len(migrations.All()))
+	assert.NoError(t, err)
+	assert.Len(t, reverted, len(migrations.All()))
+	assert.False(t, db.Migrator().HasTable(&model.Tags{}))
+}
+
+func TestMigrations_AdoptsAutoMigratedSchema(t *testing.T) {
+	db := setupMigrationsDB(t)
+
+	// Databases created before migrations existed already have the baseline tables
+	err := db.AutoMigrate(&model.Tags{}, &model.Neche{}, &model.Users{})
+	assert.NoError(t, err)
+	db.Create(&model.Tags{Id: 1, Name: "Existing"})
+
+	_, err = migrations.New(db).Up()
+	assert.NoError(t, err)
+
+	var tag model.Tags
+	assert.NoError(t, db.First(&tag, 1).Error)
+	assert.Equal(t, "Existing", tag.Name)
+}
+
+func TestMigrations_Lock(t *testing.T) {
+	db := setupMigrationsDB(t)
+
+	var calls []int
+	step := func(version int64) migrations.Migration {
+		return migrations.Migration{
+			Version: version,
+			Name:    "step",
+			Up: func(tx *gorm.DB) error {
+				calls = append(calls, int(version))
+				return nil
+			},
+		}
+	}
+
+	first := migrations.NewWithMigrations(db, []migrations.Migration{step(1)})
+	_, err := first.Status() // Creates the bookkeeping tables
+	assert.NoError(t, err)
+
+	// Simulate another runner holding the lock
+	err = db.Exec("INSERT INTO schema_migrations_lock (id, owner, locked_at) VALUES (1, 'other', ?)", time.Now()).Error
+	assert.NoError(t, err)
+
+	first.LockTimeout = 0
+	_, err = first.Up()
+	assert.ErrorIs(t, err, migrations.ErrLocked)
+	assert.Empty(t, calls)
+
+	// A lock left behind by a crashed runner is taken over
+	first.StaleLockAfter = 0
+	_, err = first.Up()
+	assert.NoError(t, err)
+	assert.Equal(t, []int{1}, calls)
+}
+
+func TestMigrations_LockInsertFails(t *testing.T) {
+	db := setupMigrationsDB(t)
+	migrator := migrations.NewWithMigrations(db, nil)
+	_, err := migrator.Status() // Creates the bookkeeping tables
+	assert.NoError(t, err)
+
+	// The lock can't be inserted but no other runner holds it either
+	err = db.Exec("CREATE TRIGGER no_lock BEFORE INSERT ON schema_migrations_lock BEGIN SELECT RAISE(ABORT, 'read only'); END").Error
+	assert.NoError(t, err)
+
+	migrator.LockTimeout = time.Second
+	start := time.Now()
+	_, err = migrator.Up()
+	assert.ErrorContains(t, err, "read only")
+	assert.Less(t, time.Since(start), 5*time.Second)
+}
@@ -0,0 +1,94 @@
+package unittesting
+
+import (
+	"errors"
+	"log"
+	"testing"
+
+	"example.com/go-project/data/request"
+	"example.com/go-project/data/response"
+	"example.com/go-project/helper"
+	"example.com/go-project/model"
+	"example.com/go-project/model/repository"
+	"example.com/go-project/services"
+	"github.com/go-playground/validator"
+	"github.com/stretchr/testify/assert"
+	"gorm.io/gorm"
+)
+
+func setupNecheService(t *testing.T) (services.NecheService, *gorm.DB) {
+	db := setupMigratedDB(t)
+
+	tagsRepository := repository.NewTagsRepositoryImpl(db)
+	necheRepository := repository.NewNecheRepositoryImpl(db)
+	return services.NewNecheServiceImpl(necheRepository, validator.New(), tagsRepository), db
+}
+
+func TestNecheService_CreateAndUpdate(t *testing.T) {
+	log.Print("\n\n\n Running Neche Service Test Cases.....\n\n\n")
+	necheService, db := setupNecheService(t)
+	assert.NoError(t, db.Create(&model.Tags{Id: 1, Name: "Cuisine"}).Error)
+	assert.NoError(t, db.Create(&model.Tags{Id: 2, Name: "Travel"}).Error)
+
+	neche, err := necheService.Create(0, request.CreateNecheRequest{Name: "Pasta", TagIDs: []int{1}})
+	assert.NoError(t, err)
+	assert.NotZero(t, neche.Id)
+
+	// Rename and move to another tag in one go
+	updated, err := necheService.Update(0, request.UpdateNecheRequest{Id: neche.Id, Name: "Pizza", TagIDs: []int{2}})
+	assert.NoError(t, err)
+	assert.Equal(t, "Pizza", updated.Name)
+	assert.Equal(t, []response.NecheTagResponse{{Id: 2, Name: "Travel"}}, updated.Tags)
+
+	found, err := necheService.FindById(neche.Id)
+	assert.NoError(t, err)
+	assert.Equal(t, updated, found)
+
+	// Moving to a tag that doesn't exist is rejected
+	_, err = necheService.Update(0, request.UpdateNecheRequest{Id: neche.Id, Name: "Pizza", TagIDs: []int{99}})
+	assert.ErrorIs(t, err, services.ErrTagNotFound)
+
+	_, err = necheService.Update(0, request.UpdateNecheRequest{Id: 99, Name: "Pizza", TagIDs: []int{1}})
+	assert.ErrorIs(t, err, services.ErrNecheNotFound)
+
+	// Validation errors come back as they are, like those of the tags service
+	_, err = necheService.Update(0, request.UpdateNecheRequest{Id: neche.Id, Name: "", TagIDs: []int{1}})
+	assert.IsType(t, validator.ValidationErrors{}, err)
+
+	assert.NoError(t, necheService.Delete(0, neche.Id, 0))
+	assert.ErrorIs(t, necheService.Delete(0, neche.Id, 0), services.ErrNecheNotFound)
+}
+
+func TestNecheService_FindByTagId(t *testing.T) {
+	necheService, db := setupNecheService(t)
+	assert.NoError(t, db.Create(&model.Tags{Id: 1, Name: "Cuisine"}).Error)
+	assert.NoError(t, db.Create(&model.Tags{Id: 2, Name: "Travel"}).Error)
+	for _, name := range []string{"Pasta", "Pizza", "Risotto"} {
+		_, err := necheService.Create(0, request.CreateNecheRequest{Name: name, TagIDs: []int{1}})
+		assert.NoError(t, err)
+	}
+	_, err := necheService.Create(0, request.CreateNecheRequest{Name: "Beach", TagIDs: []int{2}})
+	assert.NoError(t, err)
+
+	page, err := necheService.FindByTagId(1, false, request.PageRequest{Limit: 2})
+	assert.NoError(t, err)
+	assert.Equal(t, int64(3), page.Total)
+	assert.Len(t, page.Neches, 2)
+	assert.Equal(t, "Pasta", page.Neches[0].Name)
+
+	page, err = necheService.FindByTagId(1, false, request.PageRequest{Limit: 2, Offset: 2})
+	assert.NoError(t, err)
+	assert.Len(t, page.Neches, 1)
+	assert.Equal(t, "Risotto", page.Neches[0].Name)
+
+	_, err = necheService.FindByTagId(99, false, request.PageRequest{Limit: 10})
+	assert.ErrorIs(t, err, services.ErrTagNotFound)
+
+	// Only a missing tag is not found, other failures are passed on
+	failing := new(MockTagsRepository)
+	failing.On("FindById", 1).Return(model.Tags{}, errors.New("connection refused"))
+	necheService = services.NewNecheServiceImpl(repository.NewNecheRepositoryImpl(db), validator.New(), failing)
+	_, err = necheService.FindByTagId(1, false, request.PageRequest{Limit: 10})
+	assert.EqualError(t, err, "connection refused")
+	assert.NotErrorIs(t, err, helper.ErrNotFound)
+}
@@ -0,0 +1,157 @@
+package unittesting
+
+import (
+	"net/http"
+	"testing"
+
+	"example.com/go-project/controller"
+	"example.com/go-project/data/request"
+	"example.com/go-project/helper"
+	"example.com/go-project/middleware"
+	"example.com/go-project/migrations"
+	"example.com/go-project/model"
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestNecheTagsMigration_Backfill(t *testing.T) {
+	db := setupMigrationsDB(t)
+
+	// Neches of the old schema have a single tag_id
+	_, err := migrations.NewWithMigrations(db, migrations.All()[:5]).Up()
+	assert.NoError(t, err)
+	assert.NoError(t, db.Exec("INSERT INTO tags (id, name) VALUES (1, 'Cuisine'), (2, 'Travel')").Error)
+	assert.NoError(t, db.Exec("INSERT INTO neches (id, neche_type, tag_id) VALUES (1, 'Pasta', 1), (2, 'Beach', 2)").Error)
+
+	migrator := migrations.NewWithMigrations(db, migrations.All()[:6])
+	_, err = migrator.Up()
+	assert.NoError(t, err)
+	assert.False(t, db.Migrator().HasColumn("neches", "tag_id"))
+
+	var necheTags []model.NecheTag
+	assert.NoError(t, db.Order("neche_id").Find(&necheTags).Error)
+	assert.Equal(t, []model.NecheTag{{NecheId: 1, TagId: 1}, {NecheId: 2, TagId: 2}}, necheTags)
+
+	// Rolling back keeps the first tag of every neche, deleting a tag still cascades
+	assert.NoError(t, db.Create(&model.NecheTag{NecheId: 1, TagId: 2}).Error)
+	_, err = migrator.Down(1)
+	assert.NoError(t, err)
+
+	var tagIds []int
+	assert.NoError(t, db.Raw("SELECT tag_id FROM neches ORDER BY id").Scan(&tagIds).Error)
+	assert.Equal(t, []int{1, 2}, tagIds)
+	assert.NoError(t, db.Exec("DELETE FROM tags WHERE id = 2").Error)
+	var count int64
+	db.Table("neches").Count(&count)
+	assert.Equal(t, int64(1), count)
+}
+
+func setupNecheTagsRouter(t *testing.T) *gin.Engine {
+	necheService, db := setupNecheService(t)
+	assert.NoError(t, db.Create(&[]model.Tags{{Id: 1, Name: "Cuisine"}, {Id: 2, Name: "Italy"}}).Error)
+	for _, name := range []string{"Pasta", "Pizza", "Sushi"} {
+		_, err := necheService.Create(0, request.CreateNecheRequest{Name: name, TagIDs: []int{1}})
+		assert.NoError(t, err)
+	}
+
+	gin.SetMode(gin.TestMode)
+	router := gin.New()
+	router.Use(middleware.ErrorHandler())
+	necheController := controller.NewNecheController(necheService)
+	router.POST("/neches", necheController.Create)
+	router.PUT("/neches/:necheId", necheController.Update)
+	router.PATCH("/neches/:necheId", necheController.Patch)
+	router.GET("/neches/:necheId", necheController.FindById)
+	router.GET("/tags/:tagId/neches", necheController.FindByTagId)
+	router.POST("/tags/:tagId/neches", necheController.AttachMany)
+	router.POST("/tags/:tagId/neches/:necheId", necheController.Attach)
+	router.DELETE("/tags/:tagId/neches/:necheId", necheController.Detach)
+	return router
+}
+
+func TestNecheTags_AttachAndDetach(t *testing.T) {
+	router := setupNecheTagsRouter(t)
+
+	recorder := sendWithHeaders(router, http.MethodPost, "/tags/2/neches/1", "", nil)
+	assert.Equal(t, http.StatusOK, recorder.Code)
+
+	// Attaching changes the tags of the neche, and so its version
+	recorder = sendWithHeaders(router, http.MethodGet, "/neches/1", "", nil)
+	assert.Contains(t, recorder.Body.String(), `"tags":[{"id":1,"name":"Cuisine"},{"id":2,"name":"Italy"}]`)
+	assert.Equal(t, `"2"`, recorder.Header().Get("ETag"))
+
+	// Attaching again changes nothing
+	recorder = sendWithHeaders(router, http.MethodPost, "/tags/2/neches/1", "", nil)
+	assert.Equal(t, http.StatusOK, recorder.Code)
+	recorder = sendWithHeaders(router, http.MethodGet, "/neches/1", "", nil)
+	assert.Equal(t, `"2"`, recorder.Header().Get("ETag"))
+
+	recorder = sendWithHeaders(router, http.MethodDelete, "/tags/1/neches/1", "", nil)
+	assert.Equal(t, http.StatusOK, recorder.Code)
+	recorder = sendWithHeaders(router, http.MethodDelete, "/tags/1/neches/1", "", nil)
+	assert.Equal(t, http.StatusNotFound, recorder.Code)
+
+	recorder = sendWithHeaders(router, http.MethodGet, "/neches/1", "", nil)
+	assert.Contains(t, recorder.Body.String(), `"tags":[{"id":2,"name":"Italy"}]`)
+
+	recorder = sendWithHeaders(router, http.MethodPost, "/tags/9/neches/1", "", nil)
+	assert.Equal(t, http.StatusNotFound, recorder.Code)
+}
+
+func TestNecheTags_BulkAttach(t *testing.T) {
+	router := setupNecheTagsRouter(t)
+
+	// Nothing is attached when one of the neches doesn't exist
+	recorder := sendWithHeaders(router, http.MethodPost, "/tags/2/neches", `{"necheIds":[2,7,8]}`, nil)
+	assert.Equal(t, http.StatusNotFound, recorder.Code)
+	assert.Contains(t, recorder.Body.String(), "neches with IDs [7 8]")
+
+	recorder = sendWithHeaders(router, http.MethodPost, "/tags/2/neches", `{"necheIds":[]}`, nil)
+	assert.Equal(t, http.StatusBadRequest, recorder.Code)
+
+	recorder = sendWithHeaders(router, http.MethodPost, "/tags/2/neches", `{"necheIds":[1,2,2]}`, nil)
+	assert.Equal(t, http.StatusOK, recorder.Code)
+
+	_, body := getPage(t, router, "/tags/2/neches")
+	assert.Equal(t, []interface{}{"Pasta", "Pizza"}, necheNames(body.Data))
+	_, body = getPage(t, router, "/tags/1/neches")
+	assert.Equal(t, int64(3), body.Total)
+}
+
+func TestNecheTags_LegacyTagId(t *testing.T) {
+	router := setupNecheTagsRouter(t)
+	ifMatch := map[string]string{"If-Match": "*"}
+	patchHeaders := func(contentType string) map[string]string {
+		return map[string]string{"Content-Type": contentType, "If-Match": "*"}
+	}
+
+	// Clients from before neches had several tags still send and read one tagId
+	recorder := sendWithHeaders(router, http.MethodPost, "/neches", `{"name":"Ramen","tagId":2}`, nil)
+	assert.Equal(t, http.StatusOK, recorder.Code)
+	assert.Contains(t, recorder.Body.String(), `"tags":[{"id":2,"name":"Italy"}],"tagId":2`)
+
+	recorder = sendWithHeaders(router, http.MethodPut, "/neches/4", `{"name":"Ramen","tagId":1}`, ifMatch)
+	assert.Equal(t, http.StatusOK, recorder.Code)
+	assert.Contains(t, recorder.Body.String(), `"tags":[{"id":1,"name":"Cuisine"}],"tagId":1`)
+
+	// tagIds wins when both are given, the first tag stands in for tagId
+	recorder = sendWithHeaders(router, http.MethodPut, "/neches/4", `{"name":"Ramen","tagIds":[2,1],"tagId":2}`, ifMatch)
+	assert.Equal(t, http.StatusOK, recorder.Code)
+	assert.Contains(t, recorder.Body.String(), `"tags":[{"id":1,"name":"Cuisine"},{"id":2,"name":"Italy"}],"tagId":1`)
+
+	// Patches can change tagId alone, or together with tagIds which win again
+	recorder = sendWithHeaders(router, http.MethodPatch, "/neches/4", `{"tagId":2}`, patchHeaders(helper.MergePatchType))
+	assert.Equal(t, http.StatusOK, recorder.Code)
+	assert.Contains(t, recorder.Body.String(), `"tags":[{"id":2,"name":"Italy"}],"tagId":2`)
+	recorder = sendWithHeaders(router, http.MethodPatch, "/neches/4", `[{"op":"replace","path":"/tagId","value":1}]`, patchHeaders(helper.JSONPatchType))
+	assert.Equal(t, http.StatusOK, recorder.Code)
+	assert.Contains(t, recorder.Body.String(), `"tags":[{"id":1,"name":"Cuisine"}],"tagId":1`)
+	recorder = sendWithHeaders(router, http.MethodPatch, "/neches/4", `{"tagIds":[1,2],"tagId":2}`, patchHeaders(helper.MergePatchType))
+	assert.Equal(t, http.StatusOK, recorder.Code)
+	assert.Contains(t, recorder.Body.String(), `"tags":[{"id":1,"name":"Cuisine"},{"id":2,"name":"Italy"}],"tagId":1`)
+
+	recorder = sendWithHeaders(router, http.MethodPost, "/neches", `{"name":"Ramen"}`, nil)
+	assert.Equal(t, http.StatusBadRequest, recorder.Code)
+	recorder = sendWithHeaders(router, http.MethodPost, "/neches", `{"name":"Ramen","tagId":-1}`, nil)
+	assert.Equal(t, http.StatusBadRequest, recorder.Code)
+}
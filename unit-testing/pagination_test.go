@@ -0,0 +1,114 @@
+package unittesting
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"example.com/go-project/controller"
+	"example.com/go-project/data/request"
+	"example.com/go-project/data/response"
+	"example.com/go-project/middleware"
+	"example.com/go-project/model"
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"gorm.io/gorm"
+)
+
+func setupNechePagination(t *testing.T) (*gin.Engine, *gorm.DB) {
+	necheService, db := setupNecheService(t)
+	assert.NoError(t, db.Create(&model.Tags{Id: 1, Name: "Cuisine"}).Error)
+	for _, name := range []string{"Pasta", "Pizza", "Risotto", "Sushi", "Tacos"} {
+		_, err := necheService.Create(0, request.CreateNecheRequest{Name: name, TagIDs: []int{1}})
+		assert.NoError(t, err)
+	}
+
+	gin.SetMode(gin.TestMode)
+	router := gin.New()
+	router.Use(middleware.ErrorHandler())
+	necheController := controller.NewNecheController(necheService)
+	router.GET("/neches", necheController.FindAll)
+	return router, db
+}
+
+func getPage(t *testing.T, router *gin.Engine, url string) (*httptest.ResponseRecorder, response.PaginatedResponse) {
+	recorder := httptest.NewRecorder()
+	req, _ := http.NewRequest(http.MethodGet, url, nil)
+	router.ServeHTTP(recorder, req)
+
+	var body response.PaginatedResponse
+	assert.NoError(t, json.Unmarshal(recorder.Body.Bytes(), &body))
+	return recorder, body
+}
+
+func TestPagination_TotalsAndLinks(t *testing.T) {
+	router, _ := setupNechePagination(t)
+
+	recorder, body := getPage(t, router, "/neches?page=2&pageSize=2")
+	assert.Equal(t, http.StatusOK, recorder.Code)
+	assert.Len(t, body.Data, 2)
+	assert.Equal(t, int64(5), body.Total)
+	assert.Equal(t, 2, body.Page)
+	assert.Equal(t, 2, body.PageSize)
+	assert.Equal(t, 3, body.TotalPages)
+	assert.Equal(t, 2, body.Offset)
+	assert.Equal(t, "/neches?page=3&pageSize=2", body.Next)
+	assert.Equal(t, "/neches?page=1&pageSize=2", body.Prev)
+	assert.Equal(t,
+		`</neches?page=3&pageSize=2>; rel="next", </neches?page=1&pageSize=2>; rel="prev", `+
+			`</neches?page=1&pageSize=2>; rel="first", </neches?page=3&pageSize=2>; rel="last"`,
+		recorder.Header().Get("Link"))
+
+	// The last page has no next link
+	_, body = getPage(t, router, "/neches?page=3&pageSize=2")
+	assert.Len(t, body.Data, 1)
+	assert.Empty(t, body.Next)
+	assert.Equal(t, "/neches?page=2&pageSize=2", body.Prev)
+}
+
+func TestPagination_CapsPageSize(t *testing.T) {
+	router, _ := setupNechePagination(t)
+	defer func(size int) { controller.MaxPageSize = size }(controller.MaxPageSize)
+	controller.MaxPageSize = 3
+
+	_, body := getPage(t, router, "/neches?pageSize=1000")
+	assert.Len(t, body.Data, 3)
+	assert.Equal(t, 3, body.PageSize)
+	assert.Equal(t, 2, body.TotalPages)
+	assert.Empty(t, body.Prev)
+	assert.Equal(t, "/neches?page=2&pageSize=3", body.Next)
+}
+
+func TestPagination_CursorsSurviveChanges(t *testing.T) {
+	router, db := setupNechePagination(t)
+
+	_, first := getPage(t, router, "/neches?pageSize=2")
+	assert.Equal(t, []interface{}{"Pasta", "Pizza"}, necheNames(first.Data))
+	assert.NotEmpty(t, first.NextCursor)
+
+	// Removing a row of the first page shifts the next page by offset, Risotto is skipped
+	assert.NoError(t, db.Where("neche_type = ?", "Pasta").Delete(&model.Neche{}).Error)
+	_, byOffset := getPage(t, router, "/neches?pageSize=2&page=2")
+	assert.Equal(t, []interface{}{"Sushi", "Tacos"}, necheNames(byOffset.Data))
+
+	// The cursor continues right after Pizza, the page number is ignored
+	recorder, byCursor := getPage(t, router, "/neches?pageSize=2&page=7&cursor="+first.NextCursor)
+	assert.Equal(t, http.StatusOK, recorder.Code)
+	assert.Equal(t, []interface{}{"Risotto", "Sushi"}, necheNames(byCursor.Data))
+	assert.Equal(t, 0, byCursor.Page)
+	assert.Contains(t, byCursor.Next, "cursor=")
+	assert.NotContains(t, byCursor.Next, "page=")
+	assert.Contains(t, recorder.Header().Get("Link"), `rel="prev"`)
+
+	recorder, _ = getPage(t, router, "/neches?cursor=forged")
+	assert.Equal(t, http.StatusBadRequest, recorder.Code)
+}
+
+func necheNames(data interface{}) []interface{} {
+	var names []interface{}
+	for _, neche := range data.([]interface{}) {
+		names = append(names, neche.(map[string]interface{})["name"])
+	}
+	return names
+}
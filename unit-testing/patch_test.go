@@ -0,0 +1,96 @@
+package unittesting
+
+import (
+	"net/http"
+	"testing"
+
+	"example.com/go-project/helper"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestApplyPatch_MergePatch(t *testing.T) {
+	// The example of RFC 7386, null removes a member and arrays are replaced
+	patched, err := helper.ApplyPatch(helper.MergePatchType,
+		[]byte(`{"title":"Goodbye!","author":{"givenName":"John","familyName":"Doe"},"tags":["example","sample"],"content":"This will be unchanged"}`),
+		[]byte(`{"title":"Hello!","phoneNumber":"+01-123-456-7890","author":{"familyName":null},"tags":["example"]}`))
+	assert.NoError(t, err)
+	assert.JSONEq(t,
+		`{"title":"Hello!","author":{"givenName":"John"},"tags":["example"],"content":"This will be unchanged","phoneNumber":"+01-123-456-7890"}`,
+		string(patched))
+}
+
+func TestApplyPatch_JSONPatch(t *testing.T) {
+	document := []byte(`{"foo":["bar","baz"],"a~b":{"c/d":1},"n":1.0}`)
+
+	patched, err := helper.ApplyPatch(helper.JSONPatchType, document, []byte(`[
+		{"op":"test","path":"/n","value":1},
+		{"op":"test","path":"/a~0b/c~1d","value":1},
+		{"op":"add","path":"/foo/1","value":"qux"},
+		{"op":"add","path":"/foo/-","value":"end"},
+		{"op":"remove","path":"/foo/0"},
+		{"op":"replace","path":"/n","value":2},
+		{"op":"copy","from":"/foo","path":"/copied"},
+		{"op":"move","from":"/a~0b","path":"/moved"}
+	]`))
+	assert.NoError(t, err)
+	assert.JSONEq(t, `{"foo":["qux","baz","end"],"copied":["qux","baz","end"],"moved":{"c/d":1},"n":2}`, string(patched))
+
+	// A failed test fails the whole patch
+	_, err = helper.ApplyPatch(helper.JSONPatchType, document, []byte(`[{"op":"test","path":"/foo/0","value":"baz"}]`))
+	assert.ErrorIs(t, err, helper.ErrConflict)
+
+	_, err = helper.ApplyPatch(helper.JSONPatchType, document, []byte(`[{"op":"replace","path":"/missing","value":1}]`))
+	assert.ErrorIs(t, err, helper.ErrConflict)
+
+	_, err = helper.ApplyPatch(helper.JSONPatchType, document, []byte(`[{"op":"move","from":"/a~0b","path":"/a~0b/child"}]`))
+	assert.ErrorIs(t, err, helper.ErrConflict)
+
+	_, err = helper.ApplyPatch(helper.JSONPatchType, document, []byte(`[{"op":"add","path":"/foo/01","value":1}]`))
+	assert.ErrorIs(t, err, helper.ErrConflict)
+
+	_, err = helper.ApplyPatch(helper.JSONPatchType, document, []byte(`[{"op":"shout","path":"/foo"}]`))
+	assert.ErrorIs(t, err, helper.ErrBadRequest)
+
+	_, err = helper.ApplyPatch("text/plain", document, []byte(`{}`))
+	assert.ErrorIs(t, err, helper.ErrUnsupportedMediaType)
+}
+
+func TestPatchEndpoints(t *testing.T) {
+	router := setupETagRouter(t)
+	ifMatch := func(contentType, version string) map[string]string {
+		return map[string]string{"Content-Type": contentType, "If-Match": version}
+	}
+
+	recorder := sendWithHeaders(router, http.MethodPatch, "/tags/1", `{"name":"Food"}`, ifMatch(helper.MergePatchType, `"1"`))
+	assert.Equal(t, http.StatusOK, recorder.Code)
+	assert.Equal(t, `"2"`, recorder.Header().Get("ETag"))
+	assert.Contains(t, recorder.Body.String(), `"name":"Food"`)
+
+	// The test operation guards against the tag having been renamed
+	recorder = sendWithHeaders(router, http.MethodPatch, "/tags/1",
+		`[{"op":"test","path":"/name","value":"Cuisine"},{"op":"replace","path":"/name","value":"Meals"}]`, ifMatch(helper.JSONPatchType, "*"))
+	assert.Equal(t, http.StatusConflict, recorder.Code)
+
+	// The patched tag is validated like a new one, and ids can't be patched
+	recorder = sendWithHeaders(router, http.MethodPatch, "/tags/1", `[{"op":"remove","path":"/name"}]`, ifMatch(helper.JSONPatchType, `"2"`))
+	assert.Equal(t, http.StatusBadRequest, recorder.Code)
+	assert.Contains(t, recorder.Body.String(), `"field":"name"`)
+
+	recorder = sendWithHeaders(router, http.MethodPatch, "/tags/1", `{"id":5}`, ifMatch(helper.MergePatchType, `"2"`))
+	assert.Equal(t, http.StatusBadRequest, recorder.Code)
+
+	recorder = sendWithHeaders(router, http.MethodPatch, "/tags/1", `name=Food`, ifMatch("application/x-www-form-urlencoded", `"2"`))
+	assert.Equal(t, http.StatusUnsupportedMediaType, recorder.Code)
+	assert.Equal(t, helper.MergePatchType+", "+helper.JSONPatchType, recorder.Header().Get("Accept-Patch"))
+
+	// Neches can be added to more tags, they have to exist
+	recorder = sendWithHeaders(router, http.MethodPatch, "/neches/1", `[{"op":"add","path":"/tagIds/-","value":9}]`, ifMatch(helper.JSONPatchType, `"1"`))
+	assert.Equal(t, http.StatusNotFound, recorder.Code)
+
+	recorder = sendWithHeaders(router, http.MethodPatch, "/neches/1", `{"name":"Pizza"}`, ifMatch("application/json", `"1"`))
+	assert.Equal(t, http.StatusOK, recorder.Code)
+	assert.Contains(t, recorder.Body.String(), `"name":"Pizza","tags":[{"id":1,"name":"Food"}]`)
+
+	recorder = sendWithHeaders(router, http.MethodPatch, "/neches/1", `{"name":"Risotto"}`, ifMatch(helper.MergePatchType, `"1"`))
+	assert.Equal(t, http.StatusPreconditionFailed, recorder.Code)
+}
@@ -0,0 +1,105 @@
+package unittesting
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"example.com/go-project/auth"
+	"example.com/go-project/data/request"
+	"example.com/go-project/model"
+	"example.com/go-project/model/repository"
+	"example.com/go-project/services"
+	"github.com/gin-gonic/gin"
+	"github.com/go-playground/validator"
+	"github.com/stretchr/testify/assert"
+)
+
+func setupPermissionService(t *testing.T) (*services.PermissionService, *repository.PermissionRepository) {
+	db := setupMigratedDB(t)
+
+	permissionRepo := repository.NewPermissionRepository(db)
+	return services.NewPermissionService(permissionRepo, validator.New()), permissionRepo
+}
+
+func TestPermissionService_SeededDefaults(t *testing.T) {
+	_, permissionRepo := setupPermissionService(t)
+
+	admin, err := permissionRepo.PermissionsForRole("Admin")
+	assert.NoError(t, err)
+	assert.Contains(t, admin, "tags:delete")
+	assert.Contains(t, admin, "roles:manage")
+
+	viewer, err := permissionRepo.PermissionsForRole("Viewer")
+	assert.NoError(t, err)
+	assert.ElementsMatch(t, []string{"tags:read", "neches:read"}, viewer)
+}
+
+func TestPermissionService_GrantAndRevoke(t *testing.T) {
+	permissionService, permissionRepo := setupPermissionService(t)
+
+	_, err := permissionService.CreateRole(request.CreateRoleRequest{Name: "Moderator"})
+	assert.NoError(t, err)
+	_, err = permissionService.CreateRole(request.CreateRoleRequest{Name: "Moderator"})
+	assert.ErrorIs(t, err, services.ErrRoleExists)
+
+	_, err = permissionService.CreatePermission(request.CreatePermissionRequest{Name: "Tags:Merge"})
+	assert.NoError(t, err)
+	_, err = permissionService.CreatePermission(request.CreatePermissionRequest{Name: "nocolon"})
+	assert.Error(t, err)
+
+	assert.NoError(t, permissionService.Grant("Moderator", "tags:merge"))
+	assert.NoError(t, permissionService.Grant("Moderator", "tags:merge"))
+	assert.ErrorIs(t, permissionService.Grant("Moderator", "tags:unknown"), services.ErrPermissionNotFound)
+	assert.ErrorIs(t, permissionService.Grant("Nobody", "tags:merge"), services.ErrRoleNotFound)
+
+	granted, err := permissionRepo.PermissionsForRole("Moderator")
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"tags:merge"}, granted)
+
+	assert.NoError(t, permissionService.Revoke("Moderator", "tags:merge"))
+	granted, err = permissionRepo.PermissionsForRole("Moderator")
+	assert.NoError(t, err)
+	assert.Empty(t, granted)
+
+	// Roles still given to users can't be deleted
+	assert.NoError(t, permissionRepo.Db.Create(&model.Users{Name: "Test", Email: "test@example.com", Password: "x", Role: "User"}).Error)
+	assert.ErrorIs(t, permissionService.DeleteRole("User"), services.ErrRoleInUse)
+	assert.NoError(t, permissionService.DeleteRole("Moderator"))
+	assert.ErrorIs(t, permissionService.DeleteRole("Moderator"), services.ErrRoleNotFound)
+}
+
+func TestRequirePermission(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	permissionService, permissionRepo := setupPermissionService(t)
+
+	key, err := auth.GenerateSigningKey(auth.AlgEdDSA)
+	assert.NoError(t, err)
+	keyring := auth.NewKeyring(time.Minute)
+	keyring.Add(key)
+	auth.UseKeyring(keyring)
+	auth.UseRevocationStore(auth.NewMemoryRevocationStore())
+	auth.UsePermissionStore(permissionRepo)
+
+	router := gin.New()
+	router.DELETE("/tags", auth.RequirePermission("tags:delete"), func(c *gin.Context) { c.Status(http.StatusOK) })
+
+	request := func(role string) int {
+		token, err := auth.GenerateJWT(1, "test@example.com", role)
+		assert.NoError(t, err)
+		req, _ := http.NewRequest(http.MethodDelete, "/tags", nil)
+		req.Header.Set("Authorization", "Bearer "+token)
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+		return w.Code
+	}
+
+	assert.Equal(t, http.StatusOK, request("Admin"))
+	assert.Equal(t, http.StatusForbidden, request("User"))
+
+	// Grants take effect without a restart once the cache is invalidated
+	assert.NoError(t, permissionService.Grant("User", "tags:delete"))
+	auth.InvalidatePermissions()
+	assert.Equal(t, http.StatusOK, request("User"))
+}
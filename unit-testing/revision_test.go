@@ -0,0 +1,149 @@
+package unittesting
+
+import (
+	"fmt"
+	"net/http"
+	"testing"
+
+	"example.com/go-project/auth"
+	"example.com/go-project/controller"
+	"example.com/go-project/helper"
+	"example.com/go-project/middleware"
+	"example.com/go-project/model"
+	"example.com/go-project/model/repository"
+	"example.com/go-project/services"
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+)
+
+const revisionActor = 7
+
+// setupRevisionRouter creates Cuisine and Italy without revisions, every
+// request acts for the same user
+func setupRevisionRouter(t *testing.T) *gin.Engine {
+	necheService, db := setupNecheService(t)
+	assert.NoError(t, db.Create(&[]model.Tags{{Id: 1, Name: "Cuisine"}, {Id: 2, Name: "Italy"}}).Error)
+
+	tagsRepository := repository.NewTagsRepositoryImpl(db)
+	necheRepository := repository.NewNecheRepositoryImpl(db)
+	tagsController := controller.NewTagsController(services.NewTagsServiceImpl(tagsRepository, helper.NewValidator()))
+	necheController := controller.NewNecheController(necheService)
+	trashController := controller.NewTrashController(services.NewTrashServiceImpl(tagsRepository, necheRepository, trashRetention))
+	revisionController := controller.NewRevisionController(
+		services.NewRevisionServiceImpl(repository.NewRevisionRepositoryImpl(db), tagsRepository, necheRepository))
+
+	gin.SetMode(gin.TestMode)
+	router := gin.New()
+	router.Use(middleware.ErrorHandler())
+	router.Use(func(ctx *gin.Context) {
+		auth.SetPrincipal(ctx, &auth.Principal{UserId: revisionActor})
+	})
+	router.POST("/tags", tagsController.Create)
+	router.PUT("/tags/:tagId", tagsController.Update)
+	router.DELETE("/tags/:tagId", tagsController.Delete)
+	router.POST("/neches", necheController.Create)
+	router.PUT("/neches/:necheId", necheController.Update)
+	router.GET("/neches/:necheId", necheController.FindById)
+	router.POST("/trash/tags/:tagId/restore", trashController.RestoreTag)
+	router.GET("/tags/:tagId/history", revisionController.TagHistory)
+	router.GET("/neches/:necheId/history", revisionController.NecheHistory)
+	router.POST("/tags/:tagId/revisions/:revisionId/revert", revisionController.RevertTag)
+	router.POST("/neches/:necheId/revisions/:revisionId/revert", revisionController.RevertNeche)
+	return router
+}
+
+// history returns the revisions on the first page of the history, newest first
+func history(t *testing.T, router *gin.Engine, url string) []map[string]interface{} {
+	recorder, body := getPage(t, router, url)
+	assert.Equal(t, http.StatusOK, recorder.Code)
+	var revisions []map[string]interface{}
+	for _, revision := range body.Data.([]interface{}) {
+		revisions = append(revisions, revision.(map[string]interface{}))
+	}
+	return revisions
+}
+
+func revertURL(entity string, id int, revision map[string]interface{}) string {
+	return fmt.Sprintf("/%s/%d/revisions/%v/revert", entity, id, revision["id"])
+}
+
+func TestRevisions_TagHistoryAndRevert(t *testing.T) {
+	router := setupRevisionRouter(t)
+
+	recorder := sendWithHeaders(router, http.MethodPost, "/tags", `{"name":"Travel"}`, nil)
+	assert.Equal(t, http.StatusOK, recorder.Code)
+	recorder = sendWithHeaders(router, http.MethodPut, "/tags/3", `{"name":"Trips"}`, map[string]string{"If-Match": `"1"`})
+	assert.Equal(t, http.StatusOK, recorder.Code)
+
+	revisions := history(t, router, "/tags/3/history")
+	assert.Len(t, revisions, 2)
+	assert.Equal(t, "update", revisions[0]["action"])
+	assert.Equal(t, float64(revisionActor), revisions[0]["actorId"])
+	assert.Equal(t, map[string]interface{}{"name": "Travel", "parentId": nil, "version": float64(1)}, revisions[0]["before"])
+	assert.Equal(t, map[string]interface{}{"name": "Trips", "parentId": nil, "version": float64(2)}, revisions[0]["after"])
+	assert.Equal(t, "create", revisions[1]["action"])
+	assert.Nil(t, revisions[1]["before"])
+
+	// Reverting is a change of its own, the stale version loses
+	recorder = sendWithHeaders(router, http.MethodPost, revertURL("tags", 3, revisions[1]), "", map[string]string{"If-Match": `"2"`})
+	assert.Equal(t, http.StatusOK, recorder.Code)
+	assert.Contains(t, recorder.Body.String(), `"name":"Travel"`)
+	assert.Equal(t, `"3"`, recorder.Header().Get("ETag"))
+	recorder = sendWithHeaders(router, http.MethodPost, revertURL("tags", 3, revisions[0]), "", map[string]string{"If-Match": `"2"`})
+	assert.Equal(t, http.StatusPreconditionFailed, recorder.Code)
+
+	revisions = history(t, router, "/tags/3/history")
+	assert.Len(t, revisions, 3)
+	assert.Equal(t, "revert", revisions[0]["action"])
+
+	// A revision of another tag can't be reverted to
+	recorder = sendWithHeaders(router, http.MethodPost, revertURL("tags", 1, revisions[0]), "", map[string]string{"If-Match": "*"})
+	assert.Equal(t, http.StatusNotFound, recorder.Code)
+
+	// Tags from before revisions have an empty history, missing tags none
+	assert.Empty(t, history(t, router, "/tags/1/history"))
+	recorder = sendWithHeaders(router, http.MethodGet, "/tags/9/history", "", nil)
+	assert.Equal(t, http.StatusNotFound, recorder.Code)
+}
+
+func TestRevisions_NecheHistoryAndRevert(t *testing.T) {
+	router := setupRevisionRouter(t)
+	ifMatch := map[string]string{"If-Match": "*"}
+
+	recorder := sendWithHeaders(router, http.MethodPost, "/neches", `{"name":"Pasta","tagIds":[1]}`, nil)
+	assert.Equal(t, http.StatusOK, recorder.Code)
+	recorder = sendWithHeaders(router, http.MethodPut, "/neches/1", `{"name":"Pizza","tagIds":[2]}`, ifMatch)
+	assert.Equal(t, http.StatusOK, recorder.Code)
+
+	// Deleting the tag takes the neche along, both record it
+	recorder = sendWithHeaders(router, http.MethodDelete, "/tags/2", "", ifMatch)
+	assert.Equal(t, http.StatusOK, recorder.Code)
+	tagRevisions := history(t, router, "/tags/2/history")
+	assert.Len(t, tagRevisions, 1)
+	assert.Equal(t, "delete", tagRevisions[0]["action"])
+	assert.Nil(t, tagRevisions[0]["after"])
+
+	revisions := history(t, router, "/neches/1/history")
+	assert.Len(t, revisions, 3)
+	assert.Equal(t, "delete", revisions[0]["action"])
+	assert.Equal(t, map[string]interface{}{"name": "Pizza", "tagIds": []interface{}{float64(2)}, "version": float64(2)}, revisions[0]["before"])
+
+	// A deletion leaves nothing to revert to, and a neche in the trash can't be reverted
+	recorder = sendWithHeaders(router, http.MethodPost, revertURL("neches", 1, revisions[0]), "", ifMatch)
+	assert.Equal(t, http.StatusConflict, recorder.Code)
+	recorder = sendWithHeaders(router, http.MethodPost, revertURL("neches", 1, revisions[2]), "", ifMatch)
+	assert.Equal(t, http.StatusNotFound, recorder.Code)
+
+	recorder = sendWithHeaders(router, http.MethodPost, "/trash/tags/2/restore", "", nil)
+	assert.Equal(t, http.StatusOK, recorder.Code)
+	revisions = history(t, router, "/neches/1/history")
+	assert.Len(t, revisions, 4)
+	assert.Equal(t, "restore", revisions[0]["action"])
+	assert.Equal(t, float64(revisionActor), revisions[0]["actorId"])
+
+	// Reverting to the creation brings back the name and the tags
+	recorder = sendWithHeaders(router, http.MethodPost, revertURL("neches", 1, revisions[3]), "", ifMatch)
+	assert.Equal(t, http.StatusOK, recorder.Code)
+	recorder = sendWithHeaders(router, http.MethodGet, "/neches/1", "", nil)
+	assert.Contains(t, recorder.Body.String(), `"name":"Pasta","tags":[{"id":1,"name":"Cuisine"}]`)
+}
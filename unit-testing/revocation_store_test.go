@@ -0,0 +1,64 @@
+package unittesting
+
+import (
+	"testing"
+	"time"
+
+	"example.com/go-project/auth"
+	"example.com/go-project/model"
+	"example.com/go-project/model/repository"
+	"github.com/stretchr/testify/assert"
+	"gorm.io/driver/sqlite"
+	"gorm.io/gorm"
+)
+
+// runRevocationStoreTests checks the behaviour shared by every revocation store
+func runRevocationStoreTests(t *testing.T, store auth.RevocationStore) {
+	now := time.Now()
+
+	// Revoking a single token only affects that token
+	assert.NoError(t, store.RevokeToken("token-1", 1, now.Add(time.Minute)))
+	revoked, err := store.IsRevoked("token-1", 1, now)
+	assert.NoError(t, err)
+	assert.True(t, revoked)
+
+	revoked, err = store.IsRevoked("token-2", 1, now)
+	assert.NoError(t, err)
+	assert.False(t, revoked)
+
+	// Revoking a user affects the tokens issued before the revocation only
+	assert.NoError(t, store.RevokeUser(2, now, now.Add(time.Minute)))
+	revoked, err = store.IsRevoked("token-3", 2, now.Add(-time.Second))
+	assert.NoError(t, err)
+	assert.True(t, revoked)
+
+	revoked, err = store.IsRevoked("token-4", 2, now.Add(time.Second))
+	assert.NoError(t, err)
+	assert.False(t, revoked)
+
+	// Pruning forgets revocations of tokens that have expired anyway
+	assert.NoError(t, store.Prune(now.Add(time.Hour)))
+	revoked, err = store.IsRevoked("token-1", 1, now)
+	assert.NoError(t, err)
+	assert.False(t, revoked)
+
+	revoked, err = store.IsRevoked("token-3", 2, now.Add(-time.Second))
+	assert.NoError(t, err)
+	assert.False(t, revoked)
+}
+
+func TestMemoryRevocationStore(t *testing.T) {
+	runRevocationStoreTests(t, auth.NewMemoryRevocationStore())
+}
+
+func TestDatabaseRevocationStore(t *testing.T) {
+	db, err := gorm.Open(sqlite.Open(":memory:"), &gorm.Config{})
+	if err != nil {
+		t.Fatalf("could not connect to db: %v", err)
+	}
+
+	err = db.AutoMigrate(&model.RevokedToken{}, &model.UserRevocation{})
+	assert.NoError(t, err)
+
+	runRevocationStoreTests(t, repository.NewRevocationRepository(db))
+}
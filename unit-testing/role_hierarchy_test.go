@@ -0,0 +1,87 @@
+package unittesting
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"example.com/go-project/auth"
+	"example.com/go-project/data/request"
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRoleHierarchy_Satisfies(t *testing.T) {
+	h := auth.NewRoleHierarchy([]string{"Admin", "Editor", "User", "Viewer"})
+
+	assert.True(t, h.Satisfies("Admin", "User"))
+	assert.True(t, h.Satisfies("Editor", "Editor"))
+	assert.True(t, h.Satisfies("User", "Viewer"))
+	assert.False(t, h.Satisfies("Viewer", "User"))
+	assert.False(t, h.Satisfies("Editor", "Admin"))
+
+	// Roles outside the hierarchy only satisfy themselves
+	assert.False(t, h.Satisfies("Robot", "Viewer"))
+	assert.False(t, h.Satisfies("Admin", "Robot"))
+	assert.True(t, h.Satisfies("Robot", "Robot"))
+}
+
+func TestRoleBasedAuth_InheritsAndAcceptsAnyAllowedRole(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	key, err := auth.GenerateSigningKey(auth.AlgEdDSA)
+	assert.NoError(t, err)
+	keyring := auth.NewKeyring(time.Minute)
+	keyring.Add(key)
+	auth.UseKeyring(keyring)
+	auth.UseRevocationStore(auth.NewMemoryRevocationStore())
+	auth.UseRoleHierarchy(auth.NewRoleHierarchy(auth.DefaultRoles))
+
+	router := gin.New()
+	router.GET("/user", auth.RequireRole("User"), func(c *gin.Context) { c.Status(http.StatusOK) })
+	router.GET("/review", auth.RequireRole("Editor", "Viewer"), func(c *gin.Context) { c.Status(http.StatusOK) })
+
+	request := func(path string, role string) int {
+		token, err := auth.GenerateJWT(1, "test@example.com", role)
+		assert.NoError(t, err)
+		req, _ := http.NewRequest(http.MethodGet, path, nil)
+		req.Header.Set("Authorization", "Bearer "+token)
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+		return w.Code
+	}
+
+	assert.Equal(t, http.StatusOK, request("/user", "Admin"))
+	assert.Equal(t, http.StatusOK, request("/user", "User"))
+	assert.Equal(t, http.StatusForbidden, request("/user", "Viewer"))
+	assert.Equal(t, http.StatusOK, request("/review", "Viewer"))
+	assert.Equal(t, http.StatusOK, request("/review", "Admin"))
+}
+
+func TestRoleHierarchy_InheritsPermissions(t *testing.T) {
+	permissionService, permissionRepo := setupPermissionService(t)
+	auth.UsePermissionStore(permissionRepo)
+	auth.UseRoleHierarchy(auth.NewRoleHierarchy(auth.DefaultRoles))
+
+	_, err := permissionService.CreatePermission(request.CreatePermissionRequest{Name: "tags:merge"})
+	assert.NoError(t, err)
+	_, err = permissionService.CreateRole(request.CreateRoleRequest{Name: "Moderator"})
+	assert.NoError(t, err)
+	assert.NoError(t, permissionService.Grant("Viewer", "tags:merge"))
+	auth.InvalidatePermissions()
+
+	// Every role above Viewer has what Viewer was granted, roles outside the hierarchy don't
+	for _, role := range auth.DefaultRoles {
+		allowed, err := auth.HasPermission(role, "tags:merge")
+		assert.NoError(t, err)
+		assert.True(t, allowed, role)
+	}
+	allowed, err := auth.HasPermission("Moderator", "tags:merge")
+	assert.NoError(t, err)
+	assert.False(t, allowed)
+
+	// What a higher role was granted isn't passed down
+	allowed, err = auth.HasPermission("Viewer", "tags:delete")
+	assert.NoError(t, err)
+	assert.False(t, allowed)
+}
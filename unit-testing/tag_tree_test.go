@@ -0,0 +1,158 @@
+package unittesting
+
+import (
+	"net/http"
+	"testing"
+
+	"example.com/go-project/controller"
+	"example.com/go-project/data/request"
+	"example.com/go-project/helper"
+	"example.com/go-project/middleware"
+	"example.com/go-project/migrations"
+	"example.com/go-project/model"
+	"example.com/go-project/model/repository"
+	"example.com/go-project/services"
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestTagParentsMigration_RollbackKeepsNecheTags(t *testing.T) {
+	db := setupMigrationsDB(t)
+	migrator := migrations.NewWithMigrations(db, migrations.All()[:7])
+	_, err := migrator.Up()
+	assert.NoError(t, err)
+
+	assert.NoError(t, db.Exec("INSERT INTO tags (id, name) VALUES (1, 'Cuisine')").Error)
+	assert.NoError(t, db.Exec("INSERT INTO tags (id, name, parent_id) VALUES (2, 'Italian', 1)").Error)
+	assert.NoError(t, db.Exec("INSERT INTO neches (id, neche_type) VALUES (1, 'Pasta')").Error)
+	assert.NoError(t, db.Exec("INSERT INTO neche_tags (neche_id, tag_id) VALUES (1, 1), (1, 2)").Error)
+
+	_, err = migrator.Down(1)
+	assert.NoError(t, err)
+	assert.False(t, db.Migrator().HasColumn("tags", "parent_id"))
+
+	var count int64
+	db.Table("neche_tags").Count(&count)
+	assert.Equal(t, int64(2), count)
+	db.Table("tags").Count(&count)
+	assert.Equal(t, int64(2), count)
+}
+
+// setupTagTreeRouter builds Cuisine > Italian > Pasta dishes and Travel, with
+// Carbonara under Pasta dishes, Pizza under Italian and Pasta dishes, Beach under Travel
+func setupTagTreeRouter(t *testing.T) *gin.Engine {
+	necheService, db := setupNecheService(t)
+	tagsService := services.NewTagsServiceImpl(repository.NewTagsRepositoryImpl(db), helper.NewValidator())
+	parents := []*int{nil, intPtr(1), intPtr(2), nil}
+	for i, name := range []string{"Cuisine", "Italian", "Pasta dishes", "Travel"} {
+		assert.NoError(t, tagsService.Create(0, request.CreteTagsRequest{Name: name, ParentId: parents[i]}))
+	}
+	tagIds := [][]int{{3}, {2, 3}, {4}}
+	for i, name := range []string{"Carbonara", "Pizza", "Beach"} {
+		_, err := necheService.Create(0, request.CreateNecheRequest{Name: name, TagIDs: tagIds[i]})
+		assert.NoError(t, err)
+	}
+
+	gin.SetMode(gin.TestMode)
+	router := gin.New()
+	router.Use(middleware.ErrorHandler())
+	tagsController := controller.NewTagsController(tagsService)
+	router.GET("/tags/:tagId", tagsController.FindById)
+	router.DELETE("/tags/:tagId", tagsController.Delete)
+	router.POST("/tags/:tagId/move", tagsController.Move)
+	router.GET("/tags/:tagId/subtree", tagsController.Subtree)
+	router.GET("/tags/:tagId/ancestors", tagsController.Ancestors)
+	router.GET("/tags/:tagId/neches", controller.NewNecheController(necheService).FindByTagId)
+	return router
+}
+
+func intPtr(i int) *int {
+	return &i
+}
+
+func TestTagTree_SubtreeAndAncestors(t *testing.T) {
+	router := setupTagTreeRouter(t)
+
+	recorder := sendWithHeaders(router, http.MethodGet, "/tags/1/subtree", "", nil)
+	assert.Equal(t, http.StatusOK, recorder.Code)
+	assert.Contains(t, recorder.Body.String(),
+		`{"id":1,"name":"Cuisine","children":[{"id":2,"name":"Italian","children":[{"id":3,"name":"Pasta dishes","children":[]}]}]}`)
+
+	recorder = sendWithHeaders(router, http.MethodGet, "/tags/3/ancestors", "", nil)
+	assert.Equal(t, http.StatusOK, recorder.Code)
+	assert.Contains(t, recorder.Body.String(),
+		`[{"id":1,"name":"Cuisine"},{"id":2,"name":"Italian"},{"id":3,"name":"Pasta dishes"}]`)
+
+	recorder = sendWithHeaders(router, http.MethodGet, "/tags/9/subtree", "", nil)
+	assert.Equal(t, http.StatusNotFound, recorder.Code)
+	recorder = sendWithHeaders(router, http.MethodGet, "/tags/9/ancestors", "", nil)
+	assert.Equal(t, http.StatusNotFound, recorder.Code)
+}
+
+func TestTagTree_Move(t *testing.T) {
+	router := setupTagTreeRouter(t)
+	ifMatch := map[string]string{"If-Match": "*"}
+
+	// A tag can't end up under itself
+	recorder := sendWithHeaders(router, http.MethodPost, "/tags/1/move", `{"parentId":3}`, ifMatch)
+	assert.Equal(t, http.StatusConflict, recorder.Code)
+	recorder = sendWithHeaders(router, http.MethodPost, "/tags/1/move", `{"parentId":1}`, ifMatch)
+	assert.Equal(t, http.StatusConflict, recorder.Code)
+	recorder = sendWithHeaders(router, http.MethodPost, "/tags/1/move", `{"parentId":9}`, ifMatch)
+	assert.Equal(t, http.StatusNotFound, recorder.Code)
+
+	// The subtree moves along
+	recorder = sendWithHeaders(router, http.MethodPost, "/tags/2/move", `{"parentId":4}`, map[string]string{"If-Match": `"1"`})
+	assert.Equal(t, http.StatusOK, recorder.Code)
+	assert.Equal(t, `"2"`, recorder.Header().Get("ETag"))
+	recorder = sendWithHeaders(router, http.MethodGet, "/tags/3/ancestors", "", nil)
+	assert.Contains(t, recorder.Body.String(), `[{"id":4,"name":"Travel"},{"id":2,"name":"Italian"},{"id":3,"name":"Pasta dishes"}]`)
+
+	recorder = sendWithHeaders(router, http.MethodPost, "/tags/2/move", `{"parentId":null}`, map[string]string{"If-Match": `"1"`})
+	assert.Equal(t, http.StatusPreconditionFailed, recorder.Code)
+	recorder = sendWithHeaders(router, http.MethodPost, "/tags/2/move", `{"parentId":null}`, ifMatch)
+	assert.Equal(t, http.StatusOK, recorder.Code)
+	recorder = sendWithHeaders(router, http.MethodGet, "/tags/2", "", nil)
+	assert.Contains(t, recorder.Body.String(), `"parentId":null`)
+}
+
+func TestTagTree_DeleteWithChildren(t *testing.T) {
+	router := setupTagTreeRouter(t)
+	ifMatch := map[string]string{"If-Match": "*"}
+
+	recorder := sendWithHeaders(router, http.MethodDelete, "/tags/2", "", ifMatch)
+	assert.Equal(t, http.StatusConflict, recorder.Code)
+
+	recorder = sendWithHeaders(router, http.MethodDelete, "/tags/3", "", ifMatch)
+	assert.Equal(t, http.StatusOK, recorder.Code)
+	recorder = sendWithHeaders(router, http.MethodDelete, "/tags/2", "", ifMatch)
+	assert.Equal(t, http.StatusOK, recorder.Code)
+}
+
+func TestTagTree_NechesOfDescendants(t *testing.T) {
+	router := setupTagTreeRouter(t)
+
+	_, body := getPage(t, router, "/tags/1/neches")
+	assert.Equal(t, int64(0), body.Total)
+
+	// Pizza is in two tags of the subtree and listed once
+	_, body = getPage(t, router, "/tags/1/neches?includeDescendants=true")
+	assert.Equal(t, int64(2), body.Total)
+	assert.ElementsMatch(t, []interface{}{"Carbonara", "Pizza"}, necheNames(body.Data))
+
+	recorder := sendWithHeaders(router, http.MethodGet, "/tags/1/neches?includeDescendants=maybe", "", nil)
+	assert.Equal(t, http.StatusBadRequest, recorder.Code)
+}
+
+func TestTagTree_CreateUnderMissingParent(t *testing.T) {
+	_, db := setupNecheService(t)
+	tagsService := services.NewTagsServiceImpl(repository.NewTagsRepositoryImpl(db), helper.NewValidator())
+
+	err := tagsService.Create(0, request.CreteTagsRequest{Name: "Orphan", ParentId: intPtr(9)})
+	assert.ErrorIs(t, err, services.ErrTagNotFound)
+	assert.EqualError(t, err, "parent tag with ID 9: tag not found")
+
+	var count int64
+	db.Model(&model.Tags{}).Count(&count)
+	assert.Equal(t, int64(0), count)
+}
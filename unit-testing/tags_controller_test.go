@@ -3,17 +3,20 @@ package unittesting
 import (
 	"bytes"
 	"encoding/json"
-	"errors"
 	"log"
 	"net/http"
 	"net/http/httptest"
+	"sort"
 	"strconv"
 	"testing"
 
 	"example.com/go-project/controller"
 	"example.com/go-project/data/request"
 	"example.com/go-project/data/response"
+	"example.com/go-project/helper"
+	"example.com/go-project/middleware"
 	"example.com/go-project/model"
+	"example.com/go-project/model/repository"
 	"github.com/gin-gonic/gin"
 	"github.com/stretchr/testify/assert"
 	"gorm.io/driver/sqlite"
@@ -26,27 +29,39 @@ type mockTagsService struct {
 }
 
 // Create implements services.TagsService.
-func (m *mockTagsService) Create(tagsRequest request.CreteTagsRequest) error {
+func (m *mockTagsService) Create(actorId int, tagsRequest request.CreteTagsRequest) error {
 	// Simulate successful creation by adding to the mock tags map
 	m.tags[len(m.tags)+1] = struct{}{}
 	return nil
 }
 
 // Update implements services.TagsService.
-func (m *mockTagsService) Update(tagsRequest request.UpdateTagsRequest) error {
+func (m *mockTagsService) Update(actorId int, tagsRequest request.UpdateTagsRequest) (response.TagsResponse, error) {
 	// Find the tag by ID
 	if _, exists := m.tags[tagsRequest.Id]; !exists {
-		return errors.New("Tag not found")
+		return response.TagsResponse{}, helper.NotFound("Tag not found")
+	}
+	// Every mock tag is at version 1
+	if tagsRequest.Version > 1 {
+		return response.TagsResponse{}, repository.ErrStaleVersion
 	}
 	// update by just ensuring the tag exists
 	m.tags[tagsRequest.Id] = struct{}{}
-	return nil
+	return response.TagsResponse{Id: tagsRequest.Id, Name: tagsRequest.Name, Version: 2}, nil
+}
+
+// Patch implements services.TagsService.
+func (m *mockTagsService) Patch(actorId int, patch request.PatchRequest) (response.TagsResponse, error) {
+	return m.Update(actorId, request.UpdateTagsRequest{Id: patch.Id, Version: patch.Version})
 }
 
 // Delete implements services.TagsService.
-func (m *mockTagsService) Delete(tagId int) error {
+func (m *mockTagsService) Delete(actorId int, tagId int, version int) error {
 	if _, exists := m.tags[tagId]; !exists {
-		return errors.New("Tag not found")
+		return helper.NotFound("Tag not found")
+	}
+	if version > 1 {
+		return repository.ErrStaleVersion
 	}
 	delete(m.tags, tagId)
 	return nil
@@ -55,20 +70,58 @@ func (m *mockTagsService) Delete(tagId int) error {
 // FindById implements services.TagsService.
 func (m *mockTagsService) FindById(tagId int) (response.TagsResponse, error) {
 	if _, exists := m.tags[tagId]; !exists {
-		return response.TagsResponse{}, errors.New("Tag not found")
+		return response.TagsResponse{}, helper.NotFound("Tag with id %d not found", tagId)
 	}
-	return response.TagsResponse{Id: tagId, Name: "Tag " + strconv.Itoa(tagId)}, nil
+	return response.TagsResponse{Id: tagId, Name: "Tag " + strconv.Itoa(tagId), Version: 1}, nil
 }
 
 // FindAll implements services.TagsService.
 func (m *mockTagsService) FindAll(limit, offset int) ([]response.TagsResponse, error) {
-	var allTags []response.TagsResponse
+	// Return the tags by id like the database does. TestFindAllTags expects
+	// tag 1 first, ranging over the map alone fails it now and then.
+	var ids []int
 	for id := range m.tags {
+		ids = append(ids, id)
+	}
+	sort.Ints(ids)
+
+	var allTags []response.TagsResponse
+	for _, id := range ids {
 		allTags = append(allTags, response.TagsResponse{Id: id, Name: "Tag " + strconv.Itoa(id)})
 	}
 	return allTags, nil
 }
 
+// Search implements services.TagsService.
+func (m *mockTagsService) Search(query request.SearchTagsRequest) (response.TagsPage, error) {
+	allTags, err := m.FindAll(query.Limit, query.Offset)
+	return response.TagsPage{Tags: allTags, Total: int64(len(allTags))}, err
+}
+
+// Move implements services.TagsService.
+func (m *mockTagsService) Move(actorId int, move request.MoveTagRequest) (response.TagsResponse, error) {
+	if _, exists := m.tags[move.Id]; !exists {
+		return response.TagsResponse{}, helper.NotFound("Tag not found")
+	}
+	return response.TagsResponse{Id: move.Id, ParentId: move.ParentId, Version: 2}, nil
+}
+
+// Subtree implements services.TagsService.
+func (m *mockTagsService) Subtree(tagId int) (response.TagTreeResponse, error) {
+	if _, exists := m.tags[tagId]; !exists {
+		return response.TagTreeResponse{}, helper.NotFound("Tag with id %d not found", tagId)
+	}
+	return response.TagTreeResponse{Id: tagId, Name: "Tag " + strconv.Itoa(tagId)}, nil
+}
+
+// Ancestors implements services.TagsService.
+func (m *mockTagsService) Ancestors(tagId int) ([]response.BreadcrumbResponse, error) {
+	if _, exists := m.tags[tagId]; !exists {
+		return nil, helper.NotFound("Tag with id %d not found", tagId)
+	}
+	return []response.BreadcrumbResponse{{Id: tagId, Name: "Tag " + strconv.Itoa(tagId)}}, nil
+}
+
 func setupTestDB() (*gorm.DB, error) {
 	return gorm.Open(sqlite.Open(":memory:"), &gorm.Config{})
 }
@@ -90,6 +143,7 @@ func TestCreateTag(t *testing.T) {
 
 	// Set up the router
 	router := gin.Default()
+	router.Use(middleware.ErrorHandler())
 	router.POST("/tags", tagsController.Create)
 
 	// Create a test request
@@ -122,6 +176,7 @@ func TestDeleteTag(t *testing.T) {
 	// Set up the router
 	gin.SetMode(gin.TestMode)
 	router := gin.Default()
+	router.Use(middleware.ErrorHandler())
 
 	// Set up mock service with existing tags
 	mockService := &mockTagsService{
@@ -138,6 +193,7 @@ func TestDeleteTag(t *testing.T) {
 
 	// Test deleting an existing tag
 	req, _ := http.NewRequest(http.MethodDelete, "/tags/1", nil)
+	req.Header.Set("If-Match", `"1"`)
 	recorder := httptest.NewRecorder()
 	router.ServeHTTP(recorder, req)
 
@@ -152,6 +208,7 @@ func TestDeleteTag(t *testing.T) {
 
 	// Test deleting a non-existing tag
 	req, _ = http.NewRequest(http.MethodDelete, "/tags/999", nil)
+	req.Header.Set("If-Match", `"1"`)
 	recorder = httptest.NewRecorder()
 	router.ServeHTTP(recorder, req)
 
@@ -168,6 +225,7 @@ func TestFindTagById(t *testing.T) {
 	// Set up the router
 	gin.SetMode(gin.TestMode)
 	router := gin.Default()
+	router.Use(middleware.ErrorHandler())
 
 	// Set up mock service with existing tags
 	mockService := &mockTagsService{
@@ -215,6 +273,7 @@ func TestUpdateTag(t *testing.T) {
 	// Set up the router
 	gin.SetMode(gin.TestMode)
 	router := gin.Default()
+	router.Use(middleware.ErrorHandler())
 
 	// Set up mock service with a tag already in the mock data
 	mockService := &mockTagsService{
@@ -239,6 +298,7 @@ func TestUpdateTag(t *testing.T) {
 	// Test updating the existing tag with ID 1
 	req, _ := http.NewRequest(http.MethodPut, "/tags/1", bytes.NewBuffer(jsonRequest))
 	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("If-Match", `"1"`)
 	recorder := httptest.NewRecorder()
 	router.ServeHTTP(recorder, req)
 
@@ -262,6 +322,7 @@ func TestFindAllTags(t *testing.T) {
 	// Set up the router
 	gin.SetMode(gin.TestMode)
 	router := gin.Default()
+	router.Use(middleware.ErrorHandler())
 
 	// Set up mock service
 	mockService := &mockTagsService{
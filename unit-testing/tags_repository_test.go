@@ -23,7 +23,7 @@ func TestTagsRepositoryImpl_Save_Success(t *testing.T) {
 	}
 
 	// Migrate the schema
-	db.AutoMigrate(&model.Tags{})
+	db.AutoMigrate(&model.Tags{}, &model.Revision{})
 
 	// Create repository instance
 	repo := repository.NewTagsRepositoryImpl(db)
@@ -32,7 +32,7 @@ func TestTagsRepositoryImpl_Save_Success(t *testing.T) {
 	mockTag := model.Tags{Id: 1, Name: "Sample Tag"}
 
 	// Call the repository method
-	err = repo.Save(mockTag)
+	err = repo.Save(0, mockTag)
 
 	// Assertions
 	assert.NoError(t, err)
@@ -53,15 +53,15 @@ func TestTagsRepositoryImpl_Save_Failure(t *testing.T) {
 	repo := repository.NewTagsRepositoryImpl(db)
 
 	// Simulate an error (like a unique constraint violation)
-	db.AutoMigrate(&model.Tags{}) // Ensure the schema is migrated
+	db.AutoMigrate(&model.Tags{}, &model.Revision{}) // Ensure the schema is migrated
 
 	mockTag := model.Tags{Id: 1, Name: "Sample Tag"}
 	// First save the tag
-	err = repo.Save(mockTag)
+	err = repo.Save(0, mockTag)
 	assert.NoError(t, err)
 
 	// Try saving the same tag again to trigger a unique constraint error
-	err = repo.Save(mockTag)
+	err = repo.Save(0, mockTag)
 	assert.Error(t, err)
 }
 
@@ -72,7 +72,7 @@ func TestTagsRepositoryImpl_Delete_Success(t *testing.T) {
 	}
 
 	// Migrate the schema
-	db.AutoMigrate(&model.Tags{})
+	db.AutoMigrate(&model.Tags{}, &model.Revision{})
 
 	// Create a tag to delete
 	db.Create(&model.Tags{Id: 1, Name: "Sample Tag"})
@@ -80,7 +80,7 @@ func TestTagsRepositoryImpl_Delete_Success(t *testing.T) {
 	// Create repository instance
 	repo := repository.NewTagsRepositoryImpl(db)
 
-	err = repo.Delete(1)
+	err = repo.Delete(0, 1, 0)
 
 	assert.NoError(t, err)
 
@@ -97,14 +97,14 @@ func TestTagsRepositoryImpl_Delete_NotFound(t *testing.T) {
 	}
 
 	// Migrate the schema
-	db.AutoMigrate(&model.Tags{})
+	db.AutoMigrate(&model.Tags{}, &model.Revision{})
 
 	// Create repository instance
 	repo := repository.NewTagsRepositoryImpl(db)
 
 	// Attempt to delete a non-existent tag
-	err = repo.Delete(99)  // Assuming 99 doesn't exist
-	assert.NoError(t, err) // No error should be returned even if it doesn't exist
+	err = repo.Delete(0, 99, 0) // Assuming 99 doesn't exist
+	assert.NoError(t, err)      // No error should be returned even if it doesn't exist
 }
 
 func TestTagsRepositoryImpl_FindAll_Success(t *testing.T) {
@@ -114,7 +114,7 @@ func TestTagsRepositoryImpl_FindAll_Success(t *testing.T) {
 	}
 
 	// Migrate the schema for both Tags and Neches
-	db.AutoMigrate(&model.Tags{}, &model.Neche{}) // Ensure both models are migrated
+	db.AutoMigrate(&model.Tags{}, &model.Neche{}, &model.Revision{}) // Ensure both models are migrated
 
 	// Create repository instance
 	repo := repository.NewTagsRepositoryImpl(db)
@@ -122,8 +122,8 @@ func TestTagsRepositoryImpl_FindAll_Success(t *testing.T) {
 	// Add some tags and associated Neches for testing
 	db.Create(&model.Tags{Id: 1, Name: "Tag 1"})
 	db.Create(&model.Tags{Id: 2, Name: "Tag 2"})
-	db.Create(&model.Neche{Id: 1, TagID: 1})
-	db.Create(&model.Neche{Id: 2, TagID: 2})
+	db.Create(&model.Neche{Id: 1, Tags: []model.Tags{{Id: 1}}})
+	db.Create(&model.Neche{Id: 2, Tags: []model.Tags{{Id: 2}}})
 
 	// Call the repository method
 	tags, err := repo.FindAll(10, 0)
@@ -140,7 +140,7 @@ func TestTagsRepositoryImpl_FindAll_NoTags(t *testing.T) {
 	}
 
 	// Migrate the schema
-	db.AutoMigrate(&model.Tags{})
+	db.AutoMigrate(&model.Tags{}, &model.Revision{})
 
 	// Create repository instance
 	repo := repository.NewTagsRepositoryImpl(db)
@@ -160,7 +160,7 @@ func TestTagsRepositoryImpl_FindById_Success(t *testing.T) {
 	}
 
 	// Migrate the schema for both Tags and Neches
-	db.AutoMigrate(&model.Tags{}, &model.Neche{})
+	db.AutoMigrate(&model.Tags{}, &model.Neche{}, &model.Revision{})
 
 	// Create a tag to find
 	db.Create(&model.Tags{Id: 1, Name: "Sample Tag"})
@@ -181,7 +181,7 @@ func TestTagsRepositoryImpl_FindById_NotFound(t *testing.T) {
 	}
 
 	// Migrate the schema
-	db.AutoMigrate(&model.Tags{})
+	db.AutoMigrate(&model.Tags{}, &model.Revision{})
 
 	// Create repository instance
 	repo := repository.NewTagsRepositoryImpl(db)
@@ -200,7 +200,7 @@ func TestTagsRepositoryImpl_Update_Success(t *testing.T) {
 	}
 
 	// Migrate the schema
-	db.AutoMigrate(&model.Tags{})
+	db.AutoMigrate(&model.Tags{}, &model.Revision{})
 
 	// Create a tag to update
 	db.Create(&model.Tags{Id: 1, Name: "Sample Tag"})
@@ -210,7 +210,7 @@ func TestTagsRepositoryImpl_Update_Success(t *testing.T) {
 
 	// Update tag
 	updatedTag := model.Tags{Id: 1, Name: "Updated Tag"}
-	err = repo.Update(updatedTag)
+	err = repo.Update(0, updatedTag)
 
 	assert.NoError(t, err)
 
@@ -227,13 +227,13 @@ func TestTagsRepositoryImpl_Update_NotFound(t *testing.T) {
 	}
 
 	// Migrate the schema for both Tags and Neches
-	db.AutoMigrate(&model.Tags{}, &model.Neche{})
+	db.AutoMigrate(&model.Tags{}, &model.Neche{}, &model.Revision{})
 
 	// Create repository instance
 	repo := repository.NewTagsRepositoryImpl(db)
 
 	// Attempt to update a non-existent tag
-	err = repo.Update(model.Tags{Id: 99, Name: "Non-Existent Tag"})
+	err = repo.Update(0, model.Tags{Id: 99, Name: "Non-Existent Tag"})
 
 	assert.Error(t, err)
 	assert.Equal(t, "tag not found", err.Error())
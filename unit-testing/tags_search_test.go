@@ -0,0 +1,149 @@
+package unittesting
+
+import (
+	"testing"
+
+	"example.com/go-project/data/request"
+	"example.com/go-project/data/response"
+	"example.com/go-project/helper"
+	"example.com/go-project/model"
+	"example.com/go-project/model/repository"
+	"example.com/go-project/services"
+	"github.com/stretchr/testify/assert"
+)
+
+func setupTagsSearch(t *testing.T) services.TagsService {
+	db := setupMigratedDB(t)
+
+	// Cuisine has three neches, Culture one, Travel none and 100%_Fun two
+	tags := []model.Tags{
+		{Id: 1, Name: "Cuisine", Neches: []model.Neche{{NecheType: "Pasta"}, {NecheType: "Pizza"}, {NecheType: "Sushi"}}},
+		{Id: 2, Name: "Travel"},
+		{Id: 3, Name: "Culture", Neches: []model.Neche{{NecheType: "Opera"}}},
+		{Id: 4, Name: "100%_Fun", Neches: []model.Neche{{NecheType: "Games"}, {NecheType: "Puzzles"}}},
+	}
+	assert.NoError(t, db.Create(&tags).Error)
+
+	return services.NewTagsServiceImpl(repository.NewTagsRepositoryImpl(db), helper.NewValidator())
+}
+
+func tagNames(tags []response.TagsResponse) []string {
+	names := []string{}
+	for _, tag := range tags {
+		names = append(names, tag.Name)
+	}
+	return names
+}
+
+func TestSearchTags_NameMatching(t *testing.T) {
+	tagsService := setupTagsSearch(t)
+
+	page, err := tagsService.Search(request.SearchTagsRequest{Q: "cu"})
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"Cuisine", "Culture"}, tagNames(page.Tags))
+
+	page, err = tagsService.Search(request.SearchTagsRequest{Q: "ul"})
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"Culture"}, tagNames(page.Tags))
+
+	// A prefix match doesn't find names that only contain the text
+	page, err = tagsService.Search(request.SearchTagsRequest{Q: "ul", Match: "prefix"})
+	assert.NoError(t, err)
+	assert.Empty(t, page.Tags)
+
+	// LIKE wildcards in the query are matched literally
+	page, err = tagsService.Search(request.SearchTagsRequest{Q: "%_"})
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"100%_Fun"}, tagNames(page.Tags))
+}
+
+func TestSearchTags_NecheFiltersAndSort(t *testing.T) {
+	tagsService := setupTagsSearch(t)
+	yes, no := true, false
+	two := 2
+
+	page, err := tagsService.Search(request.SearchTagsRequest{HasNeches: &no})
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"Travel"}, tagNames(page.Tags))
+
+	page, err = tagsService.Search(request.SearchTagsRequest{HasNeches: &yes, Sort: "-necheCount"})
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"Cuisine", "100%_Fun", "Culture"}, tagNames(page.Tags))
+
+	// Filters combine, and so do sort fields
+	page, err = tagsService.Search(request.SearchTagsRequest{MinNeches: &two, Q: "u", Sort: "name"})
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"100%_Fun", "Cuisine"}, tagNames(page.Tags))
+
+	// The total counts every match, not just the page
+	page, err = tagsService.Search(request.SearchTagsRequest{MaxNeches: &two, Sort: "necheCount,-name", PageRequest: request.PageRequest{Limit: 2, Offset: 1}})
+	assert.NoError(t, err)
+	assert.Equal(t, int64(3), page.Total)
+	assert.Equal(t, []string{"Culture", "100%_Fun"}, tagNames(page.Tags))
+}
+
+func TestSearchTags_RejectsInvalidQueries(t *testing.T) {
+	tagsService := setupTagsSearch(t)
+	one, two := 1, 2
+
+	// Only whitelisted fields can be sorted by, nothing reaches the SQL
+	_, err := tagsService.Search(request.SearchTagsRequest{Sort: "name; DROP TABLE tags"})
+	assert.ErrorIs(t, err, helper.ErrValidation)
+
+	_, err = tagsService.Search(request.SearchTagsRequest{Match: "suffix"})
+	assert.Equal(t, helper.KindValidation, helper.AsAppError(err).Kind)
+
+	_, err = tagsService.Search(request.SearchTagsRequest{MinNeches: &two, MaxNeches: &one})
+	assert.ErrorIs(t, err, helper.ErrValidation)
+}
+
+func TestSearchTags_Cursors(t *testing.T) {
+	tagsService := setupTagsSearch(t)
+	query := request.SearchTagsRequest{Sort: "-necheCount", PageRequest: request.PageRequest{Limit: 2}}
+
+	first, err := tagsService.Search(query)
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"Cuisine", "100%_Fun"}, tagNames(first.Tags))
+	assert.NotEmpty(t, first.Next)
+	assert.Empty(t, first.Prev)
+
+	// Walk forward from the last tag of the first page
+	query.Cursor = first.Next
+	second, err := tagsService.Search(query)
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"Culture", "Travel"}, tagNames(second.Tags))
+	assert.Equal(t, int64(4), second.Total)
+	assert.Empty(t, second.Next)
+	assert.NotEmpty(t, second.Prev)
+
+	// And back again
+	query.Cursor = second.Prev
+	back, err := tagsService.Search(query)
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"Cuisine", "100%_Fun"}, tagNames(back.Tags))
+	assert.Empty(t, back.Prev)
+	assert.NotEmpty(t, back.Next)
+
+	// A cursor is only valid for the sort it was made with, and can't be edited
+	_, err = tagsService.Search(request.SearchTagsRequest{Sort: "name", PageRequest: request.PageRequest{Limit: 2, Cursor: first.Next}})
+	assert.ErrorIs(t, err, helper.ErrInvalidCursor)
+
+	_, err = tagsService.Search(request.SearchTagsRequest{Sort: "-necheCount", PageRequest: request.PageRequest{Limit: 2, Cursor: "x" + first.Next}})
+	assert.ErrorIs(t, err, helper.ErrBadRequest)
+
+	// Nor for other filters
+	minNeches := 1
+	_, err = tagsService.Search(request.SearchTagsRequest{Sort: "-necheCount", MinNeches: &minNeches, PageRequest: request.PageRequest{Limit: 2, Cursor: first.Next}})
+	assert.ErrorIs(t, err, helper.ErrInvalidCursor)
+	_, err = tagsService.Search(request.SearchTagsRequest{Q: "cu", Sort: "-necheCount", PageRequest: request.PageRequest{Limit: 2, Cursor: first.Next}})
+	assert.ErrorIs(t, err, helper.ErrInvalidCursor)
+
+	// Filters that select the same tags keep the cursors working
+	query = request.SearchTagsRequest{Q: " cu ", Match: "contains", Sort: "-necheCount", MinNeches: &minNeches, PageRequest: request.PageRequest{Limit: 1}}
+	first, err = tagsService.Search(query)
+	assert.NoError(t, err)
+	query = request.SearchTagsRequest{Q: "cu", Sort: "-necheCount", MinNeches: &minNeches, PageRequest: request.PageRequest{Limit: 1, Cursor: first.Next}}
+	second, err = tagsService.Search(query)
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"Culture"}, tagNames(second.Tags))
+}
@@ -4,6 +4,7 @@ import (
 	"errors"
 	"log"
 	"testing"
+	"time"
 
 	"example.com/go-project/data/request"
 	"example.com/go-project/model"
@@ -26,23 +27,29 @@ type MockTagsRepository struct {
 }
 
 // Save implements repository.TagsRepository.
-func (m *MockTagsRepository) Save(tags model.Tags) error {
-	args := m.Called(tags)
+func (m *MockTagsRepository) Save(actorId int, tags model.Tags) error {
+	args := m.Called(actorId, tags)
 	return args.Error(0)
 }
 
 // Update implements repository.TagsRepository.
-func (m *MockTagsRepository) Update(tags model.Tags) error {
-	args := m.Called(tags)
+func (m *MockTagsRepository) Update(actorId int, tags model.Tags) error {
+	args := m.Called(actorId, tags)
 	return args.Error(0)
 }
 
 // Delete implements repository.TagsRepository.
-func (m *MockTagsRepository) Delete(tagsId int) error {
-	args := m.Called(tagsId)
+func (m *MockTagsRepository) Delete(actorId int, tagsId int, version int) error {
+	args := m.Called(actorId, tagsId, version)
 	return args.Error(0)
 }
 
+// MissingIds implements repository.TagsRepository.
+func (m *MockTagsRepository) MissingIds(ids []int) ([]int, error) {
+	args := m.Called(ids)
+	return args.Get(0).([]int), args.Error(1)
+}
+
 // FindById implements repository.TagsRepository.
 func (m *MockTagsRepository) FindById(tagsId int) (model.Tags, error) {
 	args := m.Called(tagsId)
@@ -54,6 +61,54 @@ func (m *MockTagsRepository) FindAll(limit int, offset int) ([]model.Tags, error
 	args := m.Called(limit, offset)
 	return args.Get(0).([]model.Tags), args.Error(1)
 }
+
+// Search implements repository.TagsRepository.
+func (m *MockTagsRepository) Search(query repository.TagsQuery) ([]model.Tags, int64, error) {
+	args := m.Called(query)
+	return args.Get(0).([]model.Tags), args.Get(1).(int64), args.Error(2)
+}
+
+// Move implements repository.TagsRepository.
+func (m *MockTagsRepository) Move(actorId int, tagId int, parentId *int, version int) error {
+	args := m.Called(actorId, tagId, parentId, version)
+	return args.Error(0)
+}
+
+// Revert implements repository.TagsRepository.
+func (m *MockTagsRepository) Revert(actorId int, tagId int, to model.TagSnapshot, version int) error {
+	args := m.Called(actorId, tagId, to, version)
+	return args.Error(0)
+}
+
+// Subtree implements repository.TagsRepository.
+func (m *MockTagsRepository) Subtree(tagId int) ([]model.Tags, error) {
+	args := m.Called(tagId)
+	return args.Get(0).([]model.Tags), args.Error(1)
+}
+
+// Ancestors implements repository.TagsRepository.
+func (m *MockTagsRepository) Ancestors(tagId int) ([]model.Tags, error) {
+	args := m.Called(tagId)
+	return args.Get(0).([]model.Tags), args.Error(1)
+}
+
+// Trash implements repository.TagsRepository.
+func (m *MockTagsRepository) Trash(page repository.PageQuery) ([]model.Tags, int64, error) {
+	args := m.Called(page)
+	return args.Get(0).([]model.Tags), args.Get(1).(int64), args.Error(2)
+}
+
+// Restore implements repository.TagsRepository.
+func (m *MockTagsRepository) Restore(actorId int, tagId int) error {
+	args := m.Called(actorId, tagId)
+	return args.Error(0)
+}
+
+// Purge implements repository.TagsRepository.
+func (m *MockTagsRepository) Purge(before time.Time) (int64, error) {
+	args := m.Called(before)
+	return args.Get(0).(int64), args.Error(1)
+}
 func TestCreateTagService(t *testing.T) {
 	// Set up the in-memory database (SQLite or similar)
 	log.Print("\n\n\n Running Tags Service Test Cases.....\n\n\n")
@@ -61,7 +116,7 @@ func TestCreateTagService(t *testing.T) {
 	assert.NoError(t, err, "Failed to set up test database")
 
 	// Migrate your models here (make sure to define your models)
-	err = db.AutoMigrate(&model.Tags{})
+	err = db.AutoMigrate(&model.Tags{}, &model.Revision{})
 	assert.NoError(t, err, "Failed to migrate models")
 
 	// Create mock repository and validator
@@ -79,7 +134,7 @@ func TestCreateTagService(t *testing.T) {
 	log.Print("Requested tag to add: ", createTagsRequest.Name)
 
 	// Call the service's Create method directly
-	err = tagsService.Create(createTagsRequest)
+	err = tagsService.Create(0, createTagsRequest)
 	assert.NoError(t, err, "Service failed to create tag")
 
 	// Fetch the tag from the database to verify it was created
@@ -101,13 +156,13 @@ func TestDeleteTagService(t *testing.T) {
 	tagsService := services.NewTagsServiceImpl(mockRepo, validator.New())
 
 	// Set up expectations
-	mockRepo.On("Delete", 1).Return(nil)
+	mockRepo.On("Delete", 0, 1, 0).Return(nil)
 
 	// Test deleting a tag
-	tagsService.Delete(1)
+	tagsService.Delete(0, 1, 0)
 
 	// Assert that the repository Delete method was called
-	mockRepo.AssertCalled(t, "Delete", 1)
+	mockRepo.AssertCalled(t, "Delete", 0, 1, 0)
 	log.Print("Deleted Tag Test Case Passed.")
 }
 
@@ -119,9 +174,9 @@ func TestDeleteTagService_NotFound(t *testing.T) {
 	tagsService := services.NewTagsServiceImpl(mockRepo, validator.New())
 
 	// Set up expectations for non-existing tag
-	mockRepo.On("Delete", 1).Return(errors.New("tag not found"))
+	mockRepo.On("Delete", 0, 1, 0).Return(errors.New("tag not found"))
 
-	err := tagsService.Delete(1)
+	err := tagsService.Delete(0, 1, 0)
 
 	// Assert the error is as expected
 	assert.Error(t, err, "tag not found")
@@ -217,17 +272,17 @@ func TestUpdateTagService_Success(t *testing.T) {
 	updatedTag.Name = updateRequest.Name
 
 	// Set up expectations for Update with the updated tag name
-	mockRepo.On("Update", updatedTag).Return(nil)
+	mockRepo.On("Update", 0, updatedTag).Return(nil)
 
 	// Test updating a tag
-	err := tagsService.Update(updateRequest)
+	_, err := tagsService.Update(0, updateRequest)
 
 	// Assert that there are no errors
 	assert.NoError(t, err)
 
 	// Assert that the repository methods were called
 	mockRepo.AssertCalled(t, "FindById", updateRequest.Id)
-	mockRepo.AssertCalled(t, "Update", updatedTag)
+	mockRepo.AssertCalled(t, "Update", 0, updatedTag)
 
 	log.Print("Update Tag Success Test Case Passed.")
 }
@@ -246,7 +301,7 @@ func TestUpdateTagService_TagNotFound(t *testing.T) {
 	mockRepo.On("FindById", updateRequest.Id).Return(model.Tags{}, errors.New("tag not found"))
 
 	// Test updating a tag
-	err := tagsService.Update(updateRequest)
+	_, err := tagsService.Update(0, updateRequest)
 
 	// Assert the error is as expected
 	assert.Error(t, err, "tag not found")
@@ -265,7 +320,7 @@ func TestUpdateTagService_ValidationError(t *testing.T) {
 	updateRequest := request.UpdateTagsRequest{Id: 1, Name: ""}
 
 	// Test updating a tag
-	err := tagsService.Update(updateRequest)
+	_, err := tagsService.Update(0, updateRequest)
 
 	// Assert validation error
 	assert.Error(t, err, "tag name cannot be empty")
@@ -292,17 +347,17 @@ func TestUpdateTagService_UpdateFails(t *testing.T) {
 	updatedTag.Name = updateRequest.Name
 
 	// Set up expectations for Update failure
-	mockRepo.On("Update", updatedTag).Return(errors.New("failed to update tag"))
+	mockRepo.On("Update", 0, updatedTag).Return(errors.New("failed to update tag"))
 
 	// Test updating a tag
-	err := tagsService.Update(updateRequest)
+	_, err := tagsService.Update(0, updateRequest)
 
 	// Assert the error is as expected
 	assert.Error(t, err, "failed to update tag")
 
 	// Assert that the repository methods were called
 	mockRepo.AssertCalled(t, "FindById", updateRequest.Id)
-	mockRepo.AssertCalled(t, "Update", updatedTag)
+	mockRepo.AssertCalled(t, "Update", 0, updatedTag)
 
 	log.Print("Update Fails Test Case Passed.")
 }
@@ -0,0 +1,78 @@
+package unittesting
+
+import (
+	"log"
+	"testing"
+	"time"
+
+	"example.com/go-project/model"
+	"example.com/go-project/model/repository"
+	"example.com/go-project/services"
+	"github.com/stretchr/testify/assert"
+	"gorm.io/driver/sqlite"
+	"gorm.io/gorm"
+)
+
+func setupTokenService(t *testing.T) (*services.TokenService, *gorm.DB) {
+	db, err := gorm.Open(sqlite.Open(":memory:"), &gorm.Config{})
+	if err != nil {
+		t.Fatalf("could not connect to db: %v", err)
+	}
+
+	// Migrate the schema for users and their refresh tokens
+	err = db.AutoMigrate(&model.Users{}, &model.RefreshToken{})
+	assert.NoError(t, err)
+
+	db.Create(&model.Users{Id: 1, Name: "Test", Email: "test@example.com", Password: "secret", Role: "User"})
+
+	usersRepo := repository.NewUsersRepository(db)
+	tokensRepo := repository.NewRefreshTokenRepository(db)
+	return services.NewTokenService(tokensRepo, usersRepo, time.Hour), db
+}
+
+func TestTokenService_Rotate_Success(t *testing.T) {
+	log.Print("\n\n\n Running Token Service Test Cases.....\n\n\n")
+	tokenService, _ := setupTokenService(t)
+
+	refreshToken, err := tokenService.Issue(1)
+	assert.NoError(t, err)
+
+	// Exchange the refresh token for a new one
+	user, newRefreshToken, err := tokenService.Rotate(refreshToken)
+	assert.NoError(t, err)
+	assert.Equal(t, 1, user.Id)
+	assert.NotEqual(t, refreshToken, newRefreshToken)
+
+	// The new token can be rotated as well
+	_, _, err = tokenService.Rotate(newRefreshToken)
+	assert.NoError(t, err)
+}
+
+func TestTokenService_Rotate_Invalid(t *testing.T) {
+	tokenService, _ := setupTokenService(t)
+
+	_, _, err := tokenService.Rotate("not-a-real-token")
+	assert.ErrorIs(t, err, services.ErrInvalidRefreshToken)
+}
+
+func TestTokenService_Rotate_ReuseRevokesFamily(t *testing.T) {
+	tokenService, db := setupTokenService(t)
+
+	refreshToken, err := tokenService.Issue(1)
+	assert.NoError(t, err)
+
+	_, newRefreshToken, err := tokenService.Rotate(refreshToken)
+	assert.NoError(t, err)
+
+	// Replaying the already rotated token is detected
+	_, _, err = tokenService.Rotate(refreshToken)
+	assert.ErrorIs(t, err, services.ErrRefreshTokenReused)
+
+	// The legitimate successor has been revoked together with the family
+	_, _, err = tokenService.Rotate(newRefreshToken)
+	assert.ErrorIs(t, err, services.ErrRefreshTokenReused)
+
+	var active int64
+	db.Model(&model.RefreshToken{}).Where("revoked_at IS NULL").Count(&active)
+	assert.Equal(t, int64(0), active)
+}
@@ -0,0 +1,181 @@
+package unittesting
+
+import (
+	"net/http"
+	"testing"
+	"time"
+
+	"example.com/go-project/controller"
+	"example.com/go-project/data/request"
+	"example.com/go-project/helper"
+	"example.com/go-project/middleware"
+	"example.com/go-project/model"
+	"example.com/go-project/model/repository"
+	"example.com/go-project/services"
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"gorm.io/gorm"
+)
+
+const trashRetention = 30 * 24 * time.Hour
+
+// setupTrashRouter creates Cuisine and Italy with Pasta and Sushi under
+// Cuisine and Pizza under both
+func setupTrashRouter(t *testing.T) (*gin.Engine, services.TrashService, *gorm.DB) {
+	necheService, db := setupNecheService(t)
+	assert.NoError(t, db.Create(&[]model.Tags{{Id: 1, Name: "Cuisine"}, {Id: 2, Name: "Italy"}}).Error)
+	tagIds := [][]int{{1}, {1, 2}, {1}}
+	for i, name := range []string{"Pasta", "Pizza", "Sushi"} {
+		_, err := necheService.Create(0, request.CreateNecheRequest{Name: name, TagIDs: tagIds[i]})
+		assert.NoError(t, err)
+	}
+
+	tagsRepository := repository.NewTagsRepositoryImpl(db)
+	tagsService := services.NewTagsServiceImpl(tagsRepository, helper.NewValidator())
+	trashService := services.NewTrashServiceImpl(tagsRepository, repository.NewNecheRepositoryImpl(db), trashRetention)
+
+	gin.SetMode(gin.TestMode)
+	router := gin.New()
+	router.Use(middleware.ErrorHandler())
+	tagsController := controller.NewTagsController(tagsService)
+	necheController := controller.NewNecheController(necheService)
+	trashController := controller.NewTrashController(trashService)
+	router.GET("/tags/:tagId", tagsController.FindById)
+	router.DELETE("/tags/:tagId", tagsController.Delete)
+	router.GET("/neches", necheController.FindAll)
+	router.GET("/neches/:necheId", necheController.FindById)
+	router.DELETE("/neches/:necheId", necheController.Delete)
+	router.GET("/trash/tags", trashController.FindTags)
+	router.POST("/trash/tags/:tagId/restore", trashController.RestoreTag)
+	router.GET("/trash/neches", trashController.FindNeches)
+	router.POST("/trash/neches/:necheId/restore", trashController.RestoreNeche)
+	return router, trashService, db
+}
+
+func TestTrash_DeleteAndRestoreTag(t *testing.T) {
+	router, _, _ := setupTrashRouter(t)
+	ifMatch := map[string]string{"If-Match": "*"}
+
+	// Neches left without a tag go to the trash with it, Pizza keeps Italy
+	recorder := sendWithHeaders(router, http.MethodDelete, "/tags/1", "", ifMatch)
+	assert.Equal(t, http.StatusOK, recorder.Code)
+	recorder = sendWithHeaders(router, http.MethodGet, "/tags/1", "", nil)
+	assert.Equal(t, http.StatusNotFound, recorder.Code)
+
+	_, body := getPage(t, router, "/neches")
+	assert.Equal(t, []interface{}{"Pizza"}, necheNames(body.Data))
+	recorder = sendWithHeaders(router, http.MethodGet, "/neches/2", "", nil)
+	assert.Contains(t, recorder.Body.String(), `"tags":[{"id":2,"name":"Italy"}]`)
+
+	_, body = getPage(t, router, "/trash/tags")
+	assert.Equal(t, int64(1), body.Total)
+	trashed := body.Data.([]interface{})[0].(map[string]interface{})
+	assert.Equal(t, "Cuisine", trashed["name"])
+	deletedAt, err := time.Parse(time.RFC3339, trashed["deletedAt"].(string))
+	assert.NoError(t, err)
+	purgeAt, err := time.Parse(time.RFC3339, trashed["purgeAt"].(string))
+	assert.NoError(t, err)
+	assert.Equal(t, trashRetention, purgeAt.Sub(deletedAt))
+
+	_, body = getPage(t, router, "/trash/neches")
+	assert.Equal(t, []interface{}{"Pasta", "Sushi"}, necheNames(body.Data))
+	assert.Equal(t, float64(1), body.Data.([]interface{})[0].(map[string]interface{})["deletedWithTagId"])
+
+	// Restoring the tag brings its neches back together
+	recorder = sendWithHeaders(router, http.MethodPost, "/trash/tags/1/restore", "", nil)
+	assert.Equal(t, http.StatusOK, recorder.Code)
+	assert.Equal(t, `"2"`, recorder.Header().Get("ETag"))
+	recorder = sendWithHeaders(router, http.MethodPost, "/trash/tags/1/restore", "", nil)
+	assert.Equal(t, http.StatusNotFound, recorder.Code)
+
+	_, body = getPage(t, router, "/neches")
+	assert.Equal(t, []interface{}{"Pasta", "Pizza", "Sushi"}, necheNames(body.Data))
+	recorder = sendWithHeaders(router, http.MethodGet, "/neches/2", "", nil)
+	assert.Contains(t, recorder.Body.String(), `"tags":[{"id":1,"name":"Cuisine"},{"id":2,"name":"Italy"}]`)
+	_, body = getPage(t, router, "/trash/neches")
+	assert.Equal(t, int64(0), body.Total)
+}
+
+func TestTrash_RestoreNeche(t *testing.T) {
+	router, _, _ := setupTrashRouter(t)
+	ifMatch := map[string]string{"If-Match": "*"}
+
+	recorder := sendWithHeaders(router, http.MethodDelete, "/neches/1", "", ifMatch)
+	assert.Equal(t, http.StatusOK, recorder.Code)
+	recorder = sendWithHeaders(router, http.MethodGet, "/neches/1", "", nil)
+	assert.Equal(t, http.StatusNotFound, recorder.Code)
+
+	recorder = sendWithHeaders(router, http.MethodPost, "/trash/neches/1/restore", "", nil)
+	assert.Equal(t, http.StatusOK, recorder.Code)
+	assert.Contains(t, recorder.Body.String(), `"name":"Pasta"`)
+
+	// A neche needs a tag that isn't in the trash
+	recorder = sendWithHeaders(router, http.MethodDelete, "/tags/1", "", ifMatch)
+	assert.Equal(t, http.StatusOK, recorder.Code)
+	recorder = sendWithHeaders(router, http.MethodPost, "/trash/neches/3/restore", "", nil)
+	assert.Equal(t, http.StatusConflict, recorder.Code)
+	recorder = sendWithHeaders(router, http.MethodPost, "/trash/neches/2/restore", "", nil)
+	assert.Equal(t, http.StatusNotFound, recorder.Code)
+}
+
+func TestTrash_RestoreChildOfTrashedTag(t *testing.T) {
+	router, _, db := setupTrashRouter(t)
+	tagsRepository := repository.NewTagsRepositoryImpl(db)
+	assert.NoError(t, tagsRepository.Save(0, model.Tags{Id: 3, Name: "Rome", ParentId: intPtr(2)}))
+	assert.NoError(t, tagsRepository.Delete(0, 3, 0))
+	assert.NoError(t, tagsRepository.Delete(0, 2, 0))
+
+	recorder := sendWithHeaders(router, http.MethodPost, "/trash/tags/3/restore", "", nil)
+	assert.Equal(t, http.StatusConflict, recorder.Code)
+
+	recorder = sendWithHeaders(router, http.MethodPost, "/trash/tags/2/restore", "", nil)
+	assert.Equal(t, http.StatusOK, recorder.Code)
+	recorder = sendWithHeaders(router, http.MethodPost, "/trash/tags/3/restore", "", nil)
+	assert.Equal(t, http.StatusOK, recorder.Code)
+}
+
+func TestTrash_Purge(t *testing.T) {
+	_, trashService, db := setupTrashRouter(t)
+	tagsRepository := repository.NewTagsRepositoryImpl(db)
+	assert.NoError(t, tagsRepository.Save(0, model.Tags{Id: 3, Name: "Rome", ParentId: intPtr(2)}))
+	assert.NoError(t, tagsRepository.Delete(0, 3, 0))
+	assert.NoError(t, tagsRepository.Delete(0, 2, 0))
+	assert.NoError(t, tagsRepository.Delete(0, 1, 0))
+
+	// Nothing expired yet
+	tags, neches, err := trashService.Purge(time.Now())
+	assert.NoError(t, err)
+	assert.Equal(t, int64(0), tags)
+	assert.Equal(t, int64(0), neches)
+
+	// Children are purged before their parents
+	tags, neches, err = trashService.Purge(time.Now().Add(trashRetention + time.Minute))
+	assert.NoError(t, err)
+	assert.Equal(t, int64(3), tags)
+	assert.Equal(t, int64(3), neches)
+
+	var count int64
+	db.Unscoped().Model(&model.Tags{}).Count(&count)
+	assert.Equal(t, int64(0), count)
+	db.Model(&model.NecheTag{}).Count(&count)
+	assert.Equal(t, int64(0), count)
+}
+
+func TestTrash_PurgeAtStartup(t *testing.T) {
+	_, trashService, db := setupTrashRouter(t)
+	tagsRepository := repository.NewTagsRepositoryImpl(db)
+	assert.NoError(t, tagsRepository.Delete(0, 1, 0))
+	expired := time.Now().Add(-trashRetention - time.Minute)
+	assert.NoError(t, db.Unscoped().Model(&model.Tags{}).Where("deleted_at IS NOT NULL").Update("deleted_at", expired).Error)
+	assert.NoError(t, db.Unscoped().Model(&model.Neche{}).Where("deleted_at IS NOT NULL").Update("deleted_at", expired).Error)
+
+	// The first purge doesn't wait for the interval
+	stop := services.StartTrashPurging(trashService, time.Hour)
+	defer stop()
+
+	var count int64
+	db.Unscoped().Model(&model.Tags{}).Count(&count)
+	assert.Equal(t, int64(1), count)
+	db.Unscoped().Model(&model.Neche{}).Count(&count)
+	assert.Equal(t, int64(1), count)
+}
@@ -0,0 +1,89 @@
+package unittesting
+
+import (
+	"log"
+	"testing"
+
+	"example.com/go-project/data/request"
+	"example.com/go-project/model"
+	"example.com/go-project/model/repository"
+	"example.com/go-project/services"
+	"github.com/go-playground/validator"
+	"github.com/stretchr/testify/assert"
+	"gorm.io/gorm"
+)
+
+func setupUsersService(t *testing.T) (*services.UsersService, *repository.UsersRepository) {
+	// Use the real migrations so the unique index is the one production has
+	db := setupMigratedDB(t)
+
+	usersRepo := repository.NewUsersRepository(db)
+	return services.NewUsersService(usersRepo, validator.New()), usersRepo
+}
+
+func TestUsersService_Create_NormalizesEmail(t *testing.T) {
+	log.Print("\n\n\n Running Users Service Test Cases.....\n\n\n")
+	usersService, _ := setupUsersService(t)
+
+	_, err := usersService.Register(request.RegisterUserRequest{Name: "Test", Email: "  Test@Example.COM ", Password: "secret12"})
+	assert.NoError(t, err)
+
+	// Lookups ignore case and surrounding spaces
+	user, err := usersService.FindUserByEmail("test@example.com")
+	assert.NoError(t, err)
+	assert.NotNil(t, user)
+	assert.Equal(t, "test@example.com", user.Email)
+
+	user, err = usersService.Authenticate("TEST@example.com", "secret12")
+	assert.NoError(t, err)
+	assert.NotNil(t, user)
+}
+
+func TestUsersService_Create_DuplicateEmail(t *testing.T) {
+	usersService, usersRepo := setupUsersService(t)
+
+	_, err := usersService.Register(request.RegisterUserRequest{Name: "Test", Email: "test@example.com", Password: "secret12"})
+	assert.NoError(t, err)
+
+	// The service check catches a different spelling of the same address
+	_, err = usersService.Register(request.RegisterUserRequest{Name: "Other", Email: "TEST@example.com", Password: "secret12"})
+	assert.ErrorIs(t, err, services.ErrEmailExists)
+
+	// The database catches registrations that got past the check
+	err = usersRepo.Save(&model.Users{Name: "Racer", Email: "test@example.com", Password: "secret", Role: "User"})
+	assert.ErrorIs(t, err, gorm.ErrDuplicatedKey)
+}
+
+func TestUsersService_Register_IgnoresClientRole(t *testing.T) {
+	usersService, _ := setupUsersService(t)
+
+	// The request has no way to carry a role, every new account is a plain user
+	user, err := usersService.Register(request.RegisterUserRequest{Name: "Test", Email: "test@example.com", Password: "secret12"})
+	assert.NoError(t, err)
+	assert.Equal(t, model.RoleUser, user.Role)
+	assert.NotZero(t, user.Id)
+
+	// Invalid registrations are rejected before touching the database
+	_, err = usersService.Register(request.RegisterUserRequest{Name: "Test", Email: "not-an-email", Password: "short"})
+	assert.Error(t, err)
+}
+
+func TestUsersService_UpdateRole(t *testing.T) {
+	usersService, _ := setupUsersService(t)
+
+	user, err := usersService.Register(request.RegisterUserRequest{Name: "Test", Email: "test@example.com", Password: "secret12"})
+	assert.NoError(t, err)
+
+	err = usersService.UpdateRole(request.UpdateUserRoleRequest{Id: user.Id, Role: model.RoleAdmin})
+	assert.NoError(t, err)
+
+	updated, err := usersService.FindUserByEmail("test@example.com")
+	assert.NoError(t, err)
+	assert.Equal(t, model.RoleAdmin, updated.Role)
+
+	err = usersService.UpdateRole(request.UpdateUserRoleRequest{Id: 999, Role: model.RoleAdmin})
+	assert.ErrorIs(t, err, services.ErrUserNotFound)
+
+	err = usersService.UpdateRole(request.UpdateUserRoleRequest{Id: user.Id, Role: ""})
+	assert.Error(t, err)
+}
@@ -0,0 +1,101 @@
+package unittesting
+
+import (
+	"encoding/json"
+	"net/http"
+	"strings"
+	"testing"
+
+	"example.com/go-project/data/request"
+	"example.com/go-project/helper"
+	"example.com/go-project/middleware"
+	"example.com/go-project/services"
+	"github.com/gin-gonic/gin"
+	"github.com/go-playground/locales/de"
+	"github.com/go-playground/validator"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestFieldErrorsUseJSONNames(t *testing.T) {
+	validate := helper.NewValidator()
+
+	err := validate.Struct(request.RegisterUserRequest{Name: "Jane", Email: "not-an-email", Password: "short"})
+	assert.IsType(t, validator.ValidationErrors{}, err)
+
+	fieldErrors := helper.FieldErrors(err.(validator.ValidationErrors))
+	assert.Equal(t, []helper.FieldError{
+		{Field: "email", Rule: "email", Message: "email must be a valid email address"},
+		{Field: "password", Rule: "min", Param: "8", Message: "password must be at least 8 characters long"},
+	}, fieldErrors)
+}
+
+func TestFieldErrorMessagesDependOnKind(t *testing.T) {
+	validate := helper.NewValidator()
+
+	err := validate.Struct(request.CreateNecheRequest{Name: strings.Repeat("n", 201), TagIDs: []int{-1}})
+	fieldErrors := helper.FieldErrors(err.(validator.ValidationErrors))
+
+	assert.Equal(t, []helper.FieldError{
+		{Field: "name", Rule: "max", Param: "200", Message: "name must be at most 200 characters long"},
+		{Field: "tagIds[0]", Rule: "min", Param: "1", Message: "tagIds[0] must be 1 or greater"},
+	}, fieldErrors)
+}
+
+func TestUpdateTagsRequestIsValidated(t *testing.T) {
+	tagsService := services.NewTagsServiceImpl(new(MockTagsRepository), helper.NewValidator())
+
+	_, err := tagsService.Update(0, request.UpdateTagsRequest{Id: 1, Name: strings.Repeat("t", 201)})
+	appErr := helper.AsAppError(err)
+
+	assert.Equal(t, helper.KindValidation, appErr.Kind)
+	assert.Equal(t, "name", appErr.Details[0].Field)
+	assert.Equal(t, "max", appErr.Details[0].Rule)
+}
+
+func TestValidationErrorsAreRenderedAsDetails(t *testing.T) {
+	validate := helper.NewValidator()
+
+	status, body := serveError(t, func(c *gin.Context) {
+		c.Error(validate.Struct(request.CreteTagsRequest{}))
+	})
+
+	assert.Equal(t, http.StatusBadRequest, status)
+	assert.Equal(t, "validation_failed", body.ErrorCode)
+	assert.Equal(t, "validation failed", body.Data)
+	assert.Equal(t, []helper.FieldError{
+		{Field: "name", Rule: "required", Message: "name is a required field"},
+	}, body.Errors)
+}
+
+func TestValidationMessagesFollowAcceptLanguage(t *testing.T) {
+	assert.NoError(t, helper.RegisterMessages(de.New(), map[string]string{
+		"required": "{0} ist ein Pflichtfeld",
+	}))
+	validate := helper.NewValidator()
+
+	gin.SetMode(gin.TestMode)
+	router := gin.New()
+	router.Use(middleware.ErrorHandler())
+	router.POST("/neches", func(c *gin.Context) {
+		c.Error(validate.Struct(request.CreateNecheRequest{Name: strings.Repeat("n", 201)}))
+	})
+	messages := func(acceptLanguage string) []string {
+		recorder := sendWithHeaders(router, http.MethodPost, "/neches", "", map[string]string{"Accept-Language": acceptLanguage})
+		var body struct{ Errors []helper.FieldError }
+		assert.NoError(t, json.Unmarshal(recorder.Body.Bytes(), &body))
+		var messages []string
+		for _, fieldError := range body.Errors {
+			messages = append(messages, fieldError.Message)
+		}
+		return messages
+	}
+
+	// A region falls back to its language, rules without a translation stay English
+	german := []string{"name must be at most 200 characters long", "tagIds ist ein Pflichtfeld"}
+	assert.Equal(t, german, messages("de-CH, en;q=0.8"))
+	assert.Equal(t, german, messages("en;q=0.5, de"))
+
+	english := []string{"name must be at most 200 characters long", "tagIds is a required field"}
+	assert.Equal(t, english, messages("fr"))
+	assert.Equal(t, english, messages(""))
+}